@@ -0,0 +1,126 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Address is a GPIB device address, composed of a primary address and an
+// optional secondary address. It has the same representation as Addr4882_t in
+// the C library, so an Address without a secondary address is equal to its
+// primary address and an untyped constant such as 22 may be used directly.
+type Address int
+
+// Secondary addresses are numbered from 0 to 30 on the bus, and represented by
+// linux-gpib with an offset of 0x60, which is also their command byte.
+const (
+	minSecondary = 0x60
+	maxSecondary = 0x7e
+)
+
+// NewAddress combines a primary address in the range 0 to 30 with a secondary
+// address, which is zero for none or in the range 0x60 to 0x7E.
+func NewAddress(pad, sad int) (Address, error) {
+	a := newAddress(pad, sad)
+	if err := a.Validate(); err != nil {
+		return 0, err
+	}
+	return a, nil
+}
+
+// newAddress is like NewAddress but does not validate the addresses.
+func newAddress(pad, sad int) Address {
+	return Address(pad&0xff | sad&0xff<<8)
+}
+
+// ParseAddress parses an address as formatted by String: the primary address
+// in decimal, optionally followed by a dot and the secondary address in the
+// range 0 to 30, for example "22" or "5.3".
+func ParseAddress(s string) (Address, error) {
+	p, q, dotted := strings.Cut(s, ".")
+	pad, err := strconv.Atoi(p)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q: primary address is not a number", s)
+	}
+	sad := 0
+	if dotted {
+		n, err := strconv.Atoi(q)
+		if err != nil || n < 0 || n > maxSecondary-minSecondary {
+			return 0, fmt.Errorf("invalid address %q: secondary address is not a number from 0 to 30", s)
+		}
+		sad = minSecondary + n
+	}
+	a, err := NewAddress(pad, sad)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q: %v", s, err)
+	}
+	return a, nil
+}
+
+// Primary returns the primary address.
+func (a Address) Primary() int {
+	return int(a) & 0xff
+}
+
+// Secondary returns the secondary address, which is zero for none or in the
+// range 0x60 to 0x7E.
+func (a Address) Secondary() int {
+	return int(a) >> 8 & 0xff
+}
+
+// Validate returns an error if the primary or secondary address is out of
+// range.
+func (a Address) Validate() error {
+	if a < 0 || a > 0xffff {
+		return fmt.Errorf("address 0x%x is not a valid combination of primary and secondary addresses", int(a))
+	}
+	if p := a.Primary(); p > 30 {
+		return fmt.Errorf("primary address %d is outside the range 0 to 30", p)
+	}
+	if s := a.Secondary(); s != 0 && (s < minSecondary || s > maxSecondary) {
+		return fmt.Errorf("secondary address 0x%x is neither 0 nor in the range 0x60 to 0x7E", s)
+	}
+	return nil
+}
+
+// String returns the primary address in decimal, followed by a dot and the
+// secondary address in the range 0 to 30 if there is one.
+func (a Address) String() string {
+	if a.Validate() != nil {
+		return fmt.Sprintf("Address(0x%x)", int(a))
+	}
+	if s := a.Secondary(); s != 0 {
+		return fmt.Sprintf("%d.%d", a.Primary(), s-minSecondary)
+	}
+	return strconv.Itoa(a.Primary())
+}
+
+// MarshalText implements encoding.TextMarshaler using the format of String.
+func (a Address) MarshalText() ([]byte, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseAddress.
+func (a *Address) UnmarshalText(b []byte) error {
+	n, err := ParseAddress(string(b))
+	if err != nil {
+		return err
+	}
+	*a = n
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// A Backend carries out GPIB operations on behalf of Board and Device. The
+// default backend calls the linux-gpib C library, while others may forward the
+// operations to another process or a different kind of controller.
+//
+// The methods have the same meaning as the C functions of the same name, which
+// are documented in https://linux-gpib.sourceforge.io/doc_html/reference.html.
+// Board descriptors are the board index, as in linux-gpib. Most methods return
+// ibsta, and afterwards Ibsta, Iberr and Ibcnt return the values of the C
+// globals of the same name. The values of status bits, error codes and options
+// are those of linux-gpib's ib.h.
+//
+// Calls are serialized by the same lock that protects the C library, so a
+// backend need not be safe for concurrent use unless it is shared with other
+// code. Implementations must be comparable, as a pointer is.
+type Backend interface {
+	Ibvers() string
+
+	Ibdev(board, pad, sad, tmo, eot, eos int) (ud int)
+	Ibfind(name string) (ud int)
+	Ibonl(ud, v int) int
+	Ibask(ud, option int) (ibsta, value int)
+	Ibconfig(ud, option, value int) int
+	Ibbna(ud int, name string) int
+	Ibtmo(ud, v int) int
+	Ibeot(ud, v int) int
+	Ibeos(ud, v int) int
+
+	Ibrd(ud int, buf []byte) int
+	Ibwrt(ud int, buf []byte) int
+	Ibrdf(ud int, path string) int
+	Ibwrtf(ud int, path string) int
+	Ibclr(ud int) int
+	Ibtrg(ud int) int
+	Ibrsp(ud int) (ibsta int, spr byte)
+	Ibloc(ud int) int
+	Ibwait(ud, mask int) int
+
+	Ibcmd(board int, cmd []byte) int
+	Ibsic(board int) int
+	Ibsre(board, v int) int
+	Iblines(board int) (ibsta, lines int)
+	Ibln(board, pad, sad int) (ibsta, found int)
+	SendList(board int, addrs []Address, buf []byte, eotmode int) int
+
+	Ibsta() int
+	Iberr() int
+	Ibcnt() int
+}
+
+// Aborter is implemented by backends which can abort an operation in progress
+// on a device, as used by Device.Abort.
+type Aborter interface {
+	// Abort makes the operation in progress on a device descriptor fail with
+	// EABO. Unlike the Backend methods, it is called without the lock which
+	// serializes them, while another call may be in progress, and must not
+	// wait for that call.
+	Abort(ud int) error
+}
+
+// UseBackend selects the backend for a board and its devices. The default is
+// DefaultBackend.
+func UseBackend(be Backend) Option {
+	return func(o *options) {
+		o.backend = be
+	}
+}
+
+// DefaultBackend returns the backend which calls the linux-gpib C library.
+func DefaultBackend() Backend {
+	return libgpib{}
+}
+
+// libgpib is the backend for the linux-gpib C library.
+type libgpib struct{}
+
+func (libgpib) Ibvers() string { return internal.Ibvers() }
+
+func (libgpib) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	return internal.Ibdev(board, pad, sad, tmo, eot, eos)
+}
+func (libgpib) Ibfind(name string) int              { return internal.Ibfind(name) }
+func (libgpib) Ibonl(ud, v int) int                 { return internal.Ibonl(ud, v) }
+func (libgpib) Ibask(ud, option int) (int, int)     { return internal.Ibask(ud, option) }
+func (libgpib) Ibconfig(ud, option, value int) int  { return internal.Ibconfig(ud, option, value) }
+func (libgpib) Ibbna(ud int, name string) int       { return internal.Ibbna(ud, name) }
+func (libgpib) Ibtmo(ud, v int) int                 { return internal.Ibtmo(ud, v) }
+func (libgpib) Ibeot(ud, v int) int                 { return internal.Ibeot(ud, v) }
+func (libgpib) Ibeos(ud, v int) int                 { return internal.Ibeos(ud, v) }
+func (libgpib) Ibrd(ud int, buf []byte) int         { return internal.Ibrd(ud, buf) }
+func (libgpib) Ibwrt(ud int, buf []byte) int        { return internal.Ibwrt(ud, buf) }
+func (libgpib) Ibrdf(ud int, path string) int       { return internal.Ibrdf(ud, path) }
+func (libgpib) Ibwrtf(ud int, path string) int      { return internal.Ibwrtf(ud, path) }
+func (libgpib) Ibclr(ud int) int                    { return internal.Ibclr(ud) }
+func (libgpib) Ibtrg(ud int) int                    { return internal.Ibtrg(ud) }
+func (libgpib) Ibrsp(ud int) (int, byte)            { return internal.Ibrsp(ud) }
+func (libgpib) Ibloc(ud int) int                    { return internal.Ibloc(ud) }
+func (libgpib) Ibwait(ud, mask int) int             { return internal.Ibwait(ud, mask) }
+func (libgpib) Ibcmd(board int, cmd []byte) int     { return internal.Ibcmd(board, cmd) }
+func (libgpib) Ibsic(board int) int                 { return internal.Ibsic(board) }
+func (libgpib) Ibsre(board, v int) int              { return internal.Ibsre(board, v) }
+func (libgpib) Iblines(board int) (int, int)        { return internal.Iblines(board) }
+func (libgpib) Ibln(board, pad, sad int) (int, int) { return internal.Ibln(board, pad, sad) }
+func (libgpib) Ibsta() int                          { return internal.Ibsta() }
+func (libgpib) Iberr() int                          { return internal.Iberr() }
+func (libgpib) Ibcnt() int                          { return internal.Ibcnt() }
+
+func (libgpib) SendList(board int, addrs []Address, buf []byte, eotmode int) int {
+	as := make([]internal.Address, len(addrs))
+	for i, a := range addrs {
+		as[i] = internal.Address(a)
+	}
+	internal.SendList(board, as, buf, eotmode)
+	return internal.Ibsta()
+}
+
+// backendErr converts ibsta to an error like internal.Err, obtaining iberr and
+// ibcnt from the backend.
+func backendErr(be Backend, ibsta int) error {
+	return internal.ErrFrom(ibsta, be.Iberr, be.Ibcnt)
+}
+
+// Backend returns the backend through which the board is operated, from which
+// the status of its last operation may be obtained.
+func (b *Board) Backend() Backend { return b.be }
+
+func (b *Board) err(ibsta int) error  { return backendErr(b.be, ibsta) }
+func (d *Device) err(ibsta int) error { return backendErr(d.board.be, ibsta) }
@@ -0,0 +1,266 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// fakeBackend is a Backend for tests. Every call succeeds without driving the
+// bus, except the method named by fail, which fails with EBUS, or with EDVR if
+// failErrno is set. Devices are
+// opened at any address on any board, and all of them send resp and collect
+// what is written to them.
+type fakeBackend struct {
+	res       internal.Result
+	fail      string
+	failErrno syscall.Errno
+	// devices holds the open device descriptors, which are numbered from
+	// GPIB_MAX_NUM_BOARDS in the order they were opened.
+	devices map[int]*fakeDevice
+	next    int
+	// names are the devices which Ibfind knows.
+	names map[string]Resource
+
+	// resp is sent by reads, ending with EOI. Reads time out once it has all
+	// been sent.
+	resp []byte
+	// maxRead, if nonzero, is the largest transfer by one Ibrd.
+	maxRead int
+	// limit, if nonzero, is the number of bytes which may be transferred in
+	// either direction, after which reads and writes time out. moved counts
+	// the bytes transferred.
+	limit, moved int
+	// received collects the messages written which ended with EOI, and
+	// pending the start of one which has not ended yet.
+	received []string
+	pending  []byte
+}
+
+// fakeDevice is an open device descriptor of a fakeBackend.
+type fakeDevice struct {
+	board    int
+	pad, sad int
+	eot      int
+}
+
+// done records the result of a call of the named method, which transferred
+// cnt bytes unless it is the one to fail.
+func (b *fakeBackend) done(op string, cnt int) int {
+	if op == b.fail {
+		if b.failErrno != 0 {
+			return b.res.FailErrno(int(b.failErrno))
+		}
+		return b.res.Fail(internal.EBUS)
+	}
+	return b.res.Done(0, cnt)
+}
+
+// device returns an open device, or nil if the descriptor is invalid.
+func (b *fakeBackend) device(ud int) *fakeDevice {
+	d := b.devices[ud]
+	if d == nil {
+		b.res.Fail(internal.EARG)
+	}
+	return d
+}
+
+func (b *fakeBackend) Ibvers() string { return "fake" }
+
+func (b *fakeBackend) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	if b.done("Ibdev", 0)&internal.ERR != 0 {
+		return -1
+	}
+	if b.devices == nil {
+		b.devices = map[int]*fakeDevice{}
+	}
+	ud := internal.GPIB_MAX_NUM_BOARDS + b.next
+	b.next++
+	b.devices[ud] = &fakeDevice{board: board, pad: pad, sad: sad, eot: eot}
+	return ud
+}
+
+func (b *fakeBackend) Ibfind(name string) int {
+	r, ok := b.names[name]
+	if !ok {
+		b.res.FailErrno(int(syscall.ENOENT))
+		return -1
+	}
+	return b.Ibdev(r.Board, r.Address.Primary(), r.Address.Secondary(), internal.T1s, 1, 0)
+}
+
+func (b *fakeBackend) Ibonl(ud, v int) int {
+	if v == 0 {
+		delete(b.devices, ud)
+	}
+	return b.done("Ibonl", 0)
+}
+
+func (b *fakeBackend) Ibask(ud, option int) (int, int) {
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta, 0
+	}
+	switch option {
+	case internal.IbaBNA:
+		return b.done("Ibask", 0), d.board
+	case internal.IbaPAD:
+		return b.done("Ibask", 0), d.pad
+	case internal.IbaSAD:
+		return b.done("Ibask", 0), d.sad
+	}
+	return b.done("Ibask", 0), 0
+}
+
+func (b *fakeBackend) Ibconfig(ud, option, value int) int {
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	if option == internal.IbcBNA {
+		d.board = value
+	}
+	return b.done("Ibconfig", 0)
+}
+
+func (b *fakeBackend) Ibbna(ud int, name string) int {
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	var board int
+	if _, err := fmt.Sscanf(name, "gpib%d", &board); err != nil {
+		return b.res.Fail(internal.EARG)
+	}
+	d.board = board
+	return b.done("Ibbna", 0)
+}
+
+func (b *fakeBackend) Ibeot(ud, v int) int {
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	d.eot = v
+	return b.done("Ibeot", 0)
+}
+
+func (b *fakeBackend) Ibtmo(ud, v int) int { return b.done("Ibtmo", 0) }
+func (b *fakeBackend) Ibeos(ud, v int) int { return b.done("Ibeos", 0) }
+
+// take returns how many of n bytes may be transferred before the limit.
+func (b *fakeBackend) take(n int) int {
+	if b.limit != 0 {
+		n = min(n, b.limit-b.moved)
+	}
+	b.moved += n
+	return n
+}
+
+func (b *fakeBackend) Ibrd(ud int, buf []byte) int {
+	if b.device(ud) == nil {
+		return b.res.Sta
+	}
+	if b.fail == "Ibrd" {
+		return b.done("Ibrd", 0)
+	}
+	want := min(len(buf), len(b.resp))
+	if b.maxRead != 0 {
+		want = min(want, b.maxRead)
+	}
+	n := b.take(want)
+	copy(buf, b.resp[:n])
+	b.resp = b.resp[n:]
+	switch {
+	case want == 0 || n < want:
+		return b.res.Timeout(n)
+	case len(b.resp) == 0:
+		return b.res.Done(internal.END, n)
+	}
+	return b.res.Done(0, n)
+}
+
+func (b *fakeBackend) Ibwrt(ud int, buf []byte) int {
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	if b.fail == "Ibwrt" {
+		return b.done("Ibwrt", 0)
+	}
+	n := b.take(len(buf))
+	b.pending = append(b.pending, buf[:n]...)
+	if n < len(buf) {
+		return b.res.Timeout(n)
+	}
+	if d.eot != 0 {
+		b.received = append(b.received, string(b.pending))
+		b.pending = nil
+	}
+	return b.res.Done(0, n)
+}
+
+func (b *fakeBackend) Ibrdf(ud int, path string) int {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return b.fileErr(err)
+	}
+	defer f.Close()
+	buf := make([]byte, len(b.resp))
+	ibsta := b.Ibrd(ud, buf)
+	if _, err := f.Write(buf[:b.res.Cnt]); err != nil {
+		return b.fileErr(err)
+	}
+	return ibsta
+}
+
+func (b *fakeBackend) Ibwrtf(ud int, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b.fileErr(err)
+	}
+	return b.Ibwrt(ud, data)
+}
+
+// fileErr records EFSO, with errno in ibcnt as linux-gpib does.
+func (b *fakeBackend) fileErr(err error) int {
+	errno := syscall.EIO
+	errors.As(err, &errno)
+	b.res.Sta, b.res.Err, b.res.Cnt = internal.ERR|internal.CMPL, internal.EFSO, int(errno)
+	return b.res.Sta
+}
+
+func (b *fakeBackend) Ibclr(ud int) int                { return b.done("Ibclr", 0) }
+func (b *fakeBackend) Ibtrg(ud int) int                { return b.done("Ibtrg", 0) }
+func (b *fakeBackend) Ibrsp(ud int) (int, byte)        { return b.done("Ibrsp", 0), 0 }
+func (b *fakeBackend) Ibloc(ud int) int                { return b.done("Ibloc", 0) }
+func (b *fakeBackend) Ibwait(ud, mask int) int         { return b.done("Ibwait", 0) }
+func (b *fakeBackend) Ibcmd(board int, cmd []byte) int { return b.done("Ibcmd", len(cmd)) }
+func (b *fakeBackend) Ibsic(board int) int             { return b.done("Ibsic", 0) }
+func (b *fakeBackend) Ibsre(board, v int) int          { return b.done("Ibsre", 0) }
+func (b *fakeBackend) Iblines(board int) (int, int)    { return b.done("Iblines", 0), 0 }
+func (b *fakeBackend) Ibln(board, pad, sad int) (int, int) {
+	return b.done("Ibln", 0), 0
+}
+
+func (b *fakeBackend) SendList(board int, addrs []Address, buf []byte, eotmode int) int {
+	return b.done("SendList", len(buf))
+}
+
+func (b *fakeBackend) Ibsta() int { return b.res.Sta }
+func (b *fakeBackend) Iberr() int { return b.res.Err }
+func (b *fakeBackend) Ibcnt() int { return b.res.Cnt }
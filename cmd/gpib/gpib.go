@@ -0,0 +1,537 @@
+// Copyright 2023 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Gpib performs everyday operations on GPIB devices from the command line.
+
+Usage:
+
+	gpib SUBCOMMAND [-verbose] [-json] [-board=BOARD] [-address=ADDRESS] [-timeout=DURATION] [-record=FILE] [ARGS...]
+
+The subcommands are:
+
+	scan       list the devices on the bus, with their *IDN? responses
+	idn        query the identification string of a [SCPI] device
+	write      send the arguments, joined by spaces, followed by a newline
+	query      send the arguments like write, and print the response
+	read       read until the device asserts EOI, or at most -max bytes
+	spoll      serial poll the device and print its status byte
+	trigger    trigger the device
+	clear      clear the device
+	local      return the device to local control
+	lines      show the state of the bus control lines
+	ifc        send an interface clear on the board
+	ask        send each line of standard input, printing the responses to
+	           lines which contain a question mark
+
+The flags are:
+
+	-verbose
+		Turn on logging. Without this, only the result or first error is printed.
+
+	-json
+		Print the result, or the error, as a JSON object on standard output.
+		The ask subcommand prints one object per line of input. Data read
+		which is not valid UTF-8 is printed as an object with its base64
+		encoding in the "base64" field.
+
+	-board
+		The board number. Defaults to zero, which corresponds to /dev/gpib0.
+
+	-address
+		The address of the GPIB device, such as 22, or 5.3 for primary address 5
+		and secondary address 3. It is required by the subcommands which
+		operate on a device.
+
+	-timeout
+		The timeout for each operation. Defaults to 10 seconds, or 1 second per
+		device for scan.
+
+	-max
+		For read, the maximum number of bytes to read. Zero, the default, reads
+		until EOI.
+
+	-record
+		Write a transcript of every GPIB operation to the file, which can be
+		played back with the transcript package to reproduce the session.
+
+The exit status is 0 on success, 1 for errors other than those below, 2 for
+incorrect usage, 3 if an operation timed out, and 4 if no device was listening
+at the address.
+
+Examples:
+
+	$ gpib idn -address 22
+	HEWLETT-PACKARD,34401A,0,10-5-2
+
+	$ gpib scan
+	ADDRESS  IDN
+	5        FLUKE,8842A,0,V2.0
+	22       HEWLETT-PACKARD,34401A,0,10-5-2
+
+	$ gpib query -json -address 22 MEAS:VOLT:DC?
+	{"board":0,"address":"22","response":"+1.23456789E+00"}
+
+	$ printf 'CONF:VOLT:DC 10\nREAD?\n' | gpib ask -address 22
+	+1.23456789E+00
+
+	$ gpib spoll -address 7; echo $?
+	gpib: ENOL
+	4
+
+[SCPI]: https://en.wikipedia.org/wiki/Standard_Commands_for_Programmable_Instruments
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/transcript"
+)
+
+// Exit codes.
+const (
+	exitError      = 1
+	exitUsage      = 2
+	exitTimeout    = 3
+	exitNoListener = 4
+)
+
+// defaultScanTimeout is the timeout used for each device by scan.
+const defaultScanTimeout = time.Second
+
+// subcommand describes a subcommand.
+type subcommand struct {
+	// device is true if the subcommand operates on a device, and so needs
+	// -address.
+	device bool
+	// run performs the subcommand and returns the result, which is printed as
+	// text, or as JSON after merging with the board and address.
+	run func(e *env, args []string) (result, error)
+}
+
+// result is the output of a subcommand.
+type result struct {
+	text string         // printed in text mode, followed by a newline if not empty
+	raw  []byte         // printed in text mode as is
+	json map[string]any // merged into the JSON object
+}
+
+// env is what subcommands operate on.
+type env struct {
+	board   *linuxgpib.Board
+	dev     *linuxgpib.Device
+	addr    linuxgpib.Address
+	json    bool
+	out     io.Writer
+	in      io.Reader
+	max     int
+	timeout time.Duration
+	header  map[string]any // board and address for JSON output
+}
+
+var subcommands = map[string]subcommand{
+	"scan":    {false, scan},
+	"idn":     {true, idn},
+	"write":   {true, write},
+	"query":   {true, query},
+	"read":    {true, read},
+	"spoll":   {true, spoll},
+	"trigger": {true, simple((*linuxgpib.Device).Trigger)},
+	"clear":   {true, simple((*linuxgpib.Device).Clear)},
+	"local":   {true, simple((*linuxgpib.Device).Local)},
+	"lines":   {false, lines},
+	"ifc":     {false, ifc},
+	"ask":     {true, ask},
+}
+
+func scan(e *env, args []string) (result, error) {
+	addrs, err := e.board.Enumerate()
+	if err != nil {
+		return result{}, err
+	}
+	type found struct {
+		Address string `json:"address"`
+		IDN     string `json:"idn,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+	devices := []found{}
+	var text bytes.Buffer
+	tw := tabwriter.NewWriter(&text, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tIDN")
+	for _, a := range addrs {
+		f := found{Address: a.String()}
+		d, err := e.board.NewDevice(a, linuxgpib.Timeout(e.timeout))
+		if err == nil {
+			f.IDN, err = d.Query("*IDN?")
+			d.Close()
+		}
+		if err != nil {
+			f.Error = err.Error()
+			fmt.Fprintf(tw, "%v\t(%v)\n", a, err)
+		} else {
+			fmt.Fprintf(tw, "%v\t%s\n", a, f.IDN)
+		}
+		devices = append(devices, f)
+	}
+	tw.Flush()
+	return result{
+		text: strings.TrimRight(text.String(), "\n"),
+		json: map[string]any{"devices": devices},
+	}, nil
+}
+
+func idn(e *env, args []string) (result, error) {
+	return query(e, []string{"*IDN?"})
+}
+
+func write(e *env, args []string) (result, error) {
+	if len(args) == 0 {
+		return result{}, usageError("write needs a message")
+	}
+	_, err := e.dev.Write([]byte(strings.Join(args, " ") + "\n"))
+	return result{}, err
+}
+
+func query(e *env, args []string) (result, error) {
+	if len(args) == 0 {
+		return result{}, usageError("query needs a message")
+	}
+	resp, err := e.dev.Query(strings.Join(args, " "))
+	if err != nil {
+		return result{}, err
+	}
+	return result{text: resp, json: map[string]any{"response": resp}}, nil
+}
+
+func read(e *env, args []string) (result, error) {
+	if e.max > 0 {
+		buf := make([]byte, e.max)
+		n, end, err := e.dev.ReadEnd(buf)
+		if err != nil {
+			return result{}, err
+		}
+		return result{raw: buf[:n], json: map[string]any{"data": transcript.Data(buf[:n]), "end": end}}, nil
+	}
+	var buf bytes.Buffer
+	if _, err := e.dev.ReadTo(&buf); err != nil {
+		return result{}, err
+	}
+	return result{raw: buf.Bytes(), json: map[string]any{"data": transcript.Data(buf.Bytes()), "end": true}}, nil
+}
+
+func spoll(e *env, args []string) (result, error) {
+	stb, err := e.dev.Spoll()
+	if err != nil {
+		return result{}, err
+	}
+	return result{text: fmt.Sprint(stb), json: map[string]any{"status": stb}}, nil
+}
+
+// simple returns a subcommand which performs an operation without output.
+func simple(op func(*linuxgpib.Device) error) func(*env, []string) (result, error) {
+	return func(e *env, args []string) (result, error) {
+		return result{}, op(e.dev)
+	}
+}
+
+func lines(e *env, args []string) (result, error) {
+	l, err := e.board.Lines()
+	if err != nil {
+		return result{}, err
+	}
+	states := map[string]any{}
+	for name, s := range map[string]linuxgpib.LineState{
+		"EOI": l.EOI, "ATN": l.ATN, "SRQ": l.SRQ, "REN": l.REN,
+		"IFC": l.IFC, "NRFD": l.NRFD, "NDAC": l.NDAC, "DAV": l.DAV,
+	} {
+		if s.Valid {
+			states[name] = s.Asserted
+		}
+	}
+	return result{text: l.String(), json: map[string]any{"lines": states}}, nil
+}
+
+func ifc(e *env, args []string) (result, error) {
+	return result{}, e.board.InterfaceClear()
+}
+
+// ask sends each line of input to the device, and prints the responses to
+// queries. In JSON mode it prints an object per line, and continues after
+// errors, which are reported by the exit status of the last one.
+func ask(e *env, args []string) (result, error) {
+	s := bufio.NewScanner(e.in)
+	var last error
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		var r result
+		var err error
+		if strings.Contains(line, "?") {
+			r, err = query(e, []string{line})
+		} else {
+			r, err = write(e, []string{line})
+		}
+		if !e.json {
+			if err != nil {
+				return result{}, err
+			}
+			printResult(e, r)
+			continue
+		}
+		obj := map[string]any{"command": line}
+		for k, v := range r.json {
+			obj[k] = v
+		}
+		if err != nil {
+			obj["error"] = err.Error()
+			last = err
+		}
+		printJSON(e, obj)
+	}
+	if err := s.Err(); err != nil {
+		return result{}, err
+	}
+	if last != nil {
+		return result{}, errReported{last}
+	}
+	return result{}, nil
+}
+
+// errReported wraps an error which has already been printed.
+type errReported struct{ error }
+
+func (e errReported) Unwrap() error { return e.error }
+
+// usageError is an error in the command line.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// exitCode returns the exit status for an error.
+func exitCode(err error) int {
+	var t interface{ Timeout() bool }
+	var u usageError
+	switch {
+	case errors.As(err, &u):
+		return exitUsage
+	case errors.As(err, &t) && t.Timeout():
+		return exitTimeout
+	case errors.Is(err, linuxgpib.ErrNoListeners):
+		return exitNoListener
+	default:
+		return exitError
+	}
+}
+
+func printJSON(e *env, obj map[string]any) {
+	for k, v := range e.header {
+		obj[k] = v
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		b = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	fmt.Fprintf(e.out, "%s\n", b)
+}
+
+func printResult(e *env, r result) {
+	switch {
+	case r.raw != nil:
+		e.out.Write(r.raw)
+	case r.text != "":
+		fmt.Fprintln(e.out, r.text)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: gpib SUBCOMMAND [-verbose] [-json] [-board=BOARD] [-address=ADDRESS] [-timeout=DURATION] [-record=FILE] [ARGS...]")
+	fmt.Fprintln(os.Stderr, "Subcommands: scan idn write query read spoll trigger clear local lines ifc ask")
+}
+
+func main() {
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		usage()
+		os.Exit(exitUsage)
+	}
+	name := os.Args[1]
+	sc, ok := subcommands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown subcommand %q\n", name)
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	fs := flag.NewFlagSet("gpib "+name, flag.ExitOnError)
+	verbose := fs.Bool(
+		"verbose", false,
+		"Turn on logging. Without this, only the result or first error is printed.",
+	)
+	jsonOut := fs.Bool(
+		"json", false,
+		"Print the result, or the error, as a JSON object on standard output.",
+	)
+	board := fs.Int(
+		"board", 0,
+		"The board number. Defaults to zero, which corresponds to /dev/gpib0.",
+	)
+	address := fs.String(
+		"address", "",
+		"The address of the GPIB device, such as 22 or 5.3.",
+	)
+	timeout := fs.Duration(
+		"timeout", 0,
+		"The timeout for each operation. Defaults to 10s, or 1s per device for scan.",
+	)
+	maxBytes := fs.Int(
+		"max", 0,
+		"For read, the maximum number of bytes to read. Zero reads until EOI.",
+	)
+	record := fs.String(
+		"record", "",
+		"Write a transcript of every GPIB operation to the file.",
+	)
+	fs.Parse(os.Args[2:])
+
+	e := &env{
+		json:    *jsonOut,
+		out:     os.Stdout,
+		in:      os.Stdin,
+		max:     *maxBytes,
+		timeout: *timeout,
+		header:  map[string]any{"board": *board},
+	}
+	report := func(err error) {
+		if _, ok := err.(errReported); !ok {
+			if e.json {
+				printJSON(e, map[string]any{"error": err.Error()})
+			} else {
+				fmt.Fprintln(os.Stderr, "gpib:", err)
+			}
+		}
+	}
+
+	// closeRecord closes the transcript, if there is one, and returns any
+	// error writing it. It must be called before exiting, after the board is
+	// closed.
+	var recFile *os.File
+	var rec *transcript.Recorder
+	closeRecord := func() error {
+		if recFile == nil {
+			return nil
+		}
+		err := rec.Err()
+		if cerr := recFile.Close(); err == nil {
+			err = cerr
+		}
+		recFile = nil
+		if err != nil {
+			return fmt.Errorf("failed to write transcript: %w", err)
+		}
+		return nil
+	}
+
+	fail := func(err error) {
+		report(err)
+		if rerr := closeRecord(); rerr != nil {
+			report(rerr)
+		}
+		os.Exit(exitCode(err))
+	}
+
+	var opts []linuxgpib.Option
+	if *verbose {
+		opts = append(opts, linuxgpib.Log(log.Default()))
+	}
+	if name == "scan" && e.timeout == 0 {
+		e.timeout = defaultScanTimeout
+	}
+	if e.timeout != 0 {
+		opts = append(opts, linuxgpib.Timeout(e.timeout))
+	}
+
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			fail(err)
+		}
+		recFile = f
+		rec = transcript.NewRecorder(linuxgpib.DefaultBackend(), f)
+		opts = append(opts, linuxgpib.UseBackend(rec))
+	}
+
+	if sc.device {
+		if *address == "" {
+			fail(usageError("please specify an -address"))
+		}
+		addr, err := linuxgpib.ParseAddress(*address)
+		if err != nil {
+			fail(usageError(err.Error()))
+		}
+		e.addr = addr
+		e.header["address"] = addr.String()
+	}
+
+	b, err := linuxgpib.NewBoard(*board, opts...)
+	if err != nil {
+		fail(fmt.Errorf("failed to open board: %w", err))
+	}
+	e.board = b
+	if sc.device {
+		d, err := b.NewDevice(e.addr)
+		if err != nil {
+			b.Close()
+			fail(fmt.Errorf("failed to open device: %w", err))
+		}
+		e.dev = d
+	}
+
+	r, err := sc.run(e, fs.Args())
+	if err != nil {
+		if e.dev != nil {
+			e.dev.Close()
+		}
+		b.Close()
+		fail(err)
+	}
+	if e.dev != nil {
+		e.dev.Close()
+	}
+	b.Close()
+	if err := closeRecord(); err != nil {
+		fail(err)
+	}
+	if e.json {
+		if name != "ask" {
+			obj := map[string]any{}
+			for k, v := range r.json {
+				obj[k] = v
+			}
+			printJSON(e, obj)
+		}
+		return
+	}
+	printResult(e, r)
+}
@@ -0,0 +1,238 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Gpibconf checks a proposed linux-gpib configuration file and shows how it
+differs from the live one.
+
+Both files are parsed and put into a canonical form before comparison, so
+comments, formatting, the order of sections and settings with default values
+do not appear as differences.
+
+Usage:
+
+	gpibconf [-live=FILE] [-format] PROPOSED
+
+The flags are:
+
+	-live
+		The configuration currently in use. Defaults to /etc/gpib.conf.
+
+	-format
+		Print the canonical form of the proposed file instead of a diff.
+
+The exit status is 0 if the configurations are equivalent, 1 if they differ,
+and 2 if either cannot be read or the proposed one is invalid.
+
+Examples:
+
+	$ gpibconf new.conf
+	--- /etc/gpib.conf
+	+++ new.conf
+	@@ -12,5 +12,5 @@
+	 device {
+	 	minor = 0
+	 	name = "dmm"
+	-	pad = 22
+	+	pad = 23
+	 }
+	$
+
+	$ gpibconf bad.conf
+	bad.conf: line 14: pad 31 is outside the range 0 to 30
+	$
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/msiegen/linuxgpib/gpibconf"
+)
+
+func main() {
+	live := flag.String(
+		"live", gpibconf.DefaultPath,
+		"The configuration currently in use.",
+	)
+	format := flag.Bool(
+		"format", false,
+		"Print the canonical form of the proposed file instead of a diff.",
+	)
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Please specify one PROPOSED file!")
+		os.Exit(2)
+	}
+	proposed := flag.Arg(0)
+
+	p, err := gpibconf.ParseFile(proposed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", proposed, err)
+		os.Exit(2)
+	}
+	if err := p.Validate(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", proposed, line)
+		}
+		os.Exit(2)
+	}
+	p.Sort()
+
+	if *format {
+		os.Stdout.Write(p.Format())
+		return
+	}
+
+	l, err := gpibconf.ParseFile(*live)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *live, err)
+		os.Exit(2)
+	}
+	l.Sort()
+
+	a := lines(l.Format())
+	b := lines(p.Format())
+	hunks := diff(a, b)
+	if len(hunks) == 0 {
+		return
+	}
+	fmt.Printf("--- %s\n+++ %s\n", *live, proposed)
+	for _, h := range hunks {
+		fmt.Print(h)
+	}
+	os.Exit(1)
+}
+
+// lines splits b into lines, each including its newline.
+func lines(b []byte) []string {
+	s := strings.SplitAfter(string(b), "\n")
+	if s[len(s)-1] == "" {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+// edit is one line of a diff: ' ' for a line common to both inputs, '-' for a
+// line only in the first and '+' for a line only in the second.
+type edit struct {
+	op   byte
+	line string
+}
+
+// diff returns the differences between a and b as unified diff hunks.
+func diff(a, b []string) []string {
+	// Find the longest common subsequence by dynamic programming. The files
+	// are small, so the quadratic cost does not matter.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+
+	// Group the edits into hunks with a few lines of context.
+	var hunks []string
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		start := max(k-context, 0)
+		end := k
+		for unchanged := 0; end < len(edits) && unchanged <= 2*context; end++ {
+			if edits[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// Trim trailing context beyond what is needed.
+		for end > k && edits[end-1].op == ' ' && trailing(edits[:end]) > context {
+			end--
+		}
+
+		aStart, bStart := 1, 1
+		for _, e := range edits[:start] {
+			if e.op != '+' {
+				aStart++
+			}
+			if e.op != '-' {
+				bStart++
+			}
+		}
+		var aLen, bLen int
+		var body strings.Builder
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				aLen++
+			}
+			if e.op != '-' {
+				bLen++
+			}
+			body.WriteByte(e.op)
+			body.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		// By convention an empty range starts at the line before it.
+		if aLen == 0 {
+			aStart--
+		}
+		if bLen == 0 {
+			bStart--
+		}
+		hunks = append(hunks, fmt.Sprintf("@@ -%d,%d +%d,%d @@\n%s", aStart, aLen, bStart, bLen, body.String()))
+		k = end
+	}
+	return hunks
+}
+
+// trailing returns the number of unchanged lines at the end of edits.
+func trailing(edits []edit) int {
+	n := 0
+	for i := len(edits) - 1; i >= 0 && edits[i].op == ' '; i-- {
+		n++
+	}
+	return n
+}
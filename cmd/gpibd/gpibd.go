@@ -0,0 +1,121 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Gpibd shares the GPIB boards of this host between many client processes.
+
+It owns the boards and serves GPIB operations over a Unix socket. Programs using
+the linuxgpib package connect with gpibd.Dial and pass the result to
+linuxgpib.UseBackend, after which their Board and Device code works unchanged.
+
+Usage:
+
+	gpibd [-verbose] [-socket=PATH] [-mode=MODE]
+
+The flags are:
+
+	-verbose
+		Log client connections and the devices they open.
+
+	-socket
+		The path of the Unix socket. Defaults to /run/gpibd.sock.
+
+	-mode
+		The permissions of the socket, in octal. Defaults to 0660, so that
+		clients must run as the same user or group as the daemon.
+
+Examples:
+
+	$ gpibd -verbose
+	2026/10/18 09:12:40 Listening on /run/gpibd.sock with version 4.3.4
+	2026/10/18 09:12:44 Client 0xc000012345 connected
+	2026/10/18 09:12:44 Opened address 22 on board 0 as device 16 for client 0xc000012345
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/gpibd"
+)
+
+func main() {
+	verbose := flag.Bool(
+		"verbose", false,
+		"Log client connections and the devices they open.",
+	)
+	socket := flag.String(
+		"socket", gpibd.DefaultSocket,
+		"The path of the Unix socket.",
+	)
+	mode := flag.String(
+		"mode", "0660",
+		"The permissions of the socket, in octal.",
+	)
+
+	flag.Parse()
+
+	perm, err := strconv.ParseUint(*mode, 8, 32)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid -mode:", err)
+		os.Exit(1)
+	}
+
+	// Remove a socket left behind by a previous run, but nothing else.
+	if fi, err := os.Lstat(*socket); err == nil {
+		if fi.Mode().Type() != fs.ModeSocket {
+			fmt.Fprintf(os.Stderr, "%s exists and is not a socket\n", *socket)
+			os.Exit(1)
+		}
+		os.Remove(*socket)
+	}
+
+	l, err := net.Listen("unix", *socket)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to listen:", err)
+		os.Exit(1)
+	}
+	if err := os.Chmod(*socket, fs.FileMode(perm)); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to set socket permissions:", err)
+		l.Close()
+		os.Exit(1)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		l.Close()
+	}()
+
+	be := linuxgpib.DefaultBackend()
+	var logger linuxgpib.Logger
+	if *verbose {
+		logger = log.Default()
+		log.Printf("Listening on %s with version %s", *socket, be.Ibvers())
+	}
+	err = gpibd.NewServer(be, logger).Serve(l)
+	l.Close()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		fmt.Fprintln(os.Stderr, "Failed to serve:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Gpibrun runs test procedures written as plain-text scripts.
+
+The scripts are run in order, sharing variables and open devices, and stop
+with a diagnostic at the first failure. The statements are described in the
+documentation of the [script] package. The values captured by QUERY, READ,
+SPOLL and WAIT SRQ are written out as CSV or JSON.
+
+Usage:
+
+	gpibrun [-verbose] [-trace] [-board=BOARD] [-set NAME=VALUE]... [-output=FILE] [-format=FORMAT] [-record=FILE] SCRIPT...
+
+A SCRIPT of - reads standard input.
+
+The flags are:
+
+	-verbose
+		Turn on logging of GPIB traffic.
+
+	-trace
+		Print each statement to standard error before running it.
+
+	-board
+		The board number. Defaults to zero, which corresponds to /dev/gpib0.
+
+	-set
+		Set a variable before running the scripts. May be repeated.
+
+	-output
+		The file to write captured values to. Defaults to standard output.
+
+	-format
+		The format of the captured values, csv or json. Defaults to json if
+		-output ends in .json, and csv otherwise.
+
+	-record
+		Write a transcript of every GPIB operation to the file, which can be
+		played back with the transcript package to reproduce the session.
+
+The exit status is 0 on success, 1 for errors other than those below, 2 for
+incorrect usage or a mistake in a script, 3 if an operation timed out, and 4 if
+no device was listening at an address.
+
+Examples:
+
+	$ cat psu.gpib
+	WRITE $psu *RST
+	FOR v IN 1 2 5
+	  WRITE $psu VOLT $v;OUTP ON
+	  SLEEP 500ms
+	  QUERY $dmm MEAS:VOLT:DC? -> volts
+	  EXPECT $volts > $v
+	END
+	$ gpibrun -set psu=5 -set dmm=22 psu.gpib
+	time,script,line,address,statement,name,value
+	2026-10-18T10:15:02.5Z,psu.gpib,5,22,QUERY 22 MEAS:VOLT:DC?,volts,+1.00012E+00
+	2026-10-18T10:15:03.1Z,psu.gpib,5,22,QUERY 22 MEAS:VOLT:DC?,volts,+1.99987E+00
+	gpibrun: psu.gpib:6: EXPECT $volts > $v: expected "+1.99987E+00" > "2"
+
+	$ gpibrun -output results.json psu.gpib
+	gpibrun: psu.gpib:1: WRITE $psu *RST: undefined variable "psu"
+*/
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/script"
+	"github.com/msiegen/linuxgpib/transcript"
+)
+
+// Exit codes.
+const (
+	exitError      = 1
+	exitUsage      = 2
+	exitTimeout    = 3
+	exitNoListener = 4
+)
+
+// exitCode returns the exit status for an error.
+func exitCode(err error) int {
+	var t interface{ Timeout() bool }
+	var se *script.SyntaxError
+	switch {
+	case errors.As(err, &se):
+		return exitUsage
+	case errors.As(err, &t) && t.Timeout():
+		return exitTimeout
+	case errors.Is(err, linuxgpib.ErrNoListeners):
+		return exitNoListener
+	default:
+		return exitError
+	}
+}
+
+// recorder writes captured values.
+type recorder interface {
+	record(script.Record) error
+	close() error
+}
+
+// csvRecorder writes each value as it is captured, so that nothing is lost if
+// a script is interrupted.
+type csvRecorder struct {
+	w *csv.Writer
+}
+
+func newCSVRecorder(w io.Writer) *csvRecorder {
+	r := &csvRecorder{csv.NewWriter(w)}
+	r.w.Write([]string{"time", "script", "line", "address", "statement", "name", "value"})
+	return r
+}
+
+func (r *csvRecorder) record(rec script.Record) error {
+	r.w.Write([]string{
+		rec.Time.Format(time.RFC3339Nano),
+		rec.Script,
+		strconv.Itoa(rec.Line),
+		rec.Address.String(),
+		rec.Statement,
+		rec.Name,
+		rec.Value,
+	})
+	r.w.Flush()
+	return r.w.Error()
+}
+
+func (r *csvRecorder) close() error { return nil }
+
+// jsonRecorder writes the values as an array when closed.
+type jsonRecorder struct {
+	w    io.Writer
+	recs []map[string]any
+}
+
+func (r *jsonRecorder) record(rec script.Record) error {
+	obj := map[string]any{
+		"time":      rec.Time.Format(time.RFC3339Nano),
+		"script":    rec.Script,
+		"line":      rec.Line,
+		"address":   rec.Address.String(),
+		"statement": rec.Statement,
+		"value":     rec.Value,
+	}
+	if rec.Name != "" {
+		obj["name"] = rec.Name
+	}
+	r.recs = append(r.recs, obj)
+	return nil
+}
+
+func (r *jsonRecorder) close() error {
+	if r.recs == nil {
+		r.recs = []map[string]any{}
+	}
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.recs)
+}
+
+func main() {
+	verbose := flag.Bool(
+		"verbose", false,
+		"Turn on logging of GPIB traffic.",
+	)
+	trace := flag.Bool(
+		"trace", false,
+		"Print each statement to standard error before running it.",
+	)
+	board := flag.Int(
+		"board", 0,
+		"The board number. Defaults to zero, which corresponds to /dev/gpib0.",
+	)
+	vars := map[string]string{}
+	flag.Func(
+		"set",
+		"Set a variable before running the scripts, as NAME=VALUE. May be repeated.",
+		func(s string) error {
+			name, value, ok := strings.Cut(s, "=")
+			if !ok {
+				return errors.New("want NAME=VALUE")
+			}
+			vars[name] = value
+			return nil
+		},
+	)
+	output := flag.String(
+		"output", "",
+		"The file to write captured values to. Defaults to standard output.",
+	)
+	format := flag.String(
+		"format", "",
+		"The format of the captured values, csv or json. Defaults to json if -output ends in .json, and csv otherwise.",
+	)
+
+	record := flag.String(
+		"record", "",
+		"Write a transcript of every GPIB operation to the file.",
+	)
+
+	flag.Parse()
+
+	// closeRecord closes the transcript, if there is one, and returns any
+	// error writing it. It must be called before exiting, after the board is
+	// closed.
+	var recFile *os.File
+	var tr *transcript.Recorder
+	closeRecord := func() error {
+		if recFile == nil {
+			return nil
+		}
+		err := tr.Err()
+		if cerr := recFile.Close(); err == nil {
+			err = cerr
+		}
+		recFile = nil
+		return err
+	}
+
+	fail := func(code int, v ...any) {
+		fmt.Fprintln(os.Stderr, append([]any{"gpibrun:"}, v...)...)
+		if err := closeRecord(); err != nil {
+			fmt.Fprintln(os.Stderr, "gpibrun: Failed to write transcript:", err)
+		}
+		os.Exit(code)
+	}
+	if flag.NArg() == 0 {
+		fail(exitUsage, "Please specify at least one script!")
+	}
+	if *format == "" {
+		*format = "csv"
+		if strings.HasSuffix(*output, ".json") {
+			*format = "json"
+		}
+	}
+	if *format != "csv" && *format != "json" {
+		fail(exitUsage, "unknown format", *format)
+	}
+
+	// Parse every script up front, so that a mistake in a later one is found
+	// before any instrument is touched.
+	var scripts []*script.Script
+	for _, path := range flag.Args() {
+		var s *script.Script
+		var err error
+		if path == "-" {
+			s, err = script.Parse(os.Stdin, "stdin")
+		} else {
+			var f *os.File
+			if f, err = os.Open(path); err == nil {
+				s, err = script.Parse(f, path)
+				f.Close()
+			}
+		}
+		if err != nil {
+			fail(exitCode(err), err)
+		}
+		scripts = append(scripts, s)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fail(exitError, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	var rec recorder = &jsonRecorder{w: out}
+	if *format == "csv" {
+		rec = newCSVRecorder(out)
+	}
+
+	be := linuxgpib.DefaultBackend()
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			fail(exitError, err)
+		}
+		recFile = f
+		tr = transcript.NewRecorder(be, f)
+		be = tr
+	}
+	opts := []linuxgpib.Option{linuxgpib.UseBackend(be)}
+	if *verbose {
+		opts = append(opts, linuxgpib.Log(log.Default()))
+	}
+	b, err := linuxgpib.NewBoard(*board, opts...)
+	if err != nil {
+		fail(exitError, "Failed to open board:", err)
+	}
+
+	var recErr error
+	r := &script.Runner{
+		Board: b,
+		Vars:  vars,
+		Out:   os.Stderr,
+		Record: func(r script.Record) {
+			if err := rec.record(r); err != nil && recErr == nil {
+				recErr = err
+			}
+		},
+	}
+	if *trace {
+		r.Trace = func(line int, text string) {
+			fmt.Fprintf(os.Stderr, "%d: %s\n", line, text)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for _, s := range scripts {
+		if err = r.Run(ctx, s); err != nil {
+			break
+		}
+	}
+	r.Close()
+	b.Close()
+	if cerr := rec.close(); recErr == nil {
+		recErr = cerr
+	}
+	if err != nil {
+		fail(exitCode(err), err)
+	}
+	if recErr != nil {
+		fail(exitError, "Failed to write results:", recErr)
+	}
+	if err := closeRecord(); err != nil {
+		fail(exitError, "Failed to write transcript:", err)
+	}
+}
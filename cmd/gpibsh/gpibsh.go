@@ -0,0 +1,514 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Gpibsh is an interactive shell for talking to GPIB devices.
+
+It opens a board and an address, and then runs commands typed at its prompt
+to write to the device, run queries, serial poll, trigger and so on. Lines can
+be edited, and earlier lines recalled with the up and down arrow keys. Type
+"help" for the list of commands.
+
+Usage:
+
+	gpibsh [-verbose] [-board=BOARD] [-address=ADDRESS] [-history=PATH]
+
+The flags are:
+
+	-verbose
+		Turn on logging of GPIB traffic. It can be toggled with the verbose
+		command.
+
+	-board
+		The board number. Defaults to zero, which corresponds to /dev/gpib0.
+
+	-address
+		The address of the GPIB device to open at startup, such as 22, or 5.3
+		for primary address 5 and secondary address 3.
+
+	-history
+		The file in which to keep the command history. Defaults to
+		~/.gpibsh_history, and an empty value disables it.
+
+Examples:
+
+	$ gpibsh -address 22
+	gpib0:22> query *IDN?
+	HEWLETT-PACKARD,34401A,0,10-5-2
+	gpib0:22> write CONF:VOLT:DC 10
+	gpib0:22> spoll
+	0x00 (0)
+	gpib0:22> status
+	ibsta 0x0100 [CMPL], ibcnt 0
+	gpib0:22> lines
+	1efb eoi atn srq ren ifc NRFD ndac dav
+	gpib0:22> address 5.3
+	gpib0:5.3> quit
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// defaultReadSize is the number of bytes read by the read command if no limit
+// is given.
+const defaultReadSize = 4096
+
+// switchLogger is a logger which can be turned on and off.
+type switchLogger struct {
+	on bool
+	l  *log.Logger
+}
+
+func (s *switchLogger) Printf(format string, v ...interface{}) {
+	if s.on {
+		s.l.Printf(format, v...)
+	}
+}
+
+// shell is the state of the session.
+type shell struct {
+	be     linuxgpib.Backend
+	logger *switchLogger
+	out    io.Writer
+	board  *linuxgpib.Board
+	index  int
+	dev    *linuxgpib.Device
+	addr   linuxgpib.Address
+}
+
+// command is a shell command.
+type command struct {
+	args string
+	help string
+	run  func(s *shell, arg string) error
+}
+
+var commands map[string]command
+
+// aliases are alternative names for commands.
+var aliases = map[string]string{
+	"addr": "address",
+	"exit": "quit",
+	"q":    "query",
+	"w":    "write",
+	"r":    "read",
+	"?":    "help",
+}
+
+func init() {
+	commands = map[string]command{
+		"open":    {"[BOARD] ADDRESS", "open a device, closing the current one", (*shell).open},
+		"address": {"ADDRESS", "switch to another address on the same board", (*shell).address},
+		"write":   {"TEXT", "send TEXT followed by a newline", (*shell).write},
+		"query":   {"TEXT", "send TEXT and print the response", (*shell).query},
+		"read":    {"[MAX]", "read at most MAX bytes, by default 4096", (*shell).read},
+		"spoll":   {"", "serial poll the device", (*shell).spoll},
+		"trigger": {"", "trigger the device", (*shell).trigger},
+		"clear":   {"", "clear the device", (*shell).clear},
+		"local":   {"", "return the device to local control", (*shell).local},
+		"remote":  {"", "place the device under remote control", (*shell).remote},
+		"ifc":     {"", "send an interface clear on the board", (*shell).ifc},
+		"lines":   {"", "show the bus control lines", (*shell).lines},
+		"timeout": {"DURATION", "set the device's timeout, such as 3s", (*shell).timeout},
+		"status":  {"", "show the status of the last GPIB operation", (*shell).status},
+		"verbose": {"[on|off]", "toggle logging of GPIB traffic", (*shell).verbose},
+		"help":    {"", "show this list", (*shell).help},
+		"quit":    {"", "leave the shell", nil},
+	}
+}
+
+// unescape interprets the escapes \n, \r, \t, \\ and \xNN in text typed by
+// the user. Other backslashes are kept.
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '\\':
+			b.WriteByte('\\')
+		case 'x':
+			if v, err := strconv.ParseUint(s[min(i+2, len(s)):min(i+4, len(s))], 16, 8); err == nil && i+4 <= len(s) {
+				b.WriteByte(byte(v))
+				i += 2
+				break
+			}
+			b.WriteString(s[i : i+2])
+		default:
+			b.WriteString(s[i : i+2])
+		}
+		i++
+	}
+	return b.String()
+}
+
+// printable returns data as text if it is printable, and quoted otherwise.
+func printable(data []byte) string {
+	s := strings.TrimRight(string(data), "\r\n")
+	if !utf8.ValidString(s) {
+		return strconv.Quote(s)
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) && r != '\t' {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+func (s *shell) prompt() string {
+	if s.dev == nil {
+		return fmt.Sprintf("gpib%d> ", s.index)
+	}
+	return fmt.Sprintf("gpib%d:%v> ", s.index, s.addr)
+}
+
+// device returns the open device, or an error if there is none.
+func (s *shell) device() (*linuxgpib.Device, error) {
+	if s.dev == nil {
+		return nil, errors.New("no device is open; use open or address first")
+	}
+	return s.dev, nil
+}
+
+// openBoard opens a board, closing the current one if it differs.
+func (s *shell) openBoard(index int) error {
+	if s.board != nil && s.index == index {
+		return nil
+	}
+	b, err := linuxgpib.NewBoard(index, linuxgpib.UseBackend(s.be), linuxgpib.Log(s.logger))
+	if err != nil {
+		return err
+	}
+	s.closeDevice()
+	if s.board != nil {
+		s.board.Close()
+	}
+	s.board, s.index = b, index
+	return nil
+}
+
+func (s *shell) closeDevice() {
+	if s.dev != nil {
+		s.dev.Close()
+		s.dev = nil
+	}
+}
+
+func (s *shell) open(arg string) error {
+	fields := strings.Fields(arg)
+	index := s.index
+	switch len(fields) {
+	case 1:
+	case 2:
+		var err error
+		if index, err = strconv.Atoi(fields[0]); err != nil {
+			return fmt.Errorf("invalid board %q", fields[0])
+		}
+		fields = fields[1:]
+	default:
+		return errors.New("usage: open [BOARD] ADDRESS")
+	}
+	addr, err := linuxgpib.ParseAddress(fields[0])
+	if err != nil {
+		return err
+	}
+	if err := s.openBoard(index); err != nil {
+		return err
+	}
+	s.closeDevice()
+	if s.dev, err = s.board.NewDevice(addr); err != nil {
+		return err
+	}
+	s.addr = addr
+	return nil
+}
+
+func (s *shell) address(arg string) error {
+	if strings.TrimSpace(arg) == "" {
+		return errors.New("usage: address ADDRESS")
+	}
+	return s.open(arg)
+}
+
+func (s *shell) write(arg string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	_, err = d.Write([]byte(unescape(arg) + "\n"))
+	return err
+}
+
+func (s *shell) query(arg string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	resp, err := d.Query(unescape(arg))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out, printable([]byte(resp)))
+	return nil
+}
+
+func (s *shell) read(arg string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	size := defaultReadSize
+	if arg = strings.TrimSpace(arg); arg != "" {
+		if size, err = strconv.Atoi(arg); err != nil || size <= 0 {
+			return fmt.Errorf("invalid byte limit %q", arg)
+		}
+	}
+	buf := make([]byte, size)
+	n, end, err := d.ReadEnd(buf)
+	if n > 0 {
+		fmt.Fprintln(s.out, printable(buf[:n]))
+	}
+	if err != nil {
+		return err
+	}
+	if !end {
+		fmt.Fprintf(s.out, "(read %d bytes without END; more may follow)\n", n)
+	}
+	return nil
+}
+
+func (s *shell) spoll(string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	stb, err := d.Spoll()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "0x%02x (%d)\n", stb, stb)
+	return nil
+}
+
+func (s *shell) trigger(string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	return d.Trigger()
+}
+
+func (s *shell) clear(string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	return d.Clear()
+}
+
+func (s *shell) local(string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	return d.Local()
+}
+
+func (s *shell) remote(string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	return d.Remote()
+}
+
+func (s *shell) ifc(string) error {
+	if err := s.openBoard(s.index); err != nil {
+		return err
+	}
+	return s.board.InterfaceClear()
+}
+
+func (s *shell) lines(string) error {
+	if err := s.openBoard(s.index); err != nil {
+		return err
+	}
+	l, err := s.board.Lines()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out, l)
+	return nil
+}
+
+func (s *shell) timeout(arg string) error {
+	d, err := s.device()
+	if err != nil {
+		return err
+	}
+	t, err := time.ParseDuration(strings.TrimSpace(arg))
+	if err != nil {
+		return err
+	}
+	return d.SetTimeout(t)
+}
+
+func (s *shell) status(string) error {
+	ibsta := s.be.Ibsta()
+	fmt.Fprintf(s.out, "ibsta 0x%04x [%s], ibcnt %d\n", ibsta, internal.FormatIbsta(ibsta), s.be.Ibcnt())
+	if err := internal.ErrFrom(ibsta, s.be.Iberr, s.be.Ibcnt); err != nil {
+		fmt.Fprintln(s.out, "error:", err)
+	}
+	return nil
+}
+
+func (s *shell) verbose(arg string) error {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "":
+		s.logger.on = !s.logger.on
+	case "on":
+		s.logger.on = true
+	case "off":
+		s.logger.on = false
+	default:
+		return errors.New("usage: verbose [on|off]")
+	}
+	state := "off"
+	if s.logger.on {
+		state = "on"
+	}
+	fmt.Fprintln(s.out, "verbose", state)
+	return nil
+}
+
+func (s *shell) help(string) error {
+	// Show the commands in a fixed, useful order rather than alphabetically.
+	order := "open address write query read spoll trigger clear local remote ifc lines timeout status verbose help quit"
+	for _, name := range strings.Fields(order) {
+		c := commands[name]
+		fmt.Fprintf(s.out, "  %-24s %s\n", strings.TrimSpace(name+" "+c.args), c.help)
+	}
+	fmt.Fprintln(s.out, `TEXT may contain the escapes \n, \r, \t, \\ and \xNN.`)
+	return nil
+}
+
+// run runs a line typed by the user, and reports whether to quit.
+func (s *shell) run(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return false
+	}
+	name, arg, _ := strings.Cut(line, " ")
+	name = strings.ToLower(name)
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+	c, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "Unknown command %q; type help for a list.\n", name)
+		return false
+	}
+	if c.run == nil {
+		return true
+	}
+	if err := c.run(s, arg); err != nil {
+		fmt.Fprintln(s.out, "Error:", err)
+	}
+	return false
+}
+
+func main() {
+	verbose := flag.Bool(
+		"verbose", false,
+		"Turn on logging of GPIB traffic.",
+	)
+	board := flag.Int(
+		"board", 0,
+		"The board number. Defaults to zero, which corresponds to /dev/gpib0.",
+	)
+	address := flag.String(
+		"address", "",
+		"The address of the GPIB device to open at startup, such as 22 or 5.3.",
+	)
+	historyPath := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		historyPath = filepath.Join(home, ".gpibsh_history")
+	}
+	history := flag.String(
+		"history", historyPath,
+		"The file in which to keep the command history. Empty disables it.",
+	)
+
+	flag.Parse()
+
+	s := &shell{
+		be:     linuxgpib.DefaultBackend(),
+		logger: &switchLogger{on: *verbose, l: log.Default()},
+		out:    os.Stdout,
+		index:  *board,
+	}
+	defer func() {
+		s.closeDevice()
+		if s.board != nil {
+			s.board.Close()
+		}
+	}()
+	if *address != "" {
+		if err := s.open(*address); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to open device:", err)
+			os.Exit(1)
+		}
+	}
+
+	lr := newLineReader(os.Stdin, os.Stdout, *history)
+	defer lr.save()
+	for {
+		line, err := lr.readLine(s.prompt())
+		if err == errInterrupt {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintln(os.Stderr, "Failed to read input:", err)
+			}
+			fmt.Fprintln(s.out)
+			return
+		}
+		if s.run(line) {
+			return
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// maxHistory is the number of lines kept in the history file.
+const maxHistory = 1000
+
+// errInterrupt is returned by readLine when the user presses Ctrl-C.
+var errInterrupt = errors.New("interrupted")
+
+// lineReader reads lines from a terminal with simple editing and history, or
+// plain lines if the input is not a terminal.
+type lineReader struct {
+	in      *os.File
+	out     io.Writer
+	r       *bufio.Reader
+	term    bool
+	history []string
+	path    string // history file, or empty for none
+}
+
+func newLineReader(in *os.File, out io.Writer, path string) *lineReader {
+	lr := &lineReader{in: in, out: out, r: bufio.NewReader(in), path: path}
+	_, err := getTermios(in)
+	lr.term = err == nil
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			lr.history = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		}
+	}
+	return lr
+}
+
+func getTermios(f *os.File) (*syscall.Termios, error) {
+	var t syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return nil, errno
+	}
+	return &t, nil
+}
+
+func setTermios(f *os.File, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// add appends a line to the history, and to the history file.
+func (lr *lineReader) add(line string) {
+	if line == "" || len(lr.history) > 0 && lr.history[len(lr.history)-1] == line {
+		return
+	}
+	lr.history = append(lr.history, line)
+	if len(lr.history) > maxHistory {
+		lr.history = lr.history[len(lr.history)-maxHistory:]
+	}
+	if lr.path == "" {
+		return
+	}
+	f, err := os.OpenFile(lr.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(f, line)
+	f.Close()
+}
+
+// save rewrites the history file, to keep it from growing without limit.
+func (lr *lineReader) save() {
+	if lr.path == "" || len(lr.history) == 0 {
+		return
+	}
+	os.WriteFile(lr.path, []byte(strings.Join(lr.history, "\n")+"\n"), 0600)
+}
+
+// readLine prints a prompt and reads a line, which is added to the history.
+func (lr *lineReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(lr.out, prompt)
+	if !lr.term {
+		line, err := lr.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lr.add(strings.TrimSpace(line))
+		return line, nil
+	}
+
+	old, err := getTermios(lr.in)
+	if err != nil {
+		return "", err
+	}
+	raw := *old
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := setTermios(lr.in, &raw); err != nil {
+		return "", err
+	}
+	defer setTermios(lr.in, old)
+
+	line, err := lr.edit(prompt)
+	fmt.Fprint(lr.out, "\r\n")
+	if err == nil {
+		lr.add(strings.TrimSpace(line))
+	}
+	return line, err
+}
+
+// edit reads keys until Enter, maintaining the line and cursor position.
+func (lr *lineReader) edit(prompt string) (string, error) {
+	var line []rune
+	pos := 0
+	hist := len(lr.history)
+	saved := ""
+	redraw := func() {
+		fmt.Fprintf(lr.out, "\r%s%s\x1b[K", prompt, string(line))
+		if n := len(line) - pos; n > 0 {
+			fmt.Fprintf(lr.out, "\x1b[%dD", n)
+		}
+	}
+	recall := func(i int) {
+		if hist == len(lr.history) {
+			saved = string(line)
+		}
+		hist = i
+		if hist == len(lr.history) {
+			line = []rune(saved)
+		} else {
+			line = []rune(lr.history[hist])
+		}
+		pos = len(line)
+		redraw()
+	}
+	for {
+		c, _, err := lr.r.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case '\r', '\n':
+			return string(line), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(lr.out, "^C")
+			return "", errInterrupt
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(line)
+		case 21: // Ctrl-U
+			line, pos = line[pos:], 0
+		case 8, 127: // Backspace
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case 0x1b:
+			if err := lr.escape(&line, &pos, recall, hist); err != nil {
+				return "", err
+			}
+		default:
+			if !unicode.IsPrint(c) || c == utf8.RuneError {
+				continue
+			}
+			line = append(line[:pos], append([]rune{c}, line[pos:]...)...)
+			pos++
+		}
+		redraw()
+	}
+}
+
+// escape handles the cursor keys, which send escape sequences.
+func (lr *lineReader) escape(line *[]rune, pos *int, recall func(int), hist int) error {
+	b, err := lr.r.ReadByte()
+	if err != nil || b != '[' && b != 'O' {
+		return err
+	}
+	b, err = lr.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch b {
+	case 'A':
+		if hist > 0 {
+			recall(hist - 1)
+		}
+	case 'B':
+		if hist < len(lr.history) {
+			recall(hist + 1)
+		}
+	case 'C':
+		if *pos < len(*line) {
+			*pos++
+		}
+	case 'D':
+		if *pos > 0 {
+			*pos--
+		}
+	case 'H':
+		*pos = 0
+	case 'F':
+		*pos = len(*line)
+	case '3':
+		if b, err := lr.r.ReadByte(); err != nil || b != '~' {
+			return err
+		}
+		if *pos < len(*line) {
+			*line = append((*line)[:*pos], (*line)[*pos+1:]...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Gpibsock serves GPIB devices as raw socket network instruments.
+
+Each device is mapped to a TCP port, which accepts newline-terminated [SCPI]
+commands and returns the responses to queries, as LXI instruments do on port
+5025. A device may also have a control port, which accepts DCL to clear the
+device and SPOLL to read its status byte.
+
+Usage:
+
+	gpibsock [-verbose] [-board=BOARD] [-listen=HOST] [-metrics=HOST:PORT] PORT=ADDRESS[,CONTROLPORT]...
+
+The flags are:
+
+	-verbose
+		Turn on logging of GPIB traffic and connection errors.
+
+	-board
+		The board number. Defaults to zero, which corresponds to /dev/gpib0.
+
+	-listen
+		The host name or IP address to listen on. Defaults to all interfaces.
+
+	-metrics
+		The host and port on which to serve Prometheus metrics at /metrics,
+		such as :9488. Disabled by default.
+
+Examples:
+
+	$ gpibsock 5025=22,5000 5026=5.3 &
+	$ echo '*IDN?' | nc -q1 localhost 5025
+	HEWLETT-PACKARD,34401A,0,10-5-2
+	$ echo SPOLL | nc -q1 localhost 5000
+	0
+
+[SCPI]: https://en.wikipedia.org/wiki/Standard_Commands_for_Programmable_Instruments
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/metrics"
+	"github.com/msiegen/linuxgpib/rawsocket"
+)
+
+// mapping is a device and the ports on which it is served.
+type mapping struct {
+	port, control int
+	addr          linuxgpib.Address
+}
+
+// parseMapping parses a PORT=ADDRESS[,CONTROLPORT] argument.
+func parseMapping(s string) (mapping, error) {
+	p, rest, ok := strings.Cut(s, "=")
+	if !ok {
+		return mapping{}, fmt.Errorf("invalid mapping %q: want PORT=ADDRESS[,CONTROLPORT]", s)
+	}
+	a, c, hasControl := strings.Cut(rest, ",")
+	var m mapping
+	var err error
+	if m.port, err = strconv.Atoi(p); err != nil {
+		return mapping{}, fmt.Errorf("invalid mapping %q: bad port", s)
+	}
+	if m.addr, err = linuxgpib.ParseAddress(a); err != nil {
+		return mapping{}, fmt.Errorf("invalid mapping %q: %v", s, err)
+	}
+	if hasControl {
+		if m.control, err = strconv.Atoi(c); err != nil {
+			return mapping{}, fmt.Errorf("invalid mapping %q: bad control port", s)
+		}
+	}
+	return m, nil
+}
+
+func main() {
+	verbose := flag.Bool(
+		"verbose", false,
+		"Turn on logging of GPIB traffic and connection errors.",
+	)
+	board := flag.Int(
+		"board", 0,
+		"The board number. Defaults to zero, which corresponds to /dev/gpib0.",
+	)
+	host := flag.String(
+		"listen", "",
+		"The host name or IP address to listen on. Defaults to all interfaces.",
+	)
+	metricsAddr := flag.String(
+		"metrics", "",
+		"The host and port on which to serve Prometheus metrics at /metrics.",
+	)
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Please specify at least one PORT=ADDRESS mapping!")
+		os.Exit(1)
+	}
+	var ms []mapping
+	for _, arg := range flag.Args() {
+		m, err := parseMapping(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		ms = append(ms, m)
+	}
+
+	var opts []linuxgpib.Option
+	var logger linuxgpib.Logger
+	if *verbose {
+		logger = log.Default()
+		opts = append(opts, linuxgpib.Log(logger))
+	}
+
+	if *metricsAddr != "" {
+		c := metrics.New()
+		opts = append(opts, linuxgpib.Observe(c.Observe))
+		go func() {
+			err := c.ListenAndServe(*metricsAddr)
+			fmt.Fprintln(os.Stderr, "Failed to serve metrics:", err)
+			os.Exit(1)
+		}()
+	}
+
+	b, err := linuxgpib.NewBoard(*board, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to open board:", err)
+		os.Exit(1)
+	}
+
+	errc := make(chan error)
+	listen := func(port int, serve func(net.Listener) error) {
+		l, err := net.Listen("tcp", net.JoinHostPort(*host, strconv.Itoa(port)))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to listen:", err)
+			os.Exit(1)
+		}
+		go func() { errc <- serve(l) }()
+	}
+	for _, m := range ms {
+		d, err := b.NewDevice(m.addr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open device %v: %v\n", m.addr, err)
+			os.Exit(1)
+		}
+		in := rawsocket.NewInstrument(d, logger)
+		listen(m.port, in.Serve)
+		if m.control != 0 {
+			listen(m.control, in.ServeControl)
+		}
+	}
+
+	err = <-errc
+	fmt.Fprintln(os.Stderr, "Failed to serve:", err)
+	os.Exit(1)
+}
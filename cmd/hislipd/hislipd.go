@@ -0,0 +1,148 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Hislipd serves GPIB devices as HiSLIP network instruments.
+
+Each device is given a sub-address, and lab software on other machines opens it
+with a VISA resource string such as TCPIP::host::hislip0::INSTR.
+
+Usage:
+
+	hislipd [-verbose] [-board=BOARD] [-listen=HOST] [-port=PORT] [-overlapped] [-metrics=HOST:PORT] SUBADDRESS=ADDRESS...
+
+The flags are:
+
+	-verbose
+		Turn on logging of GPIB traffic and sessions.
+
+	-board
+		The board number. Defaults to zero, which corresponds to /dev/gpib0.
+
+	-listen
+		The host name or IP address to listen on. Defaults to all interfaces.
+
+	-port
+		The TCP port to listen on. Defaults to 4880.
+
+	-overlapped
+		Start sessions in overlapped mode instead of synchronized mode.
+
+	-metrics
+		The host and port on which to serve Prometheus metrics at /metrics,
+		such as :9488. Disabled by default.
+
+Examples:
+
+	$ hislipd hislip0=22 hislip1=5.3
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/hislip"
+	"github.com/msiegen/linuxgpib/metrics"
+)
+
+func main() {
+	verbose := flag.Bool(
+		"verbose", false,
+		"Turn on logging of GPIB traffic and sessions.",
+	)
+	board := flag.Int(
+		"board", 0,
+		"The board number. Defaults to zero, which corresponds to /dev/gpib0.",
+	)
+	host := flag.String(
+		"listen", "",
+		"The host name or IP address to listen on. Defaults to all interfaces.",
+	)
+	port := flag.Int(
+		"port", hislip.DefaultPort,
+		"The TCP port to listen on.",
+	)
+	overlapped := flag.Bool(
+		"overlapped", false,
+		"Start sessions in overlapped mode instead of synchronized mode.",
+	)
+	metricsAddr := flag.String(
+		"metrics", "",
+		"The host and port on which to serve Prometheus metrics at /metrics.",
+	)
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Please specify at least one SUBADDRESS=ADDRESS mapping!")
+		os.Exit(1)
+	}
+
+	var opts []linuxgpib.Option
+	var logger linuxgpib.Logger
+	if *verbose {
+		logger = log.Default()
+		opts = append(opts, linuxgpib.Log(logger))
+	}
+
+	if *metricsAddr != "" {
+		c := metrics.New()
+		opts = append(opts, linuxgpib.Observe(c.Observe))
+		go func() {
+			err := c.ListenAndServe(*metricsAddr)
+			fmt.Fprintln(os.Stderr, "Failed to serve metrics:", err)
+			os.Exit(1)
+		}()
+	}
+
+	b, err := linuxgpib.NewBoard(*board, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to open board:", err)
+		os.Exit(1)
+	}
+
+	devices := map[string]*linuxgpib.Device{}
+	for _, arg := range flag.Args() {
+		name, a, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			fmt.Fprintf(os.Stderr, "Invalid mapping %q: want SUBADDRESS=ADDRESS\n", arg)
+			os.Exit(1)
+		}
+		addr, err := linuxgpib.ParseAddress(a)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid mapping %q: %v\n", arg, err)
+			os.Exit(1)
+		}
+		d, err := b.NewDevice(addr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open device %v: %v\n", addr, err)
+			os.Exit(1)
+		}
+		devices[name] = d
+	}
+
+	l, err := net.Listen("tcp", net.JoinHostPort(*host, strconv.Itoa(*port)))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to listen:", err)
+		os.Exit(1)
+	}
+	if err := hislip.NewServer(devices, *overlapped, logger).Serve(l); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to serve:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Prologixd makes a GPIB board look like a Prologix GPIB-ETHERNET adapter.
+
+Programs which only know how to talk to a Prologix adapter can connect to it on
+TCP port 1234, or open its pseudo-terminal as if it were a GPIB-USB adapter,
+and use the ++ commands to drive the instruments on the board.
+
+Usage:
+
+	prologixd [-verbose] [-board=BOARD] [-listen=HOST] [-port=PORT] [-pty=PATH]
+
+The flags are:
+
+	-verbose
+		Turn on logging of GPIB traffic and ++ commands.
+
+	-board
+		The board number. Defaults to zero, which corresponds to /dev/gpib0.
+
+	-listen
+		The host name or IP address to listen on. Defaults to all interfaces.
+
+	-port
+		The TCP port to listen on. Defaults to 1234. Zero disables TCP.
+
+	-pty
+		Also serve on a pseudo-terminal, creating a symbolic link to it at the
+		given path.
+
+Examples:
+
+	$ prologixd -pty /tmp/ttyGPIB &
+	$ printf '++addr 22\n*IDN?\n++read eoi\n' | nc -q1 localhost 1234
+	HEWLETT-PACKARD,34401A,0,10-5-2
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/prologix"
+)
+
+func main() {
+	verbose := flag.Bool(
+		"verbose", false,
+		"Turn on logging of GPIB traffic and ++ commands.",
+	)
+	board := flag.Int(
+		"board", 0,
+		"The board number. Defaults to zero, which corresponds to /dev/gpib0.",
+	)
+	host := flag.String(
+		"listen", "",
+		"The host name or IP address to listen on. Defaults to all interfaces.",
+	)
+	port := flag.Int(
+		"port", prologix.DefaultPort,
+		"The TCP port to listen on. Zero disables TCP.",
+	)
+	link := flag.String(
+		"pty", "",
+		"Also serve on a pseudo-terminal, creating a symbolic link to it at the given path.",
+	)
+
+	flag.Parse()
+
+	if *port == 0 && *link == "" {
+		fmt.Fprintln(os.Stderr, "Please specify a -port or a -pty to serve on!")
+		os.Exit(1)
+	}
+
+	var opts []linuxgpib.Option
+	var logger linuxgpib.Logger
+	if *verbose {
+		logger = log.Default()
+		opts = append(opts, linuxgpib.Log(logger))
+	}
+
+	b, err := linuxgpib.NewBoard(*board, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to open board:", err)
+		os.Exit(1)
+	}
+	e := prologix.NewEmulator(b, logger)
+
+	done := make(chan error, 2)
+	var l net.Listener
+	if *port != 0 {
+		l, err = net.Listen("tcp", net.JoinHostPort(*host, strconv.Itoa(*port)))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to listen:", err)
+			os.Exit(1)
+		}
+		go func() { done <- e.Serve(l) }()
+	}
+	if *link != "" {
+		// Replace a link left behind by a previous run, but nothing else.
+		if fi, err := os.Lstat(*link); err == nil {
+			if fi.Mode().Type() != fs.ModeSymlink {
+				fmt.Fprintf(os.Stderr, "%s exists and is not a symbolic link\n", *link)
+				os.Exit(1)
+			}
+			os.Remove(*link)
+		}
+		pty, path, err := prologix.OpenPTY()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to create pseudo-terminal:", err)
+			os.Exit(1)
+		}
+		if err := os.Symlink(path, *link); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to link pseudo-terminal:", err)
+			os.Exit(1)
+		}
+		defer os.Remove(*link)
+		if *verbose {
+			log.Printf("Serving on %s at %s", path, *link)
+		}
+		go func() {
+			e.ServeConn(pty)
+			done <- errors.New("pseudo-terminal closed")
+		}()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-sig:
+	case err = <-done:
+	}
+	if l != nil {
+		l.Close()
+	}
+	e.Close()
+	b.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to serve:", err)
+		if *link != "" {
+			os.Remove(*link)
+		}
+		os.Exit(1)
+	}
+}
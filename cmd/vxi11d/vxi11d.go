@@ -0,0 +1,164 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+/*
+Vxi11d serves GPIB devices as VXI-11 network instruments.
+
+Lab software on other machines opens a device by a VISA resource string such as
+TCPIP::host::gpib0,22::INSTR, which names board 0 and primary address 22. A
+secondary address is given after another comma, as in gpib0,5,3.
+
+VXI-11 clients find the server through the portmapper on port 111. Vxi11d
+answers portmapper requests itself, so the host must not run rpcbind unless
+-portmap is set to zero and the core port is registered with rpcbind by other
+means.
+
+Usage:
+
+	vxi11d [-verbose] [-boards=LIST] [-listen=HOST] [-port=PORT] [-abort=PORT] [-portmap=PORT] [-metrics=HOST:PORT]
+
+The flags are:
+
+	-verbose
+		Turn on logging of GPIB traffic and links.
+
+	-boards
+		A comma-separated list of board numbers to serve. Defaults to 0, which
+		corresponds to /dev/gpib0.
+
+	-listen
+		The host name or IP address to listen on. Defaults to all interfaces.
+
+	-port
+		The port of the core channel. Defaults to 1024.
+
+	-abort
+		The port of the abort channel. Defaults to 1025.
+
+	-portmap
+		The port of the portmapper, or zero to not run one. Defaults to 111,
+		which requires privileges to listen on.
+
+	-metrics
+		The host and port on which to serve Prometheus metrics at /metrics,
+		such as :9488. Disabled by default.
+
+Examples:
+
+	$ sudo vxi11d -verbose
+	2026/10/18 10:02:11 Created link 1 to "gpib0,22"
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/metrics"
+	"github.com/msiegen/linuxgpib/vxi11"
+)
+
+func main() {
+	verbose := flag.Bool(
+		"verbose", false,
+		"Turn on logging of GPIB traffic and links.",
+	)
+	boardList := flag.String(
+		"boards", "0",
+		"A comma-separated list of board numbers to serve.",
+	)
+	host := flag.String(
+		"listen", "",
+		"The host name or IP address to listen on. Defaults to all interfaces.",
+	)
+	port := flag.Int(
+		"port", 1024,
+		"The port of the core channel.",
+	)
+	abortPort := flag.Int(
+		"abort", 1025,
+		"The port of the abort channel.",
+	)
+	portmapPort := flag.Int(
+		"portmap", vxi11.PortmapPort,
+		"The port of the portmapper, or zero to not run one.",
+	)
+	metricsAddr := flag.String(
+		"metrics", "",
+		"The host and port on which to serve Prometheus metrics at /metrics.",
+	)
+
+	flag.Parse()
+
+	var opts []linuxgpib.Option
+	var logger linuxgpib.Logger
+	if *verbose {
+		logger = log.Default()
+		opts = append(opts, linuxgpib.Log(logger))
+	}
+
+	if *metricsAddr != "" {
+		c := metrics.New()
+		opts = append(opts, linuxgpib.Observe(c.Observe))
+		go func() {
+			err := c.ListenAndServe(*metricsAddr)
+			fmt.Fprintln(os.Stderr, "Failed to serve metrics:", err)
+			os.Exit(1)
+		}()
+	}
+
+	boards := map[int]*linuxgpib.Board{}
+	for _, s := range strings.Split(*boardList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid board number %q\n", s)
+			os.Exit(1)
+		}
+		b, err := linuxgpib.NewBoard(n, opts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open board %d: %v\n", n, err)
+			os.Exit(1)
+		}
+		boards[n] = b
+	}
+
+	listen := func(port int) net.Listener {
+		l, err := net.Listen("tcp", net.JoinHostPort(*host, strconv.Itoa(port)))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to listen:", err)
+			os.Exit(1)
+		}
+		return l
+	}
+	core := listen(*port)
+	abort := listen(*abortPort)
+	if *portmapPort != 0 {
+		pm := &vxi11.Portmap{CorePort: core.Addr().(*net.TCPAddr).Port}
+		l := listen(*portmapPort)
+		go func() {
+			if err := pm.Serve(l); err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to serve portmapper:", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	if err := vxi11.NewServer(boards, logger).Serve(core, abort); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to serve:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// A Command is a multiline interface message, which is sent to all devices on
+// the bus with ATN asserted.
+type Command byte
+
+// Universal and addressed commands.
+const (
+	GTL Command = internal.GTL // go to local
+	SDC Command = internal.SDC // selected device clear
+	PPC Command = internal.PPC // parallel poll configure
+	GET Command = internal.GET // group execute trigger
+	TCT Command = internal.TCT // take control
+	LLO Command = internal.LLO // local lockout
+	DCL Command = internal.DCL // device clear
+	PPU Command = internal.PPU // parallel poll unconfigure
+	SPE Command = internal.SPE // serial poll enable
+	SPD Command = internal.SPD // serial poll disable
+	UNL Command = internal.UNL // unlisten
+	UNT Command = internal.UNT // untalk
+	PPD Command = internal.PPD // parallel poll disable
+)
+
+var commandStrings = map[Command]string{
+	GTL: "GTL",
+	SDC: "SDC",
+	PPC: "PPC",
+	GET: "GET",
+	TCT: "TCT",
+	LLO: "LLO",
+	DCL: "DCL",
+	PPU: "PPU",
+	SPE: "SPE",
+	SPD: "SPD",
+	UNL: "UNL",
+	UNT: "UNT",
+}
+
+// Listen returns the command that addresses the device at the given primary
+// address, from 0 to 30, as a listener.
+func Listen(pad int) (Command, error) {
+	if pad < 0 || pad > 30 {
+		return 0, fmt.Errorf("primary address %d is outside the range 0 to 30", pad)
+	}
+	return Command(internal.LAD | pad), nil
+}
+
+// Talk returns the command that addresses the device at the given primary
+// address, from 0 to 30, as the talker.
+func Talk(pad int) (Command, error) {
+	if pad < 0 || pad > 30 {
+		return 0, fmt.Errorf("primary address %d is outside the range 0 to 30", pad)
+	}
+	return Command(internal.TAD | pad), nil
+}
+
+// Secondary returns the command that selects a secondary address, in the range
+// 0x60 to 0x7E as used by linux-gpib. It must follow a Listen or Talk command.
+func Secondary(sad int) (Command, error) {
+	if sad < minSecondary || sad > maxSecondary {
+		return 0, fmt.Errorf("secondary address 0x%x is outside the range 0x60 to 0x7E", sad)
+	}
+	return Command(sad), nil
+}
+
+// PPE returns the parallel poll enable command, which must follow PPC. The
+// device will drive the DIO line numbered 1 to 8 when its individual status
+// matches sense.
+func PPE(line int, sense bool) (Command, error) {
+	if line < 1 || line > 8 {
+		return 0, fmt.Errorf("parallel poll line %d is outside the range 1 to 8", line)
+	}
+	c := Command(internal.PPE | (line - 1))
+	if sense {
+		c |= internal.PPC_SENSE
+	}
+	return c, nil
+}
+
+func (c Command) isListen() bool    { return c&0x60 == internal.LAD && c != UNL }
+func (c Command) isTalk() bool      { return c&0x60 == internal.TAD && c != UNT }
+func (c Command) isSecondary() bool { return c&0x60 == internal.SAD }
+
+// String returns the mnemonic of the command. Secondary commands are shown as
+// addresses; use Commands.String to decode them as parallel poll commands where
+// the context requires it.
+func (c Command) String() string {
+	switch {
+	case c >= 0x80:
+		return fmt.Sprintf("0x%02X", byte(c))
+	case c.isListen():
+		return fmt.Sprintf("LAD%d", c&0x1f)
+	case c.isTalk():
+		return fmt.Sprintf("TAD%d", c&0x1f)
+	case c.isSecondary():
+		return fmt.Sprintf("SAD%d", c&0x1f)
+	}
+	if s, ok := commandStrings[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("0x%02X", byte(c))
+}
+
+// Commands is a sequence of commands which is sent as a unit.
+type Commands []Command
+
+// Bytes returns the commands as they appear on the bus.
+func (cs Commands) Bytes() []byte {
+	b := make([]byte, len(cs))
+	for i, c := range cs {
+		b[i] = byte(c)
+	}
+	return b
+}
+
+// String returns the mnemonics of the commands separated by spaces.
+func (cs Commands) String() string {
+	s := make([]string, len(cs))
+	var prev Command
+	for i, c := range cs {
+		switch {
+		case prev == PPC && c == PPD:
+			s[i] = "PPD"
+		case prev == PPC && c.isSecondary() && c < PPD:
+			sense := 0
+			if c&internal.PPC_SENSE != 0 {
+				sense = 1
+			}
+			s[i] = fmt.Sprintf("PPE%d/%d", c&internal.PPC_DIO_MASK+1, sense)
+		default:
+			s[i] = c.String()
+		}
+		prev = c
+	}
+	return strings.Join(s, " ")
+}
+
+// Validate reports whether the commands are well formed: each one must be a
+// known 7-bit command, and secondary commands must follow a listen, talk, or
+// parallel poll configure command.
+func (cs Commands) Validate() error {
+	var prev Command
+	for i, c := range cs {
+		switch {
+		case c >= 0x80:
+			return fmt.Errorf("command %d: 0x%02X is not a 7-bit command", i, byte(c))
+		case c.isListen() || c.isTalk():
+			if c&0x1f > 30 {
+				return fmt.Errorf("command %d: %v has invalid primary address", i, c)
+			}
+		case c.isSecondary():
+			switch {
+			case prev == PPC:
+				if c > PPD {
+					return fmt.Errorf("command %d: 0x%02X is not a parallel poll command", i, byte(c))
+				}
+			case prev.isListen() || prev.isTalk() || prev.isSecondary():
+				if c&0x1f > 30 {
+					return fmt.Errorf("command %d: %v has invalid secondary address", i, c)
+				}
+			default:
+				return fmt.Errorf("command %d: %v does not follow a primary address", i, c)
+			}
+		case c == UNL || c == UNT:
+		default:
+			if _, ok := commandStrings[c]; !ok {
+				return fmt.Errorf("command %d: 0x%02X is not a known command", i, byte(c))
+			}
+		}
+		prev = c
+	}
+	return nil
+}
+
+// Command sends the commands to all devices on the bus with ATN asserted. The
+// board must be the controller-in-charge.
+//
+// For example, to trigger the devices at addresses 5 and 22 simultaneously:
+//
+//	cmds := Commands{UNL}
+//	for _, pad := range []int{5, 22} {
+//		c, err := Listen(pad)
+//		if err != nil {
+//			return err
+//		}
+//		cmds = append(cmds, c)
+//	}
+//	err := b.Command(append(cmds, GET)...)
+func (b *Board) Command(cmds ...Command) (err error) {
+	cs := Commands(cmds)
+	if err := cs.Validate(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if b.isClosed() {
+		return errors.New("already closed")
+	}
+
+	op, err := b.begin("command", cs.Bytes())
+	if err != nil {
+		return err
+	}
+	defer func() { op.end(int64(len(cs)), nil, err) }()
+
+	started := time.Now()
+	ibsta := b.be.Ibcmd(b.index, cs.Bytes())
+	took := time.Since(started)
+	if err := b.err(ibsta); err != nil {
+		b.logErr("command", err, "Failed to send commands %v on board %d: %v", cs, b.index, err)
+		return err
+	}
+
+	b.logf(slog.LevelDebug, "command", transferAttrs(int64(len(cs)), took), "Sent commands %v in %v on board %d", cs, took.Truncate(time.Millisecond), b.index)
+	return nil
+}
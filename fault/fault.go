@@ -0,0 +1,484 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package fault injects failures into a linuxgpib backend, for testing the
+// error handling of code that uses linuxgpib.
+//
+// A Backend wraps another backend, usually a simulated board from the sim
+// package, and follows a list of rules to decide which calls fail. Random
+// choices are made from a seeded source, so a test sees the same failures
+// every time it runs:
+//
+//	be, err := fault.New(sim.New(), 1,
+//		fault.Rule{Kind: fault.NoListeners, Ops: []string{"Ibdev"}, Limit: 1},
+//		fault.Rule{Kind: fault.Timeout, Bytes: 10, Probability: 0.1},
+//	)
+//	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+package fault
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// Kind is a kind of fault.
+type Kind int
+
+const (
+	// Timeout makes a call time out. Reads first transfer up to Bytes bytes
+	// from the wrapped backend, and writes first pass it up to Bytes bytes
+	// without EOI, since the message was not completed.
+	Timeout Kind = iota
+	// ShortRead makes Ibrd transfer at most Bytes bytes, which should be at
+	// least one, as if the buffer were smaller. The rest of the message is
+	// left for the next read.
+	ShortRead
+	// NoListeners makes a call fail with ENOL, as if no device were present,
+	// which linuxgpib.ErrNoListeners matches.
+	NoListeners
+	// Abort makes a call fail with EABO, which linuxgpib.ErrAborted matches.
+	Abort
+	// BusError makes a call fail with EBUS, which linuxgpib.ErrBusError
+	// matches.
+	BusError
+	// SystemError makes a call fail with EDVR and the given Errno, which
+	// linuxgpib.ErrSystem matches.
+	SystemError
+	// StuckNRFD makes Iblines report that a device is asserting NRFD, so
+	// that waiting for it to become ready, as after a device clear, times
+	// out.
+	StuckNRFD
+	// Delay makes a call wait for a random duration shorter than Delay
+	// before it is performed.
+	Delay
+)
+
+var kindStrings = []string{
+	Timeout:     "Timeout",
+	ShortRead:   "ShortRead",
+	NoListeners: "NoListeners",
+	Abort:       "Abort",
+	BusError:    "BusError",
+	SystemError: "SystemError",
+	StuckNRFD:   "StuckNRFD",
+	Delay:       "Delay",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindStrings) {
+		return kindStrings[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// ioOps are the methods which transfer data or drive the bus, to which
+// rules apply by default.
+var ioOps = []string{
+	"Ibrd", "Ibwrt", "Ibrdf", "Ibwrtf", "Ibclr", "Ibtrg", "Ibrsp", "Ibloc",
+	"Ibcmd", "Ibsic", "SendList",
+}
+
+// otherOps are the remaining methods into which faults can be injected.
+var otherOps = []string{
+	"Ibdev", "Ibfind", "Ibonl", "Ibask", "Ibconfig", "Ibbna", "Ibtmo", "Ibeot",
+	"Ibeos", "Ibwait", "Ibsre", "Iblines", "Ibln",
+}
+
+// Rule describes when to inject a fault.
+type Rule struct {
+	Kind Kind
+	// Ops lists the backend methods the rule applies to, such as "Ibrd" or
+	// "Ibdev". If empty, it applies to those which transfer data or drive
+	// the bus. ShortRead only applies to Ibrd and StuckNRFD only to Iblines,
+	// whatever Ops contains.
+	Ops []string
+	// Skip is the number of calls the rule applies to which are let through
+	// before any fault is injected.
+	Skip int
+	// Limit is the maximum number of faults to inject, or zero for no limit.
+	Limit int
+	// Probability is the chance of injecting a fault into each call after
+	// those skipped, or zero to inject into every one.
+	Probability float64
+
+	Bytes int           // for Timeout and ShortRead
+	Errno syscall.Errno // for SystemError
+	Delay time.Duration // for Delay
+}
+
+// validate reports an error if the rule cannot be followed.
+func (r *Rule) validate() error {
+	if r.Kind < 0 || int(r.Kind) >= len(kindStrings) {
+		return fmt.Errorf("unknown kind %v", r.Kind)
+	}
+	for _, op := range r.Ops {
+		if !slices.Contains(ioOps, op) && !slices.Contains(otherOps, op) {
+			return fmt.Errorf("unknown method %q", op)
+		}
+	}
+	switch {
+	case r.Skip < 0 || r.Limit < 0 || r.Bytes < 0 || r.Delay < 0:
+		return fmt.Errorf("negative count")
+	case r.Probability < 0 || r.Probability > 1:
+		return fmt.Errorf("probability %v out of range", r.Probability)
+	case r.Kind == ShortRead && r.Bytes == 0:
+		return fmt.Errorf("ShortRead needs Bytes")
+	case r.Kind == SystemError && r.Errno == 0:
+		return fmt.Errorf("SystemError needs Errno")
+	}
+	return nil
+}
+
+// applies reports whether the rule applies to a call of the named method.
+func (r *Rule) applies(op string) bool {
+	switch r.Kind {
+	case ShortRead:
+		return op == "Ibrd"
+	case StuckNRFD:
+		return op == "Iblines"
+	}
+	if len(r.Ops) == 0 {
+		return slices.Contains(ioOps, op)
+	}
+	return slices.Contains(r.Ops, op)
+}
+
+// Injection records a fault which was injected.
+type Injection struct {
+	// Call counts the calls to the backend, from one, excluding Ibvers and
+	// the status methods.
+	Call int
+	Op   string
+	// UD is the device or board descriptor, or -1 for Ibfind.
+	UD   int
+	Kind Kind
+}
+
+// Backend is a linuxgpib.Backend which passes calls to another backend, except
+// where a rule injects a fault. It is safe for concurrent use if the backend
+// it wraps is.
+type Backend struct {
+	be    linuxgpib.Backend
+	rules []Rule
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	matched  []int // calls each rule applied to
+	injected []int // faults injected by each rule
+	calls    int
+	log      []Injection
+	res      internal.Result
+	failed   bool // whether res holds the status of the last call
+}
+
+// New returns a Backend which passes calls to be and injects faults according
+// to the rules, making random choices from the given seed. Rules are
+// considered in order, and at most one fault other than a delay is injected
+// into each call. It returns an error if a rule has an unknown kind or method,
+// or values out of range.
+func New(be linuxgpib.Backend, seed int64, rules ...Rule) (*Backend, error) {
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("fault: rule %d: %v", i+1, err)
+		}
+	}
+	return &Backend{
+		be:       be,
+		rules:    rules,
+		rand:     rand.New(rand.NewSource(seed)),
+		matched:  make([]int, len(rules)),
+		injected: make([]int, len(rules)),
+	}, nil
+}
+
+// Injected returns the faults injected so far.
+func (b *Backend) Injected() []Injection {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.log)
+}
+
+// start begins a call of the named method, sleeping for any delays. It returns
+// the rule for a fault to inject, or nil to perform the call normally. The
+// caller must hold mu.
+func (b *Backend) start(op string, ud int) *Rule {
+	b.calls++
+	b.failed = false
+	var inject *Rule
+	for i := range b.rules {
+		r := &b.rules[i]
+		if !r.applies(op) || r.Kind != Delay && inject != nil {
+			continue
+		}
+		b.matched[i]++
+		if b.matched[i] <= r.Skip || r.Limit != 0 && b.injected[i] >= r.Limit {
+			continue
+		}
+		if r.Probability != 0 && b.rand.Float64() >= r.Probability {
+			continue
+		}
+		b.injected[i]++
+		b.log = append(b.log, Injection{Call: b.calls, Op: op, UD: ud, Kind: r.Kind})
+		if r.Kind == Delay {
+			if r.Delay > 0 {
+				time.Sleep(time.Duration(b.rand.Int63n(int64(r.Delay))))
+			}
+			continue
+		}
+		inject = r
+	}
+	return inject
+}
+
+// fail records the failure for a rule, after cnt bytes were transferred, and
+// returns ibsta. The caller must hold mu, and have handled the kinds which do
+// not simply fail.
+func (b *Backend) fail(r *Rule, cnt int) int {
+	b.failed = true
+	switch r.Kind {
+	case Timeout:
+		return b.res.Timeout(cnt)
+	case NoListeners:
+		return b.res.Fail(internal.ENOL)
+	case Abort:
+		return b.res.Fail(internal.EABO)
+	case BusError:
+		return b.res.Fail(internal.EBUS)
+	}
+	return b.res.FailErrno(int(r.Errno))
+}
+
+// do performs a call of the named method, unless a fault is injected instead.
+func (b *Backend) do(op string, ud int, call func() int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if r := b.start(op, ud); r != nil {
+		return b.fail(r, 0)
+	}
+	return call()
+}
+
+func (b *Backend) Ibvers() string { return b.be.Ibvers() }
+
+func (b *Backend) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	ud := -1
+	b.do("Ibdev", board, func() int {
+		ud = b.be.Ibdev(board, pad, sad, tmo, eot, eos)
+		return 0
+	})
+	return ud
+}
+
+func (b *Backend) Ibfind(name string) int {
+	ud := -1
+	b.do("Ibfind", -1, func() int {
+		ud = b.be.Ibfind(name)
+		return 0
+	})
+	return ud
+}
+
+func (b *Backend) Ibonl(ud, v int) int {
+	return b.do("Ibonl", ud, func() int { return b.be.Ibonl(ud, v) })
+}
+
+func (b *Backend) Ibask(ud, option int) (int, int) {
+	var value int
+	ibsta := b.do("Ibask", ud, func() (ibsta int) {
+		ibsta, value = b.be.Ibask(ud, option)
+		return ibsta
+	})
+	return ibsta, value
+}
+
+func (b *Backend) Ibconfig(ud, option, value int) int {
+	return b.do("Ibconfig", ud, func() int { return b.be.Ibconfig(ud, option, value) })
+}
+
+func (b *Backend) Ibbna(ud int, name string) int {
+	return b.do("Ibbna", ud, func() int { return b.be.Ibbna(ud, name) })
+}
+
+func (b *Backend) Ibtmo(ud, v int) int {
+	return b.do("Ibtmo", ud, func() int { return b.be.Ibtmo(ud, v) })
+}
+
+func (b *Backend) Ibeot(ud, v int) int {
+	return b.do("Ibeot", ud, func() int { return b.be.Ibeot(ud, v) })
+}
+
+func (b *Backend) Ibeos(ud, v int) int {
+	return b.do("Ibeos", ud, func() int { return b.be.Ibeos(ud, v) })
+}
+
+func (b *Backend) Ibrd(ud int, buf []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r := b.start("Ibrd", ud)
+	switch {
+	case r == nil:
+		return b.be.Ibrd(ud, buf)
+	case r.Kind == ShortRead:
+		return b.be.Ibrd(ud, buf[:min(r.Bytes, len(buf))])
+	case r.Kind == Timeout && r.Bytes > 0:
+		ibsta := b.be.Ibrd(ud, buf[:min(r.Bytes, len(buf))])
+		if ibsta&internal.ERR != 0 {
+			return ibsta
+		}
+		return b.fail(r, b.be.Ibcnt())
+	}
+	return b.fail(r, 0)
+}
+
+func (b *Backend) Ibwrt(ud int, buf []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r := b.start("Ibwrt", ud)
+	if r == nil {
+		return b.be.Ibwrt(ud, buf)
+	}
+	if r.Kind == Timeout && r.Bytes > 0 {
+		return b.partialWrite(r, ud, buf[:min(r.Bytes, len(buf))])
+	}
+	return b.fail(r, 0)
+}
+
+// partialWrite passes the part of a write sent before it times out to the
+// wrapped backend, with EOT disabled so that it does not end the message. The
+// caller must hold mu.
+func (b *Backend) partialWrite(r *Rule, ud int, buf []byte) int {
+	ibsta, eot := b.be.Ibask(ud, internal.IbaEOT)
+	if ibsta&internal.ERR != 0 {
+		return ibsta
+	}
+	if eot != 0 {
+		if ibsta := b.be.Ibeot(ud, 0); ibsta&internal.ERR != 0 {
+			return ibsta
+		}
+	}
+	ibsta = b.be.Ibwrt(ud, buf)
+	// Keep the write's status, which restoring EOT would replace.
+	b.res.Sta, b.res.Err, b.res.Cnt = ibsta, b.be.Iberr(), b.be.Ibcnt()
+	if eot != 0 {
+		b.be.Ibeot(ud, eot)
+	}
+	if ibsta&internal.ERR != 0 {
+		b.failed = true
+		return ibsta
+	}
+	return b.fail(r, b.res.Cnt)
+}
+
+func (b *Backend) Ibrdf(ud int, path string) int {
+	return b.do("Ibrdf", ud, func() int { return b.be.Ibrdf(ud, path) })
+}
+
+func (b *Backend) Ibwrtf(ud int, path string) int {
+	return b.do("Ibwrtf", ud, func() int { return b.be.Ibwrtf(ud, path) })
+}
+
+func (b *Backend) Ibclr(ud int) int {
+	return b.do("Ibclr", ud, func() int { return b.be.Ibclr(ud) })
+}
+
+func (b *Backend) Ibtrg(ud int) int {
+	return b.do("Ibtrg", ud, func() int { return b.be.Ibtrg(ud) })
+}
+
+func (b *Backend) Ibrsp(ud int) (int, byte) {
+	var spr byte
+	ibsta := b.do("Ibrsp", ud, func() (ibsta int) {
+		ibsta, spr = b.be.Ibrsp(ud)
+		return ibsta
+	})
+	return ibsta, spr
+}
+
+func (b *Backend) Ibloc(ud int) int {
+	return b.do("Ibloc", ud, func() int { return b.be.Ibloc(ud) })
+}
+
+func (b *Backend) Ibwait(ud, mask int) int {
+	return b.do("Ibwait", ud, func() int { return b.be.Ibwait(ud, mask) })
+}
+
+func (b *Backend) Ibcmd(board int, cmd []byte) int {
+	return b.do("Ibcmd", board, func() int { return b.be.Ibcmd(board, cmd) })
+}
+
+func (b *Backend) Ibsic(board int) int {
+	return b.do("Ibsic", board, func() int { return b.be.Ibsic(board) })
+}
+
+func (b *Backend) Ibsre(board, v int) int {
+	return b.do("Ibsre", board, func() int { return b.be.Ibsre(board, v) })
+}
+
+func (b *Backend) Iblines(board int) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r := b.start("Iblines", board)
+	if r != nil && r.Kind != StuckNRFD {
+		return b.fail(r, 0), 0
+	}
+	ibsta, lines := b.be.Iblines(board)
+	if r != nil && ibsta&internal.ERR == 0 {
+		lines |= internal.ValidNRFD | internal.BusNRFD
+	}
+	return ibsta, lines
+}
+
+func (b *Backend) Ibln(board, pad, sad int) (int, int) {
+	var found int
+	ibsta := b.do("Ibln", board, func() (ibsta int) {
+		ibsta, found = b.be.Ibln(board, pad, sad)
+		return ibsta
+	})
+	return ibsta, found
+}
+
+func (b *Backend) SendList(board int, addrs []linuxgpib.Address, buf []byte, eotmode int) int {
+	return b.do("SendList", board, func() int { return b.be.SendList(board, addrs, buf, eotmode) })
+}
+
+func (b *Backend) Ibsta() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed {
+		return b.res.Ibsta()
+	}
+	return b.be.Ibsta()
+}
+
+func (b *Backend) Iberr() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed {
+		return b.res.Iberr()
+	}
+	return b.be.Iberr()
+}
+
+func (b *Backend) Ibcnt() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed {
+		return b.res.Ibcnt()
+	}
+	return b.be.Ibcnt()
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package fault
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+func TestBackend(t *testing.T) {
+	s := sim.New()
+	s.Attach(22, sim.NewSCPI("ACME,DMM,0,1.0"))
+	be, err := New(s, 1,
+		Rule{Kind: NoListeners, Ops: []string{"Ibdev"}, Limit: 1},
+		Rule{Kind: Timeout, Ops: []string{"Ibrd"}, Bytes: 4, Limit: 1},
+		Rule{Kind: SystemError, Ops: []string{"Ibtrg"}, Errno: syscall.EIO},
+		Rule{Kind: BusError, Ops: []string{"Ibrsp"}, Skip: 1},
+		Rule{Kind: Abort, Ops: []string{"Ibloc"}},
+		Rule{Kind: StuckNRFD},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if _, err := b.NewDevice(22); !errors.Is(err, linuxgpib.ErrNoListeners) {
+		t.Errorf("first NewDevice got error %v; want ENOL", err)
+	}
+	d, err := b.NewDevice(22, linuxgpib.Timeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// The read times out partway, and a retry gets the rest.
+	if _, err := d.Write([]byte("*IDN?\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 100)
+	n, err := d.Read(buf)
+	if !os.IsTimeout(err) || string(buf[:n]) != "ACME" {
+		t.Errorf("Read got %q, %v; want a timeout after 4 bytes", buf[:n], err)
+	}
+	n, err = d.Read(buf)
+	if err != nil || string(buf[:n]) != ",DMM,0,1.0\n" {
+		t.Errorf("second Read got %q, %v", buf[:n], err)
+	}
+
+	if err := d.Trigger(); !errors.Is(err, linuxgpib.ErrSystem) || !errors.Is(err, syscall.EIO) {
+		t.Errorf("Trigger got error %v; want EDVR with EIO", err)
+	}
+	if _, err := d.Spoll(); err != nil {
+		t.Errorf("first Spoll got error %v", err)
+	}
+	if _, err := d.Spoll(); !errors.Is(err, linuxgpib.ErrBusError) {
+		t.Errorf("second Spoll got error %v; want EBUS", err)
+	}
+	if err := d.Local(); !errors.Is(err, linuxgpib.ErrAborted) {
+		t.Errorf("Local got error %v; want EABO", err)
+	}
+	if err := d.Clear(); !os.IsTimeout(err) {
+		t.Errorf("Clear got error %v; want a timeout", err)
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	s := sim.New()
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	s.Attach(22, dmm)
+	be, err := New(s, 1, Rule{Kind: Timeout, Ops: []string{"Ibwrt"}, Bytes: 3, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// The bytes reported as sent reach the instrument, but do not end the
+	// message, so the rest completes it.
+	n, err := d.Write([]byte("*RST"))
+	if !os.IsTimeout(err) || n != 3 {
+		t.Errorf("Write got %d, %v; want a timeout after 3 bytes", n, err)
+	}
+	if r := dmm.Received(); len(r) != 0 {
+		t.Errorf("instrument received %q before the message ended", r)
+	}
+	if _, err := d.Write([]byte("T")); err != nil {
+		t.Fatal(err)
+	}
+	if r := dmm.Received(); !reflect.DeepEqual(r, []string{"*RST"}) {
+		t.Errorf("instrument received %q; want *RST", r)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	run := func(seed int64) []Injection {
+		s := sim.New()
+		s.Attach(22, sim.NewSCPI("ACME,DMM,0,1.0"))
+		be, err := New(s, seed,
+			Rule{Kind: Delay, Delay: time.Millisecond},
+			Rule{Kind: Abort, Probability: 0.5},
+			Rule{Kind: ShortRead, Bytes: 3},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		for i := 0; i < 10; i++ {
+			d.Query("*IDN?")
+		}
+		return be.Injected()
+	}
+	first := run(7)
+	if !reflect.DeepEqual(run(7), first) {
+		t.Error("the same seed gave different faults")
+	}
+	kinds := map[Kind]int{}
+	for _, in := range first {
+		kinds[in.Kind]++
+	}
+	if kinds[Delay] == 0 || kinds[Abort] == 0 || kinds[ShortRead] == 0 {
+		t.Errorf("got faults %v; want some of each kind", kinds)
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	for _, r := range []Rule{
+		{Kind: Kind(99)},
+		{Kind: Kind(-1)},
+		{Kind: Timeout, Ops: []string{"ibrd"}},
+		{Kind: Abort, Skip: -1},
+		{Kind: Abort, Probability: 1.5},
+		{Kind: ShortRead},
+		{Kind: SystemError},
+		{Kind: Delay, Delay: -time.Second},
+	} {
+		if _, err := New(sim.New(), 1, Rule{Kind: Abort, Limit: 1}, r); err == nil {
+			t.Errorf("New with %+v succeeded; want an error", r)
+		}
+	}
+}
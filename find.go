@@ -0,0 +1,328 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// findDevice opens the device with the given name from gpib.conf and returns
+// its descriptor and board index. The caller must hold mu.
+func findDevice(name string, o *options) (ud, board int, err error) {
+	be := o.backend
+	ud = be.Ibfind(name)
+	if ud == -1 {
+		if err := backendErr(be, be.Ibsta()); err != nil {
+			o.logf(errLevel(err), append([]slog.Attr{slog.String("op", "find"), slog.String("name", name)}, errAttrs(be, err)...), "Failed to find device %q: %v", name, err)
+			return 0, 0, err
+		}
+		o.logf(slog.LevelError, []slog.Attr{slog.String("op", "find"), slog.String("name", name)}, "Failed to find device %q: unknown error", name)
+		return 0, 0, errors.New("ibfind failed without setting an error")
+	}
+	ibsta, board := be.Ibask(ud, internal.IbaBNA)
+	if err := backendErr(be, ibsta); err != nil {
+		// Only device descriptors have a board, so this is a board's name.
+		// Leave it online, because it may be in use by other devices.
+		o.logf(errLevel(err), append([]slog.Attr{slog.String("op", "find"), slog.String("name", name)}, errAttrs(be, err)...), "Failed to find board for %q: %v", name, err)
+		return 0, 0, fmt.Errorf("%q does not name a device", name)
+	}
+	return ud, board, nil
+}
+
+// OpenByName returns the device with the given name in gpib.conf. The device
+// is configured with the primary and secondary address, end of string mode,
+// and timeout from the configuration file, but any of those may be overridden
+// by options.
+//
+// The device's board is opened with the given options, unless it is already
+// in use in which case it is shared. A board opened here, or by another call
+// to Open, OpenByName or NewDevice, is closed along with the last device opened
+// that way.
+func OpenByName(name string, opts ...Option) (*Device, error) {
+	o := newOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	ud, index, err := findDevice(name, o)
+	if err != nil {
+		return nil, err
+	}
+	b, existing, err := openBoard(index, o)
+	if err != nil {
+		o.backend.Ibonl(ud, 0)
+		return nil, err
+	}
+	owns := b.hold(existing)
+	// A board opened here already has the options, so the device starts
+	// afresh rather than from the board's, to apply them only once.
+	base := b.options
+	if !existing {
+		base = newOptions()
+	}
+	d, err := b.adoptDevice(ud, name, base, opts)
+	if err != nil {
+		if owns {
+			b.unhold()
+		}
+		return nil, err
+	}
+	d.ownsBoard = owns
+	return d, nil
+}
+
+// OpenByName returns the device with the given name in gpib.conf, which must
+// be on this board. See the package-level OpenByName for more details.
+func (b *Board) OpenByName(name string, opts ...Option) (*Device, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if b.isClosed() {
+		return nil, errors.New("already closed")
+	}
+
+	ud, index, err := findDevice(name, b.options)
+	if err != nil {
+		return nil, err
+	}
+	if index != b.index {
+		b.be.Ibonl(ud, 0)
+		return nil, fmt.Errorf("device %q is on board %d, not %d", name, index, b.index)
+	}
+	return b.adoptDevice(ud, name, b.options, opts)
+}
+
+// adoptDevice wraps a descriptor opened by ibfind in a Device, starting from
+// the base options and applying any that differ from the configuration file.
+// The descriptor is taken offline if an error occurs. The caller must hold mu,
+// which is released while waiting for an inter-process lock.
+func (b *Board) adoptDevice(ud int, name string, base *options, opts []Option) (_ *Device, err error) {
+	o := cloneOptions(base)
+
+	var lock *procLock
+	fail := func(format string, v ...interface{}) (*Device, error) {
+		err := fmt.Errorf(format, v...)
+		o.logf(slog.LevelError, append(boardAttrs(b.index, "open"), slog.String("name", name), slog.String("error", err.Error())), "Failed to open device %q on board %d: %v", name, b.index, err)
+		b.be.Ibonl(ud, 0)
+		lock.release()
+		return nil, err
+	}
+	ask := func(option int) (int, error) {
+		ibsta, v := b.be.Ibask(ud, option)
+		return v, b.err(ibsta)
+	}
+
+	if b.isClosed() {
+		return fail("already closed")
+	}
+	pad, err := ask(internal.IbaPAD)
+	if err != nil {
+		return fail("ibask pad: %v", err)
+	}
+	sad, err := ask(internal.IbaSAD)
+	if err != nil {
+		return fail("ibask sad: %v", err)
+	}
+	tmo, err := ask(internal.IbaTMO)
+	if err != nil {
+		return fail("ibask tmo: %v", err)
+	}
+	eosrd, err := ask(internal.IbaEOSrd)
+	if err != nil {
+		return fail("ibask eosrd: %v", err)
+	}
+	eoschar, err := ask(internal.IbaEOSchar)
+	if err != nil {
+		return fail("ibask eoschar: %v", err)
+	}
+
+	// Start from the configuration file, so that only explicit options
+	// override it.
+	o.timeout = internal.Duration(tmo)
+	o.readEOS = ""
+	if eosrd != 0 {
+		o.readEOS = string([]byte{byte(eoschar)})
+	}
+	confTimeout, confEOS := o.timeout, o.readEOS
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	addr := newAddress(pad, sad)
+	if err := addr.Validate(); err != nil {
+		return fail("%v", err)
+	}
+	if b.activeDevices[addr] {
+		return fail("device already in use: %v", addr)
+	}
+
+	// Wait for any other process to finish with the address.
+	mu.Unlock()
+	lock, err = o.lockAddress(b.index, addr)
+	mu.Lock()
+	if err != nil {
+		return fail("%v", err)
+	}
+	if b.isClosed() {
+		return fail("already closed")
+	}
+	if b.activeDevices[addr] {
+		return fail("device already in use: %v", addr)
+	}
+
+	op := &Operation{Kind: "open", Board: b.index, Device: true, Address: addr}
+	if err := o.begin(op); err != nil {
+		return fail("%v", err)
+	}
+	defer func() { op.end(0, nil, err) }()
+	if b.isClosed() {
+		return fail("already closed")
+	}
+	if b.activeDevices[addr] {
+		return fail("device already in use: %v", addr)
+	}
+
+	if o.timeout != confTimeout {
+		if err := b.err(b.be.Ibtmo(ud, internal.Timeout(o.timeout))); err != nil {
+			return fail("ibtmo: %v", err)
+		}
+	}
+	if o.readEOS != confEOS {
+		eos, err := eosMode(o.readEOS)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if err := b.err(b.be.Ibeos(ud, eos)); err != nil {
+			return fail("ibeos: %v", err)
+		}
+	}
+
+	if len(b.activeDevices) == 0 {
+		if err := b.err(b.be.Ibsre(b.index, 1)); err != nil {
+			o.logf(errLevel(err), append(boardAttrs(b.index, "open"), errAttrs(b.be, err)...), "Failed to enable remote mode on board %d", b.index)
+			b.be.Ibonl(ud, 0)
+			lock.release()
+			return nil, errors.New("ibsre failed")
+		}
+	}
+
+	b.activeDevices[addr] = true
+
+	o.logf(slog.LevelInfo, append(deviceAttrs(b.index, addr, ud, "open"), slog.String("name", name)), "Opened %q at address %v on board %d as device %d", name, addr, b.index, ud)
+	return &Device{
+		addr:    addr,
+		board:   b,
+		ud:      ud,
+		be:      b.be,
+		options: o,
+		lock:    lock,
+	}, nil
+}
+
+// Board returns the board through which the device is accessed.
+func (d *Device) Board() *Board {
+	mu.Lock()
+	defer mu.Unlock()
+	return d.board
+}
+
+// Rebind moves the device to the board with the given name in gpib.conf, using
+// ibbna. The board must already be open in this process. If the old board was
+// opened along with the device, it is closed unless other devices opened the
+// same way still use it.
+func (d *Device) Rebind(boardName string) (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.isClosed {
+		return errors.New("already closed")
+	}
+
+	op, err := d.begin("rebind", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { op.end(0, nil, err) }()
+
+	old := d.board
+	if err := d.err(d.board.be.Ibbna(d.ud, boardName)); err != nil {
+		d.logErr("rebind", err, "Failed to rebind address %v device %d to board %q: %v", d.addr, d.ud, boardName, err)
+		return err
+	}
+	ibsta, index := d.board.be.Ibask(d.ud, internal.IbaBNA)
+	if err := d.err(ibsta); err != nil {
+		d.logErr("rebind", err, "Failed to query board of address %v device %d: %v", d.addr, d.ud, err)
+		d.board.be.Ibconfig(d.ud, internal.IbcBNA, old.index)
+		return err
+	}
+	if index == old.index {
+		return nil
+	}
+
+	// Check that the new board can accept the device, and if not, restore the
+	// previous binding. The inter-process lock is not waited for, because the
+	// device is in an intermediate state until this completes.
+	b := activeBoards[boardKey{old.be, index}]
+	var lock *procLock
+	switch {
+	case b == nil:
+		err = fmt.Errorf("board %d is not open", index)
+	case b.activeDevices[d.addr]:
+		err = fmt.Errorf("device already in use on board %d: %v", index, d.addr)
+	default:
+		o := *d.options
+		o.lockWait = 0
+		lock, err = o.lockAddress(b.index, d.addr)
+		if err == nil && len(b.activeDevices) == 0 && d.err(d.board.be.Ibsre(b.index, 1)) != nil {
+			lock.release()
+			err = errors.New("ibsre failed")
+		}
+	}
+	if err != nil {
+		d.logErr("rebind", err, "Failed to rebind address %v device %d to board %q: %v", d.addr, d.ud, boardName, err)
+		d.board.be.Ibconfig(d.ud, internal.IbcBNA, old.index)
+		return err
+	}
+
+	b.activeDevices[d.addr] = true
+	delete(old.activeDevices, d.addr)
+	d.board = b
+	d.lock.release()
+	d.lock = lock
+	d.logf(slog.LevelInfo, "rebind", nil, "Moved address %v device %d from board %d to board %d", d.addr, d.ud, old.index, b.index)
+
+	var sreErr error
+	if len(old.activeDevices) == 0 {
+		if err := d.err(d.board.be.Ibsre(old.index, 0)); err != nil {
+			d.logErr("rebind", err, "Failed to disable remote mode on board %d", old.index)
+			sreErr = errors.New("ibsre failed")
+		}
+	}
+
+	// The new board is shared, so it must not be closed along with the
+	// device.
+	if d.ownsBoard {
+		d.ownsBoard = false
+		if err := old.unhold(); err != nil {
+			d.logErr("rebind", err, "Failed to close board %d: %v", old.index, err)
+			if sreErr == nil {
+				sreErr = err
+			}
+		}
+	}
+	return sreErr
+}
@@ -0,0 +1,306 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"testing"
+	"time"
+)
+
+// newFindBackend returns a backend on which Ibfind knows a device named "dmm"
+// at address 5 on board 1.
+func newFindBackend() *fakeBackend {
+	return &fakeBackend{names: map[string]Resource{"dmm": {Board: 1, Address: 5}}}
+}
+
+func TestRebindOwnedBoard(t *testing.T) {
+	be := newFindBackend()
+	shared, err := NewBoard(0, UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shared.Close()
+
+	// The device opens board 1 for itself, then moves to the shared board.
+	d, err := NewDevice(1, 5, UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Rebind("gpib0"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	_, open := activeBoards[boardKey{be, 1}]
+	mu.Unlock()
+	if open {
+		t.Error("the device's own board is still open after rebinding")
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	_, open = activeBoards[boardKey{be, 0}]
+	mu.Unlock()
+	if !open {
+		t.Error("closing the device closed the shared board")
+	}
+}
+
+func TestRebindRestoresBoard(t *testing.T) {
+	be := newFindBackend()
+	shared, err := NewBoard(0, UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shared.Close()
+	d, err := NewDevice(1, 5, UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// The device stays on its board if the new one cannot be confirmed.
+	be.fail = "Ibask"
+	if err := d.Rebind("gpib0"); err == nil {
+		t.Error("Rebind() succeeded despite failure")
+	}
+	be.fail = ""
+	if got := be.devices[d.ud].board; got != 1 {
+		t.Errorf("device is bound to board %d after a failed rebind; want 1", got)
+	}
+}
+
+// boardOpen reports whether the board is open.
+func boardOpen(be Backend, index int) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return activeBoards[boardKey{be, index}] != nil
+}
+
+func TestOpenOwnsBoard(t *testing.T) {
+	for name, open := range map[string]func(be Backend) (*Device, error){
+		"Open": func(be Backend) (*Device, error) {
+			return Open("GPIB1::5::INSTR", UseBackend(be))
+		},
+		"OpenByName": func(be Backend) (*Device, error) {
+			return OpenByName("dmm", UseBackend(be))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			be := newFindBackend()
+			d, err := open(be)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if boardOpen(be, 1) {
+				t.Error("board is still open after closing the device")
+			}
+
+			// A board which is already open is shared, and stays open.
+			shared, err := NewBoard(1, UseBackend(be))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err = open(be)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if !boardOpen(be, 1) {
+				t.Error("closing the device closed the shared board")
+			}
+			shared.Close()
+
+			// A board opened for a device which then fails is closed.
+			be.fail = "Ibsre"
+			if _, err := open(be); err == nil {
+				t.Error("opening the device succeeded despite failure")
+			}
+			if boardOpen(be, 1) {
+				t.Error("board is still open after failing to open the device")
+			}
+		})
+	}
+}
+
+func TestOpenInterceptsOnce(t *testing.T) {
+	for name, open := range map[string]func(be Backend, opts ...Option) (*Device, error){
+		"Open": func(be Backend, opts ...Option) (*Device, error) {
+			return Open("GPIB1::5::INSTR", append(opts, UseBackend(be))...)
+		},
+		"OpenByName": func(be Backend, opts ...Option) (*Device, error) {
+			return OpenByName("dmm", append(opts, UseBackend(be))...)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			be := newFindBackend()
+			var before, after int
+			count := Intercept(InterceptorFuncs{
+				BeforeFunc: func(op *Operation) error {
+					if op.Kind == "write" {
+						before++
+					}
+					return nil
+				},
+				AfterFunc: func(op *Operation) {
+					if op.Kind == "write" {
+						after++
+					}
+				},
+			})
+			write := func(board string) {
+				t.Helper()
+				d, err := open(be, count)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer d.Close()
+				before, after = 0, 0
+				if _, err := d.Write([]byte("*RST")); err != nil {
+					t.Fatal(err)
+				}
+				if before != 1 || after != 1 {
+					t.Errorf("write on a %s board intercepted %d times before and %d after; want once each", board, before, after)
+				}
+			}
+
+			write("new")
+			shared, err := NewBoard(1, UseBackend(be))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer shared.Close()
+			write("shared")
+		})
+	}
+}
+
+func TestImplicitBoardCloseOrder(t *testing.T) {
+	be := newFindBackend()
+	opens := []func() (*Device, error){
+		func() (*Device, error) { return NewDevice(1, 7, UseBackend(be)) },
+		func() (*Device, error) { return Open("GPIB1::5::INSTR", UseBackend(be)) },
+		func() (*Device, error) { return Open("GPIB1::6::INSTR", UseBackend(be)) },
+	}
+	var ds []*Device
+	for _, open := range opens {
+		d, err := open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds = append(ds, d)
+	}
+
+	// The device which opened the board is closed first, and the board stays
+	// open until the last device which shares it implicitly is closed.
+	for i, d := range ds {
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if last := i == len(ds)-1; boardOpen(be, 1) == last {
+			t.Errorf("after closing %d of %d devices, board open = %v", i+1, len(ds), !last)
+		}
+	}
+	b, err := NewBoard(1, UseBackend(be))
+	if err != nil {
+		t.Fatalf("NewBoard() after closing all devices = %v", err)
+	}
+	b.Close()
+}
+
+func TestImplicitBoardSharedWhileOpening(t *testing.T) {
+	be := newFindBackend()
+	dir := t.TempDir()
+	opts := []Option{UseBackend(be), LockDir(dir), ProcessLock(LockAddress, 5*time.Second)}
+
+	// Hold address 5 as another process would, so that the first device
+	// waits for it after opening the board.
+	o := newOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	l, err := o.lockAddress(1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := make(chan *Device)
+	go func() {
+		d, err := Open("GPIB1::5::INSTR", opts...)
+		if err != nil {
+			t.Error(err)
+		}
+		first <- d
+	}()
+	for !boardOpen(be, 1) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A second device shares the board while the first is still waiting, and
+	// holds it too.
+	d2, err := Open("GPIB1::6::INSTR", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.release()
+	d1 := <-first
+	if d1 == nil {
+		t.FailNow()
+	}
+	if err := d1.Close(); err != nil {
+		t.Error(err)
+	}
+	if !boardOpen(be, 1) {
+		t.Error("board was closed while a device was still using it")
+	}
+	if err := d2.Close(); err != nil {
+		t.Error(err)
+	}
+	if boardOpen(be, 1) {
+		t.Error("board is still open after closing all devices")
+	}
+}
+
+func TestImplicitBoardCloseError(t *testing.T) {
+	be := newFindBackend()
+	shared, err := NewBoard(0, UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shared.Close()
+
+	// The board cannot be closed along with a device which opened it while
+	// another device, opened explicitly, is still using it.
+	for name, release := range map[string]func(d *Device) error{
+		"Close":  func(d *Device) error { return d.Close() },
+		"Rebind": func(d *Device) error { defer d.Close(); return d.Rebind("gpib0") },
+	} {
+		d, err := Open("GPIB1::5::INSTR", UseBackend(be))
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := d.Board().NewDevice(6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := release(d); err == nil {
+			t.Errorf("%s succeeded without closing the board", name)
+		}
+		other.Close()
+		other.Board().Close()
+	}
+}
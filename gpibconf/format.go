@@ -0,0 +1,166 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package gpibconf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Format returns the configuration in the syntax of gpib.conf. The output
+// depends only on the settings, not on the formatting or comments of any file
+// they were parsed from, and settings that have their default values are
+// omitted.
+func (c *Config) Format() []byte {
+	var b bytes.Buffer
+	c.WriteTo(&b)
+	return b.Bytes()
+}
+
+// WriteTo writes the configuration in the syntax of gpib.conf. See Format.
+func (c *Config) WriteTo(w io.Writer) (int64, error) {
+	s := &sectionWriter{w: w}
+	for _, i := range c.Interfaces {
+		s.begin("interface")
+		s.int("minor", i.Minor)
+		s.string("board_type", i.BoardType)
+		s.string("name", i.Name)
+		s.int("pad", i.PAD)
+		s.hexIfSet("sad", i.SAD)
+		s.word("timeout", i.Timeout)
+		s.eos(i.EOS, i.EOSMode)
+		s.boolPtr("set-eot", i.EOT)
+		s.boolPtr("master", i.Master)
+		s.hexIfSet("base", i.Base)
+		s.intIfSet("irq", i.IRQ)
+		s.intIfSet("dma", i.DMA)
+		s.intPtr("pci_bus", i.PCIBus)
+		s.intPtr("pci_slot", i.PCISlot)
+		s.string("sysfs_device_path", i.SysfsDevicePath)
+		s.string("serial_number", i.SerialNumber)
+		s.end()
+	}
+	for _, d := range c.Devices {
+		s.begin("device")
+		s.int("minor", d.Minor)
+		s.string("name", d.Name)
+		s.int("pad", d.PAD)
+		s.hexIfSet("sad", d.SAD)
+		s.word("timeout", d.Timeout)
+		s.eos(d.EOS, d.EOSMode)
+		s.boolPtr("set-eot", d.EOT)
+		s.end()
+	}
+	return s.n, s.err
+}
+
+// sectionWriter writes settings, remembering the first error.
+type sectionWriter struct {
+	w        io.Writer
+	n        int64
+	err      error
+	sections int
+}
+
+func (s *sectionWriter) printf(format string, v ...interface{}) {
+	if s.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(s.w, format, v...)
+	s.n += int64(n)
+	s.err = err
+}
+
+func (s *sectionWriter) begin(kind string) {
+	if s.sections > 0 {
+		s.printf("\n")
+	}
+	s.sections++
+	s.printf("%s {\n", kind)
+}
+
+func (s *sectionWriter) end() {
+	s.printf("}\n")
+}
+
+func (s *sectionWriter) setting(key, value string) {
+	s.printf("\t%s = %s\n", key, value)
+}
+
+func (s *sectionWriter) int(key string, v int) {
+	s.setting(key, strconv.Itoa(v))
+}
+
+func (s *sectionWriter) intIfSet(key string, v int) {
+	if v != 0 {
+		s.int(key, v)
+	}
+}
+
+func (s *sectionWriter) hexIfSet(key string, v int) {
+	if v != 0 {
+		s.setting(key, fmt.Sprintf("0x%x", v))
+	}
+}
+
+func (s *sectionWriter) intPtr(key string, v *int) {
+	if v != nil {
+		s.int(key, *v)
+	}
+}
+
+func (s *sectionWriter) string(key, v string) {
+	if v != "" {
+		s.setting(key, `"`+v+`"`)
+	}
+}
+
+func (s *sectionWriter) word(key, v string) {
+	if v != "" {
+		s.setting(key, v)
+	}
+}
+
+func (s *sectionWriter) bool(key string, v bool) {
+	if v {
+		s.setting(key, "yes")
+	} else {
+		s.setting(key, "no")
+	}
+}
+
+func (s *sectionWriter) boolPtr(key string, v *bool) {
+	if v != nil {
+		s.bool(key, *v)
+	}
+}
+
+func (s *sectionWriter) eos(eos, mode int) {
+	if eos == 0 && mode == 0 {
+		return
+	}
+	s.setting("eos", fmt.Sprintf("0x%02x", eos))
+	for _, f := range []struct {
+		key string
+		bit int
+	}{
+		{"set-reos", REOS},
+		{"set-xeos", XEOS},
+		{"set-bin", BIN},
+	} {
+		if mode&f.bit != 0 {
+			s.bool(f.key, true)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package gpibconf reads, checks and writes the linux-gpib configuration file.
+//
+// The syntax is described in
+// https://linux-gpib.sourceforge.io/doc_html/configuration-gpib-conf.html
+//
+// Unlike the rest of this module, the package does not use the C library, so
+// it can be used on machines where linux-gpib is not installed.
+package gpibconf
+
+import (
+	"sort"
+)
+
+// DefaultPath is where linux-gpib looks for its configuration file.
+const DefaultPath = "/etc/gpib.conf"
+
+// End of string flags, with the same values as in linux-gpib.
+const (
+	REOS = 0x400  // terminate reads on the end of string character
+	XEOS = 0x800  // assert EOI when the end of string character is sent
+	BIN  = 0x1000 // compare all 8 bits of the end of string character
+)
+
+// Timeouts lists the names accepted for the timeout setting, in increasing
+// order of duration.
+var Timeouts = []string{
+	"TNONE", "T10us", "T30us", "T100us", "T300us", "T1ms", "T3ms", "T10ms",
+	"T30ms", "T100ms", "T300ms", "T1s", "T3s", "T10s", "T30s", "T100s",
+	"T300s", "T1000s",
+}
+
+// BoardTypes lists the board_type values known to Validate. Programs may add
+// to it if they use drivers which are not listed.
+var BoardTypes = []string{
+	"agilent_82350b",
+	"agilent_82357a",
+	"cb_pci",
+	"cb_pci_accel",
+	"cb_pci_unaccel",
+	"cb_isa",
+	"cb_isa_accel",
+	"cb_isa_unaccel",
+	"cb_pcmcia",
+	"cb_pcmcia_accel",
+	"cb_pcmcia_unaccel",
+	"cec_pci",
+	"fluke_hybrid",
+	"fluke_unaccel",
+	"fmh_gpib",
+	"fmh_gpib_pci",
+	"fmh_gpib_unaccel",
+	"gpib_bitbang",
+	"hp_82335",
+	"hp_82341",
+	"hp_82341_accel",
+	"hp_82341_unaccel",
+	"ines_pci",
+	"ines_pci_accel",
+	"ines_pci_unaccel",
+	"ines_isa",
+	"ines_pcmcia",
+	"ines_pcmcia_accel",
+	"ines_pcmcia_unaccel",
+	"lpvo_usb_gpib",
+	"ni_pci",
+	"ni_pci_accel",
+	"ni_isa",
+	"ni_isa_accel",
+	"ni_nat4882_isa",
+	"ni_nat4882_isa_accel",
+	"ni_nec_isa",
+	"ni_nec_isa_accel",
+	"ni_pcmcia",
+	"ni_pcmcia_accel",
+	"ni_usb_b",
+	"pc2",
+	"pc2a",
+	"pc2_2a",
+	"pc2a_cb7210",
+	"pc2_2a_cb7210",
+}
+
+// Config is the contents of a configuration file.
+type Config struct {
+	Interfaces []*Interface
+	Devices    []*Device
+}
+
+// Interface is an interface section, describing a board.
+type Interface struct {
+	// Line is the line in the file where the section starts, or zero if the
+	// section was not read from a file.
+	Line int
+
+	Minor     int
+	BoardType string
+	Name      string
+	PAD       int
+	SAD       int
+	Timeout   string
+	EOS       int
+	EOSMode   int // a combination of REOS, XEOS and BIN
+	EOT       *bool
+	Master    *bool
+
+	Base            int
+	IRQ             int
+	DMA             int
+	PCIBus          *int
+	PCISlot         *int
+	SysfsDevicePath string
+	SerialNumber    string
+}
+
+// Device is a device section, naming an instrument on the bus so that it can
+// be opened with ibfind.
+type Device struct {
+	// Line is the line in the file where the section starts, or zero if the
+	// section was not read from a file.
+	Line int
+
+	Minor   int
+	Name    string
+	PAD     int
+	SAD     int // zero for none, or 0x60 to 0x7E
+	Timeout string
+	EOS     int
+	EOSMode int // a combination of REOS, XEOS and BIN
+	EOT     *bool
+}
+
+// Sort orders the interfaces by minor number and the devices by minor number,
+// address and name, so that configurations which differ only in the order of
+// their sections have the same Format.
+func (c *Config) Sort() {
+	sort.SliceStable(c.Interfaces, func(i, j int) bool {
+		return c.Interfaces[i].Minor < c.Interfaces[j].Minor
+	})
+	sort.SliceStable(c.Devices, func(i, j int) bool {
+		a, b := c.Devices[i], c.Devices[j]
+		switch {
+		case a.Minor != b.Minor:
+			return a.Minor < b.Minor
+		case a.PAD != b.PAD:
+			return a.PAD < b.PAD
+		case a.SAD != b.SAD:
+			return a.SAD < b.SAD
+		}
+		return a.Name < b.Name
+	})
+}
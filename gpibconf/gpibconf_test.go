@@ -0,0 +1,163 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package gpibconf
+
+import (
+	"strings"
+	"testing"
+)
+
+const example = `/* This section configures the board. */
+interface {
+	minor = 0       /* board index, minor = 0 uses /dev/gpib0 */
+	board_type = "ni_usb_b"
+	name = "violet"
+	pad = 0
+	sad = 0
+	timeout = T3s
+	eos = 0x0a
+	set-reos = yes
+	set-bin = no
+	set-xeos = no
+	set-eot = yes
+	master = yes
+}
+
+# Instruments.
+device {
+	minor = 0
+	name = "dmm"
+	pad = 22
+	sad = 0
+}
+
+device {
+	minor = 0
+	name = "scope"
+	pad = 5
+	sad = 0x63
+	timeout = T10s
+}
+`
+
+const exampleFormatted = `interface {
+	minor = 0
+	board_type = "ni_usb_b"
+	name = "violet"
+	pad = 0
+	timeout = T3s
+	eos = 0x0a
+	set-reos = yes
+	set-eot = yes
+	master = yes
+}
+
+device {
+	minor = 0
+	name = "scope"
+	pad = 5
+	sad = 0x63
+	timeout = T10s
+}
+
+device {
+	minor = 0
+	name = "dmm"
+	pad = 22
+}
+`
+
+func TestParseFormat(t *testing.T) {
+	c, err := Parse(strings.NewReader(example))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if g := c.Devices[1].Line; g != 25 {
+		t.Errorf("scope is on line %d; want 25", g)
+	}
+	c.Sort()
+	if g := string(c.Format()); g != exampleFormatted {
+		t.Errorf("got:\n%s\nwant:\n%s", g, exampleFormatted)
+	}
+
+	// Formatting must be stable.
+	c2, err := Parse(strings.NewReader(exampleFormatted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := string(c2.Format()); g != exampleFormatted {
+		t.Errorf("reformatted got:\n%s\nwant:\n%s", g, exampleFormatted)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		Input string
+		Want  string
+	}{
+		{"board {}", `line 1: expected interface or device, found "board"`},
+		{"device {\n pad = 5\n pad = 6 }", "line 3: pad is set more than once"},
+		{"device { colour = 5 }", "line 1: colour: unknown device setting"},
+		{"device { pad = five }", `line 1: pad: expected number, found "five"`},
+		{"device { timeout = 3s }", "line 1: timeout: expected one of"},
+		{"device { name = \"dmm }", "line 1: unterminated string"},
+		{"/* device {}", "line 1: unterminated comment"},
+		{"device { set-reos = maybe }", `line 1: set-reos: expected yes or no, found "maybe"`},
+		{"device {\n pad 5 }", `line 2: expected =, found "5"`},
+	} {
+		_, err := Parse(strings.NewReader(c.Input))
+		if err == nil || !strings.HasPrefix(err.Error(), c.Want) {
+			t.Errorf("Parse(%q): got error %v, want %v", c.Input, err, c.Want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c, err := Parse(strings.NewReader(`
+interface { minor = 0 board_type = "ni_pci" name = "a" }
+interface { minor = 0 board_type = "warp_drive" }
+device { minor = 0 name = "a" pad = 31 }
+device { minor = 1 name = "b" pad = 5 sad = 3 }
+device { minor = 0 name = "c" pad = 0 }
+device { minor = 0 name = "d" pad = 7 eos = 0x8a set-reos = yes }
+device { minor = 0 name = "e" pad = 7 set-bin = yes }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded; want errors")
+	}
+	for _, w := range []string{
+		"line 3: minor 0 is already used on line 2",
+		`line 3: unknown board_type "warp_drive"`,
+		`line 4: name "a" is already used on line 2`,
+		"line 4: pad 31 is outside the range 0 to 30",
+		"line 5: sad 0x3 is neither 0 nor in the range 0x60 to 0x7e",
+		"line 5: minor 1 does not match any interface",
+		"line 6: address 0 conflicts with interface on line 2",
+		"line 7: eos 0x8a uses the eighth bit, which requires set-bin",
+		"line 8: set-bin has no effect without set-reos or set-xeos",
+		`line 8: address is the same as device "d" on line 7`,
+	} {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("missing error %q", w)
+		}
+	}
+	if g, w := strings.Count(err.Error(), "\n")+1, 10; g != w {
+		t.Errorf("got %d errors, want %d:\n%v", g, w, err)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package gpibconf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseFile reads and parses the named configuration file.
+func ParseFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse parses a configuration file. It returns an error for malformed syntax
+// or unknown settings, but does not otherwise check the values; use Validate
+// for that.
+func Parse(r io.Reader) (*Config, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lex: &lexer{src: string(b), line: 1}}
+	return p.parse()
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokNumber
+	tokString
+	tokLBrace
+	tokRBrace
+	tokEquals
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c == '/' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c)
+}
+
+// next returns the next token, skipping white space and comments in the C,
+// C++ and shell styles.
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case c == '#' || strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			start := l.line
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return token{}, fmt.Errorf("line %d: unterminated comment", start)
+			}
+			comment := l.src[l.pos : l.pos+2+end+2]
+			l.line += strings.Count(comment, "\n")
+			l.pos += len(comment)
+		default:
+			return l.token()
+		}
+	}
+	return token{kind: tokEOF, line: l.line}, nil
+}
+
+func (l *lexer) token() (token, error) {
+	c := l.src[l.pos]
+	t := token{line: l.line}
+	switch {
+	case c == '{':
+		t.kind, t.text = tokLBrace, "{"
+		l.pos++
+	case c == '}':
+		t.kind, t.text = tokRBrace, "}"
+		l.pos++
+	case c == '=':
+		t.kind, t.text = tokEquals, "="
+		l.pos++
+	case c == '"':
+		end := l.pos + 1
+		for end < len(l.src) && l.src[end] != '"' {
+			if l.src[end] == '\n' {
+				return t, fmt.Errorf("line %d: unterminated string", l.line)
+			}
+			end++
+		}
+		if end == len(l.src) {
+			return t, fmt.Errorf("line %d: unterminated string", l.line)
+		}
+		t.kind, t.text = tokString, l.src[l.pos+1:end]
+		l.pos = end + 1
+	case isWordByte(c):
+		end := l.pos
+		for end < len(l.src) && isWordByte(l.src[end]) {
+			end++
+		}
+		t.kind, t.text = tokWord, l.src[l.pos:end]
+		if isDigit(c) || c == '-' && end > l.pos+1 && isDigit(l.src[l.pos+1]) {
+			t.kind = tokNumber
+		}
+		l.pos = end
+	default:
+		return t, fmt.Errorf("line %d: unexpected character %q", l.line, c)
+	}
+	return t, nil
+}
+
+type parser struct {
+	lex *lexer
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return t, err
+	}
+	if t.kind != kind {
+		return t, fmt.Errorf("line %d: expected %s, found %v", t.line, what, t)
+	}
+	return t, nil
+}
+
+func (p *parser) parse() (*Config, error) {
+	c := &Config{}
+	for {
+		t, err := p.lex.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.kind == tokEOF:
+			return c, nil
+		case t.kind == tokWord && t.text == "interface":
+			i := &Interface{Line: t.line}
+			if err := p.section(i.set); err != nil {
+				return nil, err
+			}
+			c.Interfaces = append(c.Interfaces, i)
+		case t.kind == tokWord && t.text == "device":
+			d := &Device{Line: t.line}
+			if err := p.section(d.set); err != nil {
+				return nil, err
+			}
+			c.Devices = append(c.Devices, d)
+		default:
+			return nil, fmt.Errorf("line %d: expected interface or device, found %v", t.line, t)
+		}
+	}
+}
+
+// section parses the body of a section, calling set for each setting.
+func (p *parser) section(set func(key string, v value) error) error {
+	if _, err := p.expect(tokLBrace, "{"); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for {
+		k, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		if k.kind == tokRBrace {
+			return nil
+		}
+		if k.kind != tokWord {
+			return fmt.Errorf("line %d: expected setting name, found %v", k.line, k)
+		}
+		if _, err := p.expect(tokEquals, "="); err != nil {
+			return err
+		}
+		t, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		if t.kind != tokWord && t.kind != tokNumber && t.kind != tokString {
+			return fmt.Errorf("line %d: expected value for %s, found %v", t.line, k.text, t)
+		}
+		if seen[k.text] {
+			return fmt.Errorf("line %d: %s is set more than once", k.line, k.text)
+		}
+		seen[k.text] = true
+		if err := set(k.text, value(t)); err != nil {
+			return fmt.Errorf("line %d: %s: %v", k.line, k.text, err)
+		}
+	}
+}
+
+// value is the right hand side of a setting.
+type value token
+
+func (v value) int() (int, error) {
+	if v.kind != tokNumber {
+		return 0, fmt.Errorf("expected number, found %v", token(v))
+	}
+	n, err := strconv.ParseInt(v.text, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", v.text)
+	}
+	return int(n), nil
+}
+
+func (v value) intPtr() (*int, error) {
+	n, err := v.int()
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (v value) bool() (bool, error) {
+	if v.kind == tokWord {
+		switch v.text {
+		case "yes", "true":
+			return true, nil
+		case "no", "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("expected yes or no, found %v", token(v))
+}
+
+func (v value) boolPtr() (*bool, error) {
+	b, err := v.bool()
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (v value) string() (string, error) {
+	if v.kind != tokString {
+		return "", fmt.Errorf("expected quoted string, found %v", token(v))
+	}
+	return v.text, nil
+}
+
+func (v value) timeout() (string, error) {
+	if v.kind == tokWord {
+		for _, t := range Timeouts {
+			if v.text == t {
+				return t, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("expected one of %s, found %v", strings.Join(Timeouts, " "), token(v))
+}
+
+// flag sets or clears bit in mode according to v.
+func (v value) flag(mode *int, bit int) error {
+	b, err := v.bool()
+	if err != nil {
+		return err
+	}
+	if b {
+		*mode |= bit
+	} else {
+		*mode &^= bit
+	}
+	return nil
+}
+
+func (i *Interface) set(key string, v value) (err error) {
+	switch key {
+	case "minor":
+		i.Minor, err = v.int()
+	case "board_type":
+		i.BoardType, err = v.string()
+	case "name":
+		i.Name, err = v.string()
+	case "pad":
+		i.PAD, err = v.int()
+	case "sad":
+		i.SAD, err = v.int()
+	case "timeout":
+		i.Timeout, err = v.timeout()
+	case "eos":
+		i.EOS, err = v.int()
+	case "set-reos":
+		err = v.flag(&i.EOSMode, REOS)
+	case "set-xeos":
+		err = v.flag(&i.EOSMode, XEOS)
+	case "set-bin":
+		err = v.flag(&i.EOSMode, BIN)
+	case "set-eot":
+		i.EOT, err = v.boolPtr()
+	case "master":
+		i.Master, err = v.boolPtr()
+	case "base":
+		i.Base, err = v.int()
+	case "irq":
+		i.IRQ, err = v.int()
+	case "dma":
+		i.DMA, err = v.int()
+	case "pci_bus":
+		i.PCIBus, err = v.intPtr()
+	case "pci_slot":
+		i.PCISlot, err = v.intPtr()
+	case "sysfs_device_path":
+		i.SysfsDevicePath, err = v.string()
+	case "serial_number":
+		i.SerialNumber, err = v.string()
+	default:
+		err = fmt.Errorf("unknown interface setting")
+	}
+	return
+}
+
+func (d *Device) set(key string, v value) (err error) {
+	switch key {
+	case "minor":
+		d.Minor, err = v.int()
+	case "name":
+		d.Name, err = v.string()
+	case "pad":
+		d.PAD, err = v.int()
+	case "sad":
+		d.SAD, err = v.int()
+	case "timeout":
+		d.Timeout, err = v.timeout()
+	case "eos":
+		d.EOS, err = v.int()
+	case "set-reos":
+		err = v.flag(&d.EOSMode, REOS)
+	case "set-xeos":
+		err = v.flag(&d.EOSMode, XEOS)
+	case "set-bin":
+		err = v.flag(&d.EOSMode, BIN)
+	case "set-eot":
+		d.EOT, err = v.boolPtr()
+	default:
+		err = fmt.Errorf("unknown device setting")
+	}
+	return
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package gpibconf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxMinor is the largest board minor number supported by linux-gpib.
+const maxMinor = 15
+
+// Validate checks the configuration for mistakes which would prevent
+// linux-gpib from using it, or make it behave unexpectedly. It returns all the
+// problems found, joined into a single error.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(line int, format string, v ...interface{}) {
+		msg := fmt.Sprintf(format, v...)
+		if line > 0 {
+			msg = fmt.Sprintf("line %d: %s", line, msg)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+
+	minors := map[int]*Interface{}
+	names := map[string]int{}
+	checkName := func(line int, name string) {
+		if name == "" {
+			return
+		}
+		if prev, ok := names[name]; ok {
+			add(line, "name %q is already used on line %d", name, prev)
+			return
+		}
+		names[name] = line
+	}
+	checkCommon := func(line, pad, sad, eos, eosMode int, timeout string) {
+		if pad < 0 || pad > 30 {
+			add(line, "pad %d is outside the range 0 to 30", pad)
+		}
+		if sad != 0 && (sad < 0x60 || sad > 0x7e) {
+			add(line, "sad 0x%x is neither 0 nor in the range 0x60 to 0x7e", sad)
+		}
+		if eos < 0 || eos > 0xff {
+			add(line, "eos 0x%x is not a byte", eos)
+		}
+		if eosMode&^(REOS|XEOS|BIN) != 0 {
+			add(line, "eos mode 0x%x has unknown flags", eosMode)
+		}
+		if eosMode&BIN != 0 && eosMode&(REOS|XEOS) == 0 {
+			add(line, "set-bin has no effect without set-reos or set-xeos")
+		}
+		if eosMode&(REOS|XEOS) != 0 && eosMode&BIN == 0 && eos > 0x7f {
+			add(line, "eos 0x%x uses the eighth bit, which requires set-bin", eos)
+		}
+		if timeout != "" {
+			known := false
+			for _, t := range Timeouts {
+				known = known || t == timeout
+			}
+			if !known {
+				add(line, "unknown timeout %q", timeout)
+			}
+		}
+	}
+
+	for _, i := range c.Interfaces {
+		if i.Minor < 0 || i.Minor > maxMinor {
+			add(i.Line, "minor %d is outside the range 0 to %d", i.Minor, maxMinor)
+		}
+		if prev, ok := minors[i.Minor]; ok {
+			add(i.Line, "minor %d is already used on line %d", i.Minor, prev.Line)
+		} else {
+			minors[i.Minor] = i
+		}
+		if i.BoardType == "" {
+			add(i.Line, "board_type is missing")
+		} else {
+			known := false
+			for _, t := range BoardTypes {
+				known = known || t == i.BoardType
+			}
+			if !known {
+				add(i.Line, "unknown board_type %q", i.BoardType)
+			}
+		}
+		checkName(i.Line, i.Name)
+		checkCommon(i.Line, i.PAD, i.SAD, i.EOS, i.EOSMode, i.Timeout)
+	}
+
+	type address struct{ minor, pad, sad int }
+	addresses := map[address]*Device{}
+	for _, d := range c.Devices {
+		if d.Name == "" {
+			add(d.Line, "device has no name")
+		}
+		checkName(d.Line, d.Name)
+		checkCommon(d.Line, d.PAD, d.SAD, d.EOS, d.EOSMode, d.Timeout)
+		i, ok := minors[d.Minor]
+		if !ok {
+			add(d.Line, "minor %d does not match any interface", d.Minor)
+		} else if d.PAD == i.PAD && d.SAD == i.SAD {
+			add(d.Line, "address %d conflicts with interface on line %d", d.PAD, i.Line)
+		}
+		a := address{d.Minor, d.PAD, d.SAD}
+		if prev, ok := addresses[a]; ok {
+			add(d.Line, "address is the same as device %q on line %d", prev.Name, prev.Line)
+		} else {
+			addresses[a] = d
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,267 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package gpibd
+
+import (
+	"io"
+	"net/rpc"
+	"os"
+	"syscall"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// fileChunk is the size of each transfer in Ibrdf and Ibwrtf.
+const fileChunk = 64 * 1024
+
+// Client is a linuxgpib.Backend which performs operations through the daemon.
+// If the connection is lost, operations fail with EDVR and ENOTCONN.
+type Client struct {
+	res internal.Result
+	c   *rpc.Client
+}
+
+// Dial connects to the daemon listening on the given Unix socket.
+func Dial(path string) (*Client, error) {
+	c, err := rpc.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
+// NewClient returns a client which uses an existing RPC connection, such as
+// one obtained by rpc.NewClient on a pipe.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{c: c}
+}
+
+// Close disconnects from the daemon, which releases any devices left open.
+func (c *Client) Close() error {
+	return c.c.Close()
+}
+
+// call performs an operation and records its status. It returns ibsta.
+func (c *Client) call(method string, q Request, r *Reply) int {
+	if err := c.c.Call(serviceName+"."+method, q, r); err != nil {
+		return c.res.FailErrno(int(syscall.ENOTCONN))
+	}
+	c.res.Sta, c.res.Err, c.res.Cnt = r.Sta, r.Err, r.Cnt
+	return r.Sta
+}
+
+func (c *Client) Ibvers() string {
+	var r Reply
+	c.call("Ibvers", Request{}, &r)
+	return r.Version
+}
+
+func (c *Client) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	var r Reply
+	if c.call("Ibdev", Request{Board: board, Pad: pad, Sad: sad, Tmo: tmo, Eot: eot, Eos: eos}, &r)&internal.ERR != 0 {
+		return -1
+	}
+	return r.Value
+}
+
+func (c *Client) Ibfind(name string) int {
+	var r Reply
+	if c.call("Ibfind", Request{Name: name}, &r)&internal.ERR != 0 {
+		return -1
+	}
+	return r.Value
+}
+
+func (c *Client) Ibonl(ud, v int) int {
+	return c.call("Ibonl", Request{UD: ud, V: v}, &Reply{})
+}
+
+func (c *Client) Ibask(ud, option int) (int, int) {
+	var r Reply
+	ibsta := c.call("Ibask", Request{UD: ud, Option: option}, &r)
+	return ibsta, r.Value
+}
+
+func (c *Client) Ibconfig(ud, option, value int) int {
+	return c.call("Ibconfig", Request{UD: ud, Option: option, V: value}, &Reply{})
+}
+
+func (c *Client) Ibbna(ud int, name string) int {
+	return c.call("Ibbna", Request{UD: ud, Name: name}, &Reply{})
+}
+
+func (c *Client) Ibtmo(ud, v int) int {
+	return c.call("Ibtmo", Request{UD: ud, V: v}, &Reply{})
+}
+
+func (c *Client) Ibeot(ud, v int) int {
+	return c.call("Ibeot", Request{UD: ud, V: v}, &Reply{})
+}
+
+func (c *Client) Ibeos(ud, v int) int {
+	return c.call("Ibeos", Request{UD: ud, V: v}, &Reply{})
+}
+
+func (c *Client) Ibrd(ud int, buf []byte) int {
+	var r Reply
+	ibsta := c.call("Ibrd", Request{UD: ud, Count: min(len(buf), maxCount)}, &r)
+	copy(buf, r.Data)
+	return ibsta
+}
+
+func (c *Client) Ibwrt(ud int, buf []byte) int {
+	return c.call("Ibwrt", Request{UD: ud, Data: buf}, &Reply{})
+}
+
+// Ibrdf reads from the device into a file on the client's host, until END.
+func (c *Client) Ibrdf(ud int, path string) int {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return c.fileErr(err)
+	}
+	defer f.Close()
+	buf := make([]byte, fileChunk)
+	total := 0
+	for {
+		ibsta := c.Ibrd(ud, buf)
+		n := c.res.Cnt
+		if ibsta&internal.ERR != 0 && ibsta&internal.TIMO == 0 {
+			return ibsta
+		}
+		if _, err := f.Write(buf[:n]); err != nil {
+			return c.fileErr(err)
+		}
+		total += n
+		if ibsta&(internal.END|internal.TIMO) != 0 {
+			c.res.Cnt = total
+			return ibsta
+		}
+	}
+}
+
+// Ibwrtf writes a file on the client's host to the device.
+func (c *Client) Ibwrtf(ud int, path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return c.fileErr(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return c.fileErr(err)
+	}
+
+	// Only the last chunk is sent with EOI, if that is enabled.
+	_, eot := c.Ibask(ud, internal.IbaEOT)
+	if c.res.Sta&internal.ERR != 0 {
+		return c.res.Sta
+	}
+	defer func() {
+		if eot != 0 {
+			sta, cnt := c.res.Sta, c.res.Cnt
+			c.Ibeot(ud, eot)
+			c.res.Sta, c.res.Cnt = sta, cnt
+		}
+	}()
+
+	buf := make([]byte, fileChunk)
+	remaining, total := fi.Size(), 0
+	for {
+		n, err := io.ReadFull(f, buf[:min(int64(len(buf)), remaining)])
+		if err != nil && n == 0 && remaining > 0 {
+			return c.fileErr(err)
+		}
+		remaining -= int64(n)
+		want := 0
+		if remaining == 0 {
+			want = eot
+		}
+		if c.Ibeot(ud, want)&internal.ERR != 0 {
+			return c.res.Sta
+		}
+		ibsta := c.Ibwrt(ud, buf[:n])
+		total += c.res.Cnt
+		if ibsta&internal.ERR != 0 || remaining == 0 {
+			c.res.Cnt = total
+			return ibsta
+		}
+	}
+}
+
+// fileErr records a file system error, and returns ibsta.
+func (c *Client) fileErr(err error) int {
+	errno := syscall.EIO
+	if e, ok := err.(*os.PathError); ok {
+		if n, ok := e.Err.(syscall.Errno); ok {
+			errno = n
+		}
+	}
+	c.res.Sta, c.res.Err, c.res.Cnt = internal.ERR|internal.CMPL, internal.EFSO, int(errno)
+	return c.res.Sta
+}
+
+func (c *Client) Ibclr(ud int) int {
+	return c.call("Ibclr", Request{UD: ud}, &Reply{})
+}
+
+func (c *Client) Ibtrg(ud int) int {
+	return c.call("Ibtrg", Request{UD: ud}, &Reply{})
+}
+
+func (c *Client) Ibrsp(ud int) (int, byte) {
+	var r Reply
+	ibsta := c.call("Ibrsp", Request{UD: ud}, &r)
+	return ibsta, byte(r.Value)
+}
+
+func (c *Client) Ibloc(ud int) int {
+	return c.call("Ibloc", Request{UD: ud}, &Reply{})
+}
+
+func (c *Client) Ibwait(ud, mask int) int {
+	return c.call("Ibwait", Request{UD: ud, V: mask}, &Reply{})
+}
+
+func (c *Client) Ibcmd(board int, cmd []byte) int {
+	return c.call("Ibcmd", Request{Board: board, Data: cmd}, &Reply{})
+}
+
+func (c *Client) Ibsic(board int) int {
+	return c.call("Ibsic", Request{Board: board}, &Reply{})
+}
+
+func (c *Client) Ibsre(board, v int) int {
+	return c.call("Ibsre", Request{Board: board, V: v}, &Reply{})
+}
+
+func (c *Client) Iblines(board int) (int, int) {
+	var r Reply
+	ibsta := c.call("Iblines", Request{Board: board}, &r)
+	return ibsta, r.Value
+}
+
+func (c *Client) Ibln(board, pad, sad int) (int, int) {
+	var r Reply
+	ibsta := c.call("Ibln", Request{Board: board, Pad: pad, Sad: sad}, &r)
+	return ibsta, r.Value
+}
+
+func (c *Client) SendList(board int, addrs []linuxgpib.Address, buf []byte, eotmode int) int {
+	return c.call("SendList", Request{Board: board, Addrs: addrs, Data: buf, Eot: eotmode}, &Reply{})
+}
+
+func (c *Client) Ibsta() int { return c.res.Sta }
+func (c *Client) Iberr() int { return c.res.Err }
+func (c *Client) Ibcnt() int { return c.res.Cnt }
+
+var _ linuxgpib.Backend = (*Client)(nil)
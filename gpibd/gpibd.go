@@ -0,0 +1,64 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package gpibd shares GPIB boards between processes through a daemon.
+//
+// The daemon owns the boards and serves the operations of a linuxgpib.Backend
+// to clients over a Unix socket, using net/rpc. A client obtains a backend with
+// Dial and passes it to linuxgpib.UseBackend, after which Board and Device work
+// as if the boards were local:
+//
+//	be, err := gpibd.Dial(gpibd.DefaultSocket)
+//	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+//
+// Each device address may be open in only one client at a time, and remote
+// enable stays asserted while any client wants it. A client may operate the
+// interface of a board, for example to clear it, only once it has opened a
+// device on the board. Devices are taken offline when the client that opened
+// them disconnects.
+package gpibd
+
+import (
+	"github.com/msiegen/linuxgpib"
+)
+
+// DefaultSocket is the path on which the daemon listens by default.
+const DefaultSocket = "/run/gpibd.sock"
+
+// serviceName is the name of the RPC service.
+const serviceName = "GPIB"
+
+// maxCount is the largest read the server performs for one request. The
+// client reads no more than this at a time.
+const maxCount = 1 << 20
+
+// Request holds the arguments of a backend operation. Only the fields used by
+// the operation are set.
+type Request struct {
+	UD, Board int
+	Option, V int
+	Pad, Sad  int
+	Tmo       int
+	Eot, Eos  int
+	Name      string
+	Data      []byte
+	Count     int
+	Addrs     []linuxgpib.Address
+}
+
+// Reply holds the results of a backend operation, including the values of
+// ibsta, iberr and ibcnt afterwards.
+type Reply struct {
+	Sta, Err, Cnt int
+	Value         int
+	Data          []byte
+	Version       string
+}
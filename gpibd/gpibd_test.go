@@ -0,0 +1,320 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package gpibd
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// fakeBackend is a board with instruments that answer *IDN? and echo other
+// commands.
+type fakeBackend struct {
+	internal.Result
+	next    int
+	devices map[int]int // primary address by descriptor
+	pending map[int]string
+
+	mu    sync.Mutex // guards ren and quiet, which are used by tests
+	ren   int
+	quiet bool // no service requests are pending
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{next: 16, devices: map[int]int{}, pending: map[int]string{}}
+}
+
+func (f *fakeBackend) Ibvers() string { return "fake" }
+
+func (f *fakeBackend) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	ud := f.next
+	f.next++
+	f.devices[ud] = pad
+	f.Done(0, 0)
+	return ud
+}
+
+func (f *fakeBackend) Ibfind(name string) int { f.Fail(internal.EDVR); return -1 }
+
+func (f *fakeBackend) Ibonl(ud, v int) int {
+	if v == 0 {
+		delete(f.devices, ud)
+	}
+	return f.Done(0, 0)
+}
+
+func (f *fakeBackend) Ibask(ud, option int) (int, int) {
+	pad, ok := f.devices[ud]
+	if !ok {
+		return f.Fail(internal.EARG), 0
+	}
+	switch option {
+	case internal.IbaPAD:
+		return f.Done(0, 0), pad
+	case internal.IbaBNA:
+		return f.Done(0, 0), 0
+	}
+	return f.Done(0, 0), 0
+}
+
+func (f *fakeBackend) Ibconfig(ud, option, value int) int { return f.Done(0, 0) }
+func (f *fakeBackend) Ibbna(ud int, name string) int      { return f.Fail(internal.EARG) }
+func (f *fakeBackend) Ibtmo(ud, v int) int                { return f.Done(0, 0) }
+func (f *fakeBackend) Ibeot(ud, v int) int                { return f.Done(0, 0) }
+func (f *fakeBackend) Ibeos(ud, v int) int                { return f.Done(0, 0) }
+
+func (f *fakeBackend) Ibrd(ud int, buf []byte) int {
+	s, ok := f.pending[ud]
+	if !ok {
+		return f.Timeout(0)
+	}
+	n := copy(buf, s)
+	if n < len(s) {
+		f.pending[ud] = s[n:]
+		return f.Done(0, n)
+	}
+	delete(f.pending, ud)
+	return f.Done(internal.END, n)
+}
+
+func (f *fakeBackend) Ibwrt(ud int, buf []byte) int {
+	s := strings.TrimSpace(string(buf))
+	if s == "*IDN?" {
+		s = "FAKE,INSTRUMENT"
+	}
+	f.pending[ud] = s + "\n"
+	return f.Done(0, len(buf))
+}
+
+func (f *fakeBackend) Ibrdf(ud int, path string) int  { return f.Fail(internal.ECAP) }
+func (f *fakeBackend) Ibwrtf(ud int, path string) int { return f.Fail(internal.ECAP) }
+func (f *fakeBackend) Ibclr(ud int) int               { return f.Done(0, 0) }
+func (f *fakeBackend) Ibtrg(ud int) int               { return f.Done(0, 0) }
+func (f *fakeBackend) Ibrsp(ud int) (int, byte)       { return f.Done(0, 0), 0x40 }
+func (f *fakeBackend) Ibloc(ud int) int               { return f.Done(0, 0) }
+func (f *fakeBackend) Ibwait(ud, mask int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.quiet {
+		return f.Done(0, 0)
+	}
+	return f.Done(internal.RQS, 0)
+}
+func (f *fakeBackend) Ibcmd(board int, cmd []byte) int {
+	return f.Done(0, len(cmd))
+}
+func (f *fakeBackend) Ibsic(board int) int { return f.Done(0, 0) }
+func (f *fakeBackend) Ibsre(board, v int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ren = v
+	return f.Done(0, 0)
+}
+func (f *fakeBackend) remoteEnable() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ren != 0
+}
+
+func (f *fakeBackend) Iblines(board int) (int, int) {
+	return f.Done(0, 0), internal.ValidNRFD
+}
+func (f *fakeBackend) Ibln(board, pad, sad int) (int, int) { return f.Done(0, 0), 0 }
+func (f *fakeBackend) SendList(board int, addrs []linuxgpib.Address, buf []byte, eotmode int) int {
+	return f.Done(0, len(buf))
+}
+
+// serve starts a server on a temporary socket and returns its path.
+func serve(t *testing.T, be linuxgpib.Backend) string {
+	path := filepath.Join(t.TempDir(), "gpibd.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go NewServer(be, nil).Serve(l)
+	return path
+}
+
+func dial(t *testing.T, path string) *Client {
+	c, err := Dial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestDevice(t *testing.T) {
+	path := serve(t, newFakeBackend())
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(dial(t, path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if got, err := d.Query("*IDN?"); err != nil || got != "FAKE,INSTRUMENT" {
+		t.Errorf("Query() = %q, %v; want %q", got, err, "FAKE,INSTRUMENT")
+	}
+	if _, err := d.Read(make([]byte, 10)); err != internal.TimeoutErr {
+		t.Errorf("Read() with nothing to read = %v; want timeout", err)
+	}
+	if got, err := d.Spoll(); err != nil || got != 0x40 {
+		t.Errorf("Spoll() = %02X, %v; want 40", got, err)
+	}
+	if got, err := d.WaitSRQ(); err != nil || got != 0x40 {
+		t.Errorf("WaitSRQ() = %02X, %v; want 40", got, err)
+	}
+	if err := d.Trigger(); err != nil {
+		t.Errorf("Trigger() = %v", err)
+	}
+	if err := d.Clear(); err != nil {
+		t.Errorf("Clear() = %v", err)
+	}
+}
+
+func TestSharing(t *testing.T) {
+	be := newFakeBackend()
+	path := serve(t, be)
+	c1, c2 := dial(t, path), dial(t, path)
+
+	if _, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(c1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(c2)); err == nil {
+		t.Error("NewDevice() succeeded for an address in use by another client")
+	}
+	d2, err := linuxgpib.NewDevice(0, 5, linuxgpib.UseBackend(c2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Remote enable stays on until both clients are done.
+	if err := d2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !be.remoteEnable() {
+		t.Error("remote enable was turned off while in use")
+	}
+
+	// Disconnecting releases the address for others.
+	c1.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(c2))
+		if err == nil {
+			d.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("address was not released on disconnect: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if be.remoteEnable() {
+		t.Error("remote enable was left on after all clients were done")
+	}
+}
+
+func TestBoardAccess(t *testing.T) {
+	path := serve(t, newFakeBackend())
+	c1, c2 := dial(t, path), dial(t, path)
+	if ud := c2.Ibdev(0, 22, 0, internal.T1s, 1, 0); ud < 0 {
+		t.Fatal("Ibdev() failed")
+	}
+
+	// Only a client with a device on the board may operate its interface.
+	for name, op := range map[string]func(c *Client) int{
+		"Ibsic":    func(c *Client) int { return c.Ibsic(0) },
+		"Ibcmd":    func(c *Client) int { return c.Ibcmd(0, []byte{0x14}) },
+		"Ibsre":    func(c *Client) int { return c.Ibsre(0, 1) },
+		"Ibconfig": func(c *Client) int { return c.Ibconfig(0, internal.IbcPAD, 0) },
+		"SendList": func(c *Client) int {
+			return c.SendList(0, []linuxgpib.Address{22}, []byte("*RST"), internal.NLend)
+		},
+	} {
+		if ibsta := op(c1); ibsta&internal.ERR == 0 || c1.Iberr() != internal.EARG {
+			t.Errorf("%s() without a device = %#x, error %d; want EARG", name, ibsta, c1.Iberr())
+		}
+		if ibsta := op(c2); ibsta&internal.ERR != 0 {
+			t.Errorf("%s() with a device = %#x, error %d", name, ibsta, c2.Iberr())
+		}
+	}
+}
+
+func TestReadCount(t *testing.T) {
+	c := dial(t, serve(t, newFakeBackend()))
+	ud := c.Ibdev(0, 22, 0, internal.T1s, 1, 0)
+	if ud < 0 {
+		t.Fatal("Ibdev() failed")
+	}
+	for _, count := range []int{-1, maxCount + 1} {
+		var r Reply
+		if ibsta := c.call("Ibrd", Request{UD: ud, Count: count}, &r); ibsta&internal.ERR == 0 || c.Iberr() != internal.EARG {
+			t.Errorf("Ibrd with count %d = %#x, error %d; want EARG", count, ibsta, c.Iberr())
+		}
+	}
+
+	// A buffer larger than the server allows is filled a piece at a time.
+	c.Ibwrt(ud, []byte("*IDN?"))
+	buf := make([]byte, maxCount+10)
+	if ibsta := c.Ibrd(ud, buf); ibsta&internal.ERR != 0 || string(buf[:c.Ibcnt()]) != "FAKE,INSTRUMENT\n" {
+		t.Errorf("Ibrd() = %#x, %q; want %q", ibsta, buf[:c.Ibcnt()], "FAKE,INSTRUMENT\n")
+	}
+}
+
+func TestWaitDoesNotBlock(t *testing.T) {
+	be := newFakeBackend()
+	be.quiet = true
+	path := serve(t, be)
+	c1, c2 := dial(t, path), dial(t, path)
+	ud := c1.Ibdev(0, 22, 0, internal.TNONE, 1, 0)
+	if ud < 0 {
+		t.Fatal("Ibdev() failed")
+	}
+
+	done := make(chan int)
+	go func() { done <- c1.Ibwait(ud, internal.RQS) }()
+
+	// Another client can use the bus while the first waits.
+	d, err := linuxgpib.NewDevice(0, 5, linuxgpib.UseBackend(c2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := d.Query("*IDN?"); err != nil || got != "FAKE,INSTRUMENT" {
+		t.Errorf("Query() = %q, %v; want %q", got, err, "FAKE,INSTRUMENT")
+	}
+	select {
+	case ibsta := <-done:
+		t.Fatalf("Ibwait() = %#x before the service request", ibsta)
+	default:
+	}
+
+	be.mu.Lock()
+	be.quiet = false
+	be.mu.Unlock()
+	select {
+	case ibsta := <-done:
+		if ibsta&internal.RQS == 0 {
+			t.Errorf("Ibwait() = %#x; want RQS", ibsta)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ibwait() did not return after the service request")
+	}
+}
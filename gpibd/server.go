@@ -0,0 +1,561 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package gpibd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// waitPollInterval is how often Ibwait checks for the conditions it waits for.
+const waitPollInterval = 20 * time.Millisecond
+
+// deviceKey identifies a device address on a board.
+type deviceKey struct {
+	board int
+	addr  linuxgpib.Address
+}
+
+// Server serves a backend to clients. Backend calls from all clients are
+// performed one at a time, because each reports its status through state that
+// the next call overwrites, as with the linux-gpib globals. Waits for device
+// events are made by polling, so that they do not hold up other clients.
+type Server struct {
+	be     linuxgpib.Backend
+	logger linuxgpib.Logger
+
+	// io is held for each backend call and the reading of its status. It is
+	// acquired before mu.
+	io sync.Mutex
+	// mu guards the records of which client owns what, and is only held
+	// while they are consulted or updated.
+	mu      sync.Mutex
+	devices map[int]*session          // device descriptors by owner
+	addrs   map[deviceKey]*session    // open addresses by owner
+	ren     map[int]map[*session]bool // clients wanting remote enable by board
+}
+
+// NewServer returns a server for the given backend, which is normally
+// linuxgpib.DefaultBackend. The logger may be nil.
+func NewServer(be linuxgpib.Backend, logger linuxgpib.Logger) *Server {
+	return &Server{
+		be:      be,
+		logger:  logger,
+		devices: map[int]*session{},
+		addrs:   map[deviceKey]*session{},
+		ren:     map[int]map[*session]bool{},
+	}
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.logger != nil {
+		s.logger.Printf(format, v...)
+	}
+}
+
+// Serve accepts connections on l and serves each in a new goroutine. It
+// returns when l is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single client until it disconnects, and then releases
+// the devices it left open.
+func (s *Server) ServeConn(conn io.ReadWriteCloser) {
+	ss := &session{s: s, ud: map[int]deviceKey{}, boards: map[int]bool{}}
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(serviceName, ss); err != nil {
+		s.logf("Failed to register session: %v", err)
+		conn.Close()
+		return
+	}
+	s.logf("Client %p connected", ss)
+	srv.ServeConn(conn)
+	ss.release()
+	s.logf("Client %p disconnected", ss)
+}
+
+// session is the RPC receiver for a single client. Its exported methods are
+// the operations of the linuxgpib.Backend interface.
+type session struct {
+	s  *Server
+	ud map[int]deviceKey // devices opened by this client, guarded by s.mu
+	// boards holds the boards on which the client has opened a device or
+	// the board itself, and whose interface it may therefore operate. It is
+	// guarded by s.mu.
+	boards map[int]bool
+	// gone is set once the client has disconnected, so that any of its calls
+	// still in progress fail.
+	gone bool
+}
+
+// release takes the session's devices offline and withdraws its request for
+// remote enable.
+func (ss *session) release() {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+
+	s.mu.Lock()
+	ss.gone = true
+	uds := ss.ud
+	ss.ud = map[int]deviceKey{}
+	for ud, k := range uds {
+		delete(s.devices, ud)
+		delete(s.addrs, k)
+	}
+	var boards []int
+	for board, c := range s.ren {
+		if c[ss] {
+			delete(c, ss)
+			if len(c) == 0 {
+				boards = append(boards, board)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for ud, k := range uds {
+		s.be.Ibonl(ud, 0)
+		s.logf("Released address %v device %d on board %d", k.addr, ud, k.board)
+	}
+	for _, board := range boards {
+		s.be.Ibsre(board, 0)
+	}
+}
+
+// reply fills r with the backend's status, for the given ibsta.
+func (ss *session) reply(ibsta int, r *Reply) error {
+	be := ss.s.be
+	r.Sta, r.Err, r.Cnt = ibsta, be.Iberr(), be.Ibcnt()
+	return nil
+}
+
+// fail fills r with an error status.
+func fail(iberr, errno int, r *Reply) error {
+	r.Sta, r.Err, r.Cnt = internal.ERR|internal.CMPL, iberr, errno
+	return nil
+}
+
+// check reports whether ud may be used by this client, which is the case for
+// the devices and boards opened by the client. The caller must hold s.io, so
+// that the answer stays true until its backend call is done.
+func (ss *session) check(ud int, r *Reply) bool {
+	s := ss.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := ss.ud[ud]
+	if !ss.gone && (ok || ud < internal.GPIB_MAX_NUM_BOARDS && ss.boards[ud]) {
+		return true
+	}
+	fail(internal.EARG, 0, r)
+	return false
+}
+
+// checkBoard reports whether the interface of a board may be operated by this
+// client. The caller must hold s.io.
+func (ss *session) checkBoard(board int, r *Reply) bool {
+	s := ss.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !ss.gone && ss.boards[board] {
+		return true
+	}
+	fail(internal.EARG, 0, r)
+	return false
+}
+
+// lookup returns the address of a device opened by this client. The caller
+// must hold s.io.
+func (ss *session) lookup(ud int) (deviceKey, bool) {
+	s := ss.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := ss.ud[ud]
+	return k, ok && !ss.gone
+}
+
+// claim records that a newly opened device descriptor belongs to this client,
+// or takes it offline if its address is in use by another client. It returns
+// the descriptor, or -1 on failure. The caller must hold s.io.
+func (ss *session) claim(ud int, r *Reply) int {
+	s := ss.s
+	if ud == -1 {
+		ss.reply(s.be.Ibsta(), r)
+		return -1
+	}
+	ibsta, board := s.be.Ibask(ud, internal.IbaBNA)
+	if ibsta&internal.ERR != 0 {
+		// A board descriptor, which is shared.
+		s.mu.Lock()
+		ss.boards[ud] = true
+		s.mu.Unlock()
+		r.Sta, r.Err, r.Cnt = internal.CMPL, 0, 0
+		return ud
+	}
+	_, pad := s.be.Ibask(ud, internal.IbaPAD)
+	_, sad := s.be.Ibask(ud, internal.IbaSAD)
+	k := deviceKey{board, linuxgpib.Address(pad | sad<<8)}
+	s.mu.Lock()
+	o := s.addrs[k]
+	if o == nil || o == ss {
+		s.devices[ud] = ss
+		s.addrs[k] = ss
+		ss.ud[ud] = k
+		ss.boards[board] = true
+	}
+	s.mu.Unlock()
+	if o != nil && o != ss {
+		s.be.Ibonl(ud, 0)
+		s.logf("Refused address %v on board %d to client %p: in use by client %p", k.addr, board, ss, o)
+		fail(internal.EDVR, int(syscall.EBUSY), r)
+		return -1
+	}
+	s.logf("Opened address %v on board %d as device %d for client %p", k.addr, board, ud, ss)
+	r.Sta, r.Err, r.Cnt = internal.CMPL, 0, 0
+	return ud
+}
+
+func (ss *session) Ibvers(_ Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	r.Version = s.be.Ibvers()
+	return nil
+}
+
+func (ss *session) Ibdev(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	r.Value = ss.claim(s.be.Ibdev(q.Board, q.Pad, q.Sad, q.Tmo, q.Eot, q.Eos), r)
+	return nil
+}
+
+func (ss *session) Ibfind(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	r.Value = ss.claim(s.be.Ibfind(q.Name), r)
+	return nil
+}
+
+func (ss *session) Ibonl(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	k, ok := ss.lookup(q.UD)
+	if !ok {
+		// Boards are shared, so they may not be taken offline by a client.
+		return fail(internal.EARG, 0, r)
+	}
+	ibsta := s.be.Ibonl(q.UD, q.V)
+	if q.V == 0 {
+		s.mu.Lock()
+		delete(ss.ud, q.UD)
+		delete(s.devices, q.UD)
+		delete(s.addrs, k)
+		s.mu.Unlock()
+		s.logf("Closed address %v device %d on board %d for client %p", k.addr, q.UD, k.board, ss)
+	}
+	return ss.reply(ibsta, r)
+}
+
+func (ss *session) Ibask(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	ibsta, v := s.be.Ibask(q.UD, q.Option)
+	r.Value = v
+	return ss.reply(ibsta, r)
+}
+
+func (ss *session) Ibconfig(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibconfig(q.UD, q.Option, q.V), r)
+}
+
+func (ss *session) Ibbna(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	k, ok := ss.lookup(q.UD)
+	if !ok {
+		return fail(internal.EARG, 0, r)
+	}
+	ibsta := s.be.Ibbna(q.UD, q.Name)
+	if err := ss.reply(ibsta, r); err != nil || ibsta&internal.ERR != 0 {
+		return err
+	}
+	_, board := s.be.Ibask(q.UD, internal.IbaBNA)
+	n := deviceKey{board, k.addr}
+	s.mu.Lock()
+	o := s.addrs[n]
+	if o == nil || o == ss {
+		delete(s.addrs, k)
+		s.addrs[n] = ss
+		ss.ud[q.UD] = n
+		ss.boards[board] = true
+	}
+	s.mu.Unlock()
+	if o != nil && o != ss {
+		s.be.Ibconfig(q.UD, internal.IbcBNA, k.board)
+		return fail(internal.EDVR, int(syscall.EBUSY), r)
+	}
+	return nil
+}
+
+func (ss *session) Ibtmo(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibtmo(q.UD, q.V), r)
+}
+
+func (ss *session) Ibeot(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibeot(q.UD, q.V), r)
+}
+
+func (ss *session) Ibeos(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibeos(q.UD, q.V), r)
+}
+
+func (ss *session) Ibrd(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	if q.Count < 0 || q.Count > maxCount {
+		return fail(internal.EARG, 0, r)
+	}
+	buf := make([]byte, q.Count)
+	ibsta := s.be.Ibrd(q.UD, buf)
+	ss.reply(ibsta, r)
+	// On failure ibcnt may be an errno, unless the read timed out part way.
+	if ibsta&internal.ERR == 0 || ibsta&internal.TIMO != 0 {
+		if r.Cnt >= 0 && r.Cnt <= len(buf) {
+			r.Data = buf[:r.Cnt]
+		}
+	}
+	return nil
+}
+
+func (ss *session) Ibwrt(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibwrt(q.UD, q.Data), r)
+}
+
+func (ss *session) Ibclr(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibclr(q.UD), r)
+}
+
+func (ss *session) Ibtrg(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibtrg(q.UD), r)
+}
+
+func (ss *session) Ibrsp(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	ibsta, spr := s.be.Ibrsp(q.UD)
+	r.Value = int(spr)
+	return ss.reply(ibsta, r)
+}
+
+func (ss *session) Ibloc(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.check(q.UD, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibloc(q.UD), r)
+}
+
+// Ibwait polls for the conditions in the mask, releasing the backend between
+// polls, rather than waiting in the backend for them. If the mask includes
+// TIMO, it gives up after the descriptor's timeout.
+func (ss *session) Ibwait(q Request, r *Reply) error {
+	s := ss.s
+	want := q.V &^ internal.TIMO
+	var deadline time.Time
+	for first := true; ; first = false {
+		s.io.Lock()
+		if !ss.check(q.UD, r) {
+			s.io.Unlock()
+			return nil
+		}
+		if first && q.V&internal.TIMO != 0 {
+			ibsta, tmo := s.be.Ibask(q.UD, internal.IbaTMO)
+			if ibsta&internal.ERR == 0 && tmo != internal.TNONE {
+				deadline = time.Now().Add(internal.Duration(tmo))
+			}
+		}
+		ibsta := s.be.Ibwait(q.UD, 0)
+		done := want == 0 || ibsta&(want|internal.ERR) != 0
+		if !done && !deadline.IsZero() && !time.Now().Before(deadline) {
+			ibsta |= internal.TIMO
+			done = true
+		}
+		if done {
+			ss.reply(ibsta, r)
+			s.io.Unlock()
+			return nil
+		}
+		s.io.Unlock()
+		time.Sleep(waitPollInterval)
+	}
+}
+
+func (ss *session) Ibcmd(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.checkBoard(q.Board, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibcmd(q.Board, q.Data), r)
+}
+
+func (ss *session) Ibsic(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.checkBoard(q.Board, r) {
+		return nil
+	}
+	return ss.reply(s.be.Ibsic(q.Board), r)
+}
+
+// Ibsre records whether the client wants remote enable, which is asserted
+// while any client does.
+func (ss *session) Ibsre(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.checkBoard(q.Board, r) {
+		return nil
+	}
+	s.mu.Lock()
+	c := s.ren[q.Board]
+	if c == nil {
+		c = map[*session]bool{}
+		s.ren[q.Board] = c
+	}
+	if q.V != 0 {
+		c[ss] = true
+	} else {
+		delete(c, ss)
+	}
+	v := 0
+	if len(c) > 0 {
+		v = 1
+	}
+	s.mu.Unlock()
+	return ss.reply(s.be.Ibsre(q.Board, v), r)
+}
+
+func (ss *session) Iblines(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.checkBoard(q.Board, r) {
+		return nil
+	}
+	ibsta, lines := s.be.Iblines(q.Board)
+	r.Value = lines
+	return ss.reply(ibsta, r)
+}
+
+func (ss *session) Ibln(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.checkBoard(q.Board, r) {
+		return nil
+	}
+	ibsta, found := s.be.Ibln(q.Board, q.Pad, q.Sad)
+	r.Value = found
+	return ss.reply(ibsta, r)
+}
+
+func (ss *session) SendList(q Request, r *Reply) error {
+	s := ss.s
+	s.io.Lock()
+	defer s.io.Unlock()
+	if !ss.checkBoard(q.Board, r) {
+		return nil
+	}
+	return ss.reply(s.be.SendList(q.Board, q.Addrs, q.Data, q.Eot), r)
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package hislip serves GPIB devices over the High-Speed LAN Instrument
+// Protocol of IVI-6.1, conventionally on port 4880.
+//
+// Each device is identified by a sub-address, such as "hislip0" in the VISA
+// resource string TCPIP::host::hislip0::INSTR. Messages from the client are
+// written to the device, and if a message contains a query, indicated by a
+// question mark, the device's response is read and returned. Device clear,
+// status queries and triggers are mapped to Clear, Spoll and Trigger, and
+// service requests from the device are forwarded to clients.
+package hislip
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// DefaultPort is the port assigned to HiSLIP by IANA.
+const DefaultPort = 4880
+
+// Message types.
+const (
+	msgInitialize                      = 0
+	msgInitializeResponse              = 1
+	msgFatalError                      = 2
+	msgError                           = 3
+	msgAsyncLock                       = 4
+	msgAsyncLockResponse               = 5
+	msgData                            = 6
+	msgDataEnd                         = 7
+	msgDeviceClearComplete             = 8
+	msgDeviceClearAcknowledge          = 9
+	msgAsyncRemoteLocalControl         = 10
+	msgAsyncRemoteLocalResponse        = 11
+	msgTrigger                         = 12
+	msgInterrupted                     = 13
+	msgAsyncInterrupted                = 14
+	msgAsyncMaximumMessageSize         = 15
+	msgAsyncMaximumMessageSizeResponse = 16
+	msgAsyncInitialize                 = 17
+	msgAsyncInitializeResponse         = 18
+	msgAsyncDeviceClear                = 19
+	msgAsyncServiceRequest             = 20
+	msgAsyncStatusQuery                = 21
+	msgAsyncStatusResponse             = 22
+	msgAsyncDeviceClearAcknowledge     = 23
+	msgAsyncLockInfo                   = 24
+	msgAsyncLockInfoResponse           = 25
+)
+
+// Fatal error codes.
+const (
+	fatalUnidentified = 0
+	fatalBadHeader    = 1
+	fatalNoChannels   = 2
+	fatalInvalidInit  = 3
+)
+
+// Non-fatal error codes.
+const (
+	errUnrecognizedType    = 1
+	errUnrecognizedControl = 2
+	errTooLarge            = 4
+)
+
+const (
+	// protocolVersion is version 1.0.
+	protocolVersion = 0x0100
+	// vendorID identifies this server, as two ASCII characters.
+	vendorID = 'G'<<8 | 'O'
+	// maxMessage limits the payload that is accepted from a client.
+	maxMessage = 16 << 20
+	// headerSize is the length of a message header.
+	headerSize = 16
+)
+
+// errBadHeader is returned for a message not starting with the prologue.
+var errBadHeader = errors.New("hislip: poorly formed message header")
+
+// message is a HiSLIP message.
+type message struct {
+	typ     byte
+	control byte
+	param   uint32
+	payload []byte
+}
+
+// readMessage reads a message. Payloads longer than max are discarded and
+// reported by a nil payload with tooLarge set.
+func readMessage(r io.Reader, max uint64) (m message, tooLarge bool, err error) {
+	var h [headerSize]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return m, false, err
+	}
+	if h[0] != 'H' || h[1] != 'S' {
+		return m, false, errBadHeader
+	}
+	m.typ, m.control = h[2], h[3]
+	m.param = binary.BigEndian.Uint32(h[4:])
+	n := binary.BigEndian.Uint64(h[8:])
+	if n > max {
+		_, err := io.CopyN(io.Discard, r, int64(min(n, 1<<62)))
+		return m, true, err
+	}
+	m.payload = make([]byte, n)
+	_, err = io.ReadFull(r, m.payload)
+	return m, false, err
+}
+
+// writeMessage writes a message.
+func writeMessage(w io.Writer, m message) error {
+	b := make([]byte, headerSize, headerSize+len(m.payload))
+	b[0], b[1], b[2], b[3] = 'H', 'S', m.typ, m.control
+	binary.BigEndian.PutUint32(b[4:], m.param)
+	binary.BigEndian.PutUint64(b[8:], uint64(len(m.payload)))
+	_, err := w.Write(append(b, m.payload...))
+	return err
+}
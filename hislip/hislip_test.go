@@ -0,0 +1,165 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package hislip
+
+import (
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+// testClient is a session opened with raw messages.
+type testClient struct {
+	t           *testing.T
+	sync, async net.Conn
+	id          uint32
+}
+
+func (c *testClient) send(conn net.Conn, m message) {
+	c.t.Helper()
+	if err := writeMessage(conn, m); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) recv(conn net.Conn, typ byte) message {
+	c.t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	m, _, err := readMessage(conn, maxMessage)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if m.typ != typ {
+		c.t.Fatalf("got message type %d %q; want %d", m.typ, m.payload, typ)
+	}
+	return m
+}
+
+func dial(t *testing.T, addr, sub string) *testClient {
+	c := &testClient{t: t}
+	var err error
+	if c.sync, err = net.Dial("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.sync.Close() })
+	c.send(c.sync, message{typ: msgInitialize, param: protocolVersion<<16 | 'T'<<8 | 'C', payload: []byte(sub)})
+	m := c.recv(c.sync, msgInitializeResponse)
+	c.id = m.param & 0xffff
+	if c.async, err = net.Dial("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.async.Close() })
+	c.send(c.async, message{typ: msgAsyncInitialize, param: c.id})
+	c.recv(c.async, msgAsyncInitializeResponse)
+	return c
+}
+
+func TestServer(t *testing.T) {
+	be := sim.New()
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	be.Attach(22, dmm)
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be), linuxgpib.Timeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go NewServer(map[string]*linuxgpib.Device{"hislip0": d}, false, nil).Serve(l)
+
+	c := dial(t, l.Addr().String(), "")
+
+	// Queries are answered with the same message ID, and commands are not.
+	const id = 0xffffff00
+	c.send(c.sync, message{typ: msgData, param: id, payload: []byte("*ID")})
+	c.send(c.sync, message{typ: msgDataEnd, param: id, payload: []byte("N?\n")})
+	if m := c.recv(c.sync, msgDataEnd); string(m.payload) != "ACME,DMM,0,1.0\n" || m.param != id {
+		t.Errorf("response = %q with ID %x; want ACME,DMM,0,1.0 with ID %x", m.payload, m.param, id)
+	}
+	c.send(c.sync, message{typ: msgDataEnd, param: id + 2, payload: []byte("CONF:VOLT\n")})
+	c.send(c.sync, message{typ: msgTrigger, param: id + 4})
+	c.send(c.sync, message{typ: msgDataEnd, param: id + 6, payload: []byte("*OPC?\n")})
+	if m := c.recv(c.sync, msgDataEnd); string(m.payload) != "1\n" || m.param != id+6 {
+		t.Errorf("response = %q with ID %x; want 1 with ID %x", m.payload, m.param, id+6)
+	}
+	if dmm.Triggers() != 1 {
+		t.Errorf("instrument got %d triggers; want 1", dmm.Triggers())
+	}
+
+	// Service requests are forwarded, and the status byte polled from the
+	// device is kept for the next status query.
+	for _, stb := range []byte{0x41, 0x50} {
+		dmm.RequestService(stb)
+		if m := c.recv(c.async, msgAsyncServiceRequest); m.control != stb {
+			t.Errorf("service request with status %02X; want %02X", m.control, stb)
+		}
+		c.send(c.async, message{typ: msgAsyncStatusQuery})
+		if m := c.recv(c.async, msgAsyncStatusResponse); m.control != stb {
+			t.Errorf("status response %02X; want %02X", m.control, stb)
+		}
+	}
+
+	// Device clear switches to the mode requested by the client.
+	c.send(c.async, message{typ: msgAsyncDeviceClear})
+	c.recv(c.async, msgAsyncDeviceClearAcknowledge)
+	c.send(c.sync, message{typ: msgDeviceClearComplete, control: 1})
+	if m := c.recv(c.sync, msgDeviceClearAcknowledge); m.control != 1 {
+		t.Errorf("device clear acknowledged with mode %d; want overlapped", m.control)
+	}
+	if dmm.Clears() != 1 {
+		t.Errorf("instrument got %d clears; want 1", dmm.Clears())
+	}
+
+	// Locks exclude other sessions.
+	c2 := dial(t, l.Addr().String(), "hislip0")
+	c.send(c.async, message{typ: msgAsyncLock, control: 1, param: 0})
+	if m := c.recv(c.async, msgAsyncLockResponse); m.control != 1 {
+		t.Errorf("lock response %d; want success", m.control)
+	}
+	c2.send(c2.async, message{typ: msgAsyncLock, control: 1, param: 10})
+	if m := c2.recv(c2.async, msgAsyncLockResponse); m.control != 0 {
+		t.Errorf("lock response for second session %d; want failure", m.control)
+	}
+
+	// A message from another session waits for the lock for no longer than
+	// the device's timeout, and is then dropped.
+	n := len(dmm.Received())
+	c2.send(c2.sync, message{typ: msgDataEnd, param: id, payload: []byte("MEAS:VOLT?\n")})
+	time.Sleep(300 * time.Millisecond)
+	c.send(c.async, message{typ: msgAsyncLock, control: 0})
+	if m := c.recv(c.async, msgAsyncLockResponse); m.control != 1 {
+		t.Errorf("unlock response %d; want success", m.control)
+	}
+	c2.send(c2.sync, message{typ: msgDataEnd, param: id + 2, payload: []byte("*OPC?\n")})
+	if m := c2.recv(c2.sync, msgDataEnd); string(m.payload) != "1\n" || m.param != id+2 {
+		t.Errorf("response = %q with ID %x; want 1 with ID %x", m.payload, m.param, id+2)
+	}
+	if got := dmm.Received()[n:]; !slices.Equal(got, []string{"*OPC?"}) {
+		t.Errorf("instrument received %q after the lock was released; want only *OPC?", got)
+	}
+
+	// Unknown sub-addresses are refused.
+	bad := &testClient{t: t}
+	if bad.sync, err = net.Dial("tcp", l.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	defer bad.sync.Close()
+	bad.send(bad.sync, message{typ: msgInitialize, payload: []byte("hislip9")})
+	bad.recv(bad.sync, msgFatalError)
+}
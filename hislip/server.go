@@ -0,0 +1,605 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package hislip
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+)
+
+// DefaultSubAddress is used when a client does not give a sub-address.
+const DefaultSubAddress = "hislip0"
+
+// device is a GPIB device shared by all sessions using its sub-address.
+type device struct {
+	name string
+	d    *linuxgpib.Device
+	mu   sync.Mutex // serializes messages from all sessions
+
+	// The following are guarded by Server.mu. owner holds the exclusive lock,
+	// and released is closed when it lets go.
+	owner    *session
+	released chan struct{}
+	sessions map[*session]bool
+	stopSRQ  chan struct{}
+	// stb is a status byte taken by watchSRQ, which the next status query
+	// returns instead of polling the device again, if haveSTB is set.
+	stb     byte
+	haveSTB bool
+}
+
+// Server serves GPIB devices over HiSLIP.
+//
+// Sessions use the mode preferred by the server, or the mode requested by the
+// client when it completes a device clear. In synchronized mode, a response
+// which is ready after the client has already sent another message is
+// discarded and the client is told it was interrupted. Only exclusive locks
+// are supported, and a message waits for another session's lock for up to
+// the device's timeout before it is dropped. The status byte polled from a
+// device which requests service is returned by the next status query.
+//
+// A message is taken to be a query, whose response is read from the device
+// until EOI, if it contains a question mark anywhere, as SCPI queries do. A
+// message whose only question mark is in a string or block argument is
+// therefore also treated as a query, and waits for a response until the
+// device times out.
+type Server struct {
+	devices    map[string]*device
+	logger     linuxgpib.Logger
+	overlapped bool
+
+	mu       sync.Mutex
+	nextID   uint16
+	sessions map[uint16]*session
+}
+
+// NewServer returns a server for the given devices, keyed by sub-address. If
+// overlapped is true, sessions start in overlapped mode, and otherwise in
+// synchronized mode. The logger may be nil.
+func NewServer(devices map[string]*linuxgpib.Device, overlapped bool, logger linuxgpib.Logger) *Server {
+	s := &Server{
+		devices:    map[string]*device{},
+		logger:     logger,
+		overlapped: overlapped,
+		sessions:   map[uint16]*session{},
+	}
+	for name, d := range devices {
+		s.devices[name] = &device{name: name, d: d, sessions: map[*session]bool{}}
+	}
+	return s
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.logger != nil {
+		s.logger.Printf(format, v...)
+	}
+}
+
+// Serve accepts connections on l and serves each in a new goroutine. It
+// returns when l is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// fatal reports a fatal error to the client and closes the connection.
+func fatal(conn net.Conn, code byte, text string) {
+	writeMessage(conn, message{typ: msgFatalError, control: code, payload: []byte(text)})
+	conn.Close()
+}
+
+// serveConn serves a new connection, which becomes the synchronous or
+// asynchronous channel of a session according to its first message.
+func (s *Server) serveConn(conn net.Conn) {
+	m, tooLarge, err := readMessage(conn, maxDeviceName)
+	if err != nil || tooLarge {
+		fatal(conn, fatalInvalidInit, "invalid initialization")
+		return
+	}
+	switch m.typ {
+	case msgInitialize:
+		s.serveSync(conn, m)
+	case msgAsyncInitialize:
+		s.serveAsync(conn, m)
+	default:
+		fatal(conn, fatalInvalidInit, "expected Initialize or AsyncInitialize")
+	}
+}
+
+// maxDeviceName limits the length of a sub-address.
+const maxDeviceName = 256
+
+// work is a message from the client for the session's worker.
+type work struct {
+	typ   byte
+	id    uint32
+	data  []byte
+	reply chan struct{} // closed when a device clear is done
+	mode  bool          // overlapped mode requested with a device clear
+}
+
+// session is a client's pair of connections.
+type session struct {
+	s   *Server
+	id  uint16
+	dev *device
+
+	wmu  sync.Mutex // serializes writes to sync
+	sync net.Conn
+
+	amu   sync.Mutex // guards async and serializes writes to it
+	async net.Conn
+
+	overlapped atomic.Bool
+	maxSize    atomic.Uint64 // of the messages the client accepts
+	latest     atomic.Uint32 // ID of the last message queued
+	clearing   atomic.Bool   // discarding messages during device clear
+	queue      chan work
+}
+
+func (ss *session) writeSync(m message) error {
+	ss.wmu.Lock()
+	defer ss.wmu.Unlock()
+	return writeMessage(ss.sync, m)
+}
+
+// writeAsync sends a message on the asynchronous channel, if established.
+func (ss *session) writeAsync(m message) error {
+	ss.amu.Lock()
+	defer ss.amu.Unlock()
+	if ss.async == nil {
+		return errors.New("asynchronous channel not established")
+	}
+	return writeMessage(ss.async, m)
+}
+
+func (s *Server) serveSync(conn net.Conn, init message) {
+	name := string(init.payload)
+	if name == "" {
+		name = DefaultSubAddress
+	}
+	dev := s.devices[name]
+	if dev == nil {
+		s.logf("Refused session for unknown sub-address %q", name)
+		fatal(conn, fatalUnidentified, fmt.Sprintf("unknown sub-address %q", name))
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	for s.sessions[s.nextID] != nil {
+		s.nextID++
+	}
+	ss := &session{s: s, id: s.nextID, dev: dev, sync: conn, queue: make(chan work, 64)}
+	ss.overlapped.Store(s.overlapped)
+	ss.maxSize.Store(maxMessage)
+	s.sessions[ss.id] = ss
+	dev.sessions[ss] = true
+	if len(dev.sessions) == 1 {
+		dev.stopSRQ = make(chan struct{})
+		go s.watchSRQ(dev, dev.stopSRQ)
+	}
+	s.mu.Unlock()
+
+	var control byte
+	if ss.overlapped.Load() {
+		control = 1
+	}
+	if err := ss.writeSync(message{typ: msgInitializeResponse, control: control, param: protocolVersion<<16 | uint32(ss.id)}); err != nil {
+		ss.close()
+		return
+	}
+	s.logf("Opened session %d for %q from %v", ss.id, name, conn.RemoteAddr())
+
+	done := make(chan struct{})
+	go func() {
+		ss.work()
+		close(done)
+	}()
+	ss.readSync()
+	close(ss.queue)
+	<-done
+	ss.close()
+	s.logf("Closed session %d", ss.id)
+}
+
+// close releases the session's connections and lock.
+func (ss *session) close() {
+	s := ss.s
+	s.mu.Lock()
+	delete(s.sessions, ss.id)
+	dev := ss.dev
+	delete(dev.sessions, ss)
+	if len(dev.sessions) == 0 && dev.stopSRQ != nil {
+		close(dev.stopSRQ)
+		dev.stopSRQ = nil
+	}
+	if dev.owner == ss {
+		dev.owner = nil
+		close(dev.released)
+	}
+	s.mu.Unlock()
+
+	ss.sync.Close()
+	ss.amu.Lock()
+	if ss.async != nil {
+		ss.async.Close()
+	}
+	ss.amu.Unlock()
+}
+
+// readSync reads messages from the synchronous channel until it is closed.
+func (ss *session) readSync() {
+	var buf []byte
+	for {
+		m, tooLarge, err := readMessage(ss.sync, maxMessage)
+		if err != nil {
+			if err == errBadHeader {
+				fatal(ss.sync, fatalBadHeader, err.Error())
+			}
+			return
+		}
+		if tooLarge {
+			ss.writeSync(message{typ: msgError, control: errTooLarge, payload: []byte("message too large")})
+			continue
+		}
+		switch m.typ {
+		case msgData, msgDataEnd, msgTrigger:
+			if ss.clearing.Load() {
+				continue
+			}
+			if m.typ == msgTrigger {
+				ss.enqueue(work{typ: m.typ, id: m.param})
+				continue
+			}
+			if len(buf)+len(m.payload) > maxMessage {
+				buf = nil
+				ss.writeSync(message{typ: msgError, control: errTooLarge, payload: []byte("message too large")})
+				continue
+			}
+			buf = append(buf, m.payload...)
+			if m.typ == msgDataEnd {
+				ss.enqueue(work{typ: m.typ, id: m.param, data: buf})
+				buf = nil
+			}
+		case msgDeviceClearComplete:
+			buf = nil
+			done := make(chan struct{})
+			ss.clearing.Store(false)
+			ss.enqueue(work{typ: m.typ, reply: done, mode: m.control&1 != 0})
+			<-done
+		default:
+			ss.writeSync(message{typ: msgError, control: errUnrecognizedType, payload: []byte("unrecognized message type")})
+		}
+	}
+}
+
+func (ss *session) enqueue(w work) {
+	ss.latest.Store(w.id)
+	ss.queue <- w
+}
+
+// work performs the queued messages in order.
+func (ss *session) work() {
+	for w := range ss.queue {
+		switch w.typ {
+		case msgDataEnd:
+			ss.message(w)
+		case msgTrigger:
+			if !ss.waitUnlocked() {
+				ss.s.logf("Gave up waiting for the lock on %q to trigger it for session %d", ss.dev.name, ss.id)
+				continue
+			}
+			if err := ss.dev.d.Trigger(); err != nil {
+				ss.s.logf("Failed to trigger %q for session %d: %v", ss.dev.name, ss.id, err)
+			}
+		case msgDeviceClearComplete:
+			if err := ss.dev.d.Clear(); err != nil {
+				ss.s.logf("Failed to clear %q for session %d: %v", ss.dev.name, ss.id, err)
+			}
+			ss.overlapped.Store(w.mode)
+			var control byte
+			if w.mode {
+				control = 1
+			}
+			ss.writeSync(message{typ: msgDeviceClearAcknowledge, control: control})
+			close(w.reply)
+		}
+	}
+}
+
+// message writes a message to the device, and returns the response if it is
+// a query.
+func (ss *session) message(w work) {
+	if !ss.waitUnlocked() {
+		ss.s.logf("Gave up waiting for the lock on %q to handle %q for session %d", ss.dev.name, w.data, ss.id)
+		return
+	}
+	dev := ss.dev
+	dev.mu.Lock()
+	var resp bytes.Buffer
+	_, err := dev.d.Write(w.data)
+	if err == nil && bytes.IndexByte(w.data, '?') >= 0 {
+		_, err = dev.d.ReadTo(&resp)
+	}
+	dev.mu.Unlock()
+	if err != nil {
+		ss.s.logf("Failed to handle %q for session %d: %v", w.data, ss.id, err)
+		return
+	}
+	if resp.Len() == 0 {
+		return
+	}
+
+	// In synchronized mode, the client may not send another message until it
+	// has the response to a query. If it has, the response is discarded.
+	if !ss.overlapped.Load() && ss.latest.Load() != w.id {
+		latest := ss.latest.Load()
+		ss.writeSync(message{typ: msgInterrupted, param: latest})
+		ss.writeAsync(message{typ: msgAsyncInterrupted, param: latest})
+		return
+	}
+
+	data := resp.Bytes()
+	chunk := int(min(ss.maxSize.Load()-headerSize, maxMessage))
+	for {
+		typ := byte(msgDataEnd)
+		n := len(data)
+		if n > chunk {
+			typ, n = msgData, chunk
+		}
+		if err := ss.writeSync(message{typ: typ, param: w.id, payload: data[:n]}); err != nil {
+			return
+		}
+		data = data[n:]
+		if typ == msgDataEnd {
+			return
+		}
+	}
+}
+
+// waitUnlocked waits until no other session holds the lock on the device, for
+// up to the device's timeout. It reports whether the lock was released.
+func (ss *session) waitUnlocked() bool {
+	s := ss.s
+	timeout := ss.dev.d.Timeout()
+	deadline := time.Now().Add(timeout)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ss.dev.owner != nil && ss.dev.owner != ss {
+		remaining := time.Until(deadline)
+		if timeout == 0 {
+			remaining = 1<<63 - 1
+		} else if remaining <= 0 {
+			return false
+		}
+		released := ss.dev.released
+		s.mu.Unlock()
+		t := time.NewTimer(remaining)
+		select {
+		case <-released:
+		case <-t.C:
+		}
+		t.Stop()
+		s.mu.Lock()
+	}
+	return true
+}
+
+// lock acquires the exclusive lock, waiting for up to timeout.
+func (ss *session) lock(timeout time.Duration) bool {
+	s := ss.s
+	deadline := time.Now().Add(timeout)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ss.dev.owner != nil && ss.dev.owner != ss {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false
+		}
+		released := ss.dev.released
+		s.mu.Unlock()
+		t := time.NewTimer(remaining)
+		select {
+		case <-released:
+		case <-t.C:
+		}
+		t.Stop()
+		s.mu.Lock()
+	}
+	if ss.dev.owner == nil {
+		ss.dev.owner = ss
+		ss.dev.released = make(chan struct{})
+	}
+	return true
+}
+
+// unlock releases the exclusive lock, and reports whether it was held.
+func (ss *session) unlock() bool {
+	s := ss.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ss.dev.owner != ss {
+		return false
+	}
+	ss.dev.owner = nil
+	close(ss.dev.released)
+	return true
+}
+
+func (s *Server) serveAsync(conn net.Conn, init message) {
+	s.mu.Lock()
+	ss := s.sessions[uint16(init.param)]
+	s.mu.Unlock()
+	if ss == nil {
+		fatal(conn, fatalInvalidInit, "unknown session")
+		return
+	}
+	ss.amu.Lock()
+	if ss.async != nil {
+		ss.amu.Unlock()
+		fatal(conn, fatalInvalidInit, "asynchronous channel already established")
+		return
+	}
+	ss.async = conn
+	ss.amu.Unlock()
+	if err := ss.writeAsync(message{typ: msgAsyncInitializeResponse, param: vendorID}); err != nil {
+		return
+	}
+
+	for {
+		m, tooLarge, err := readMessage(conn, maxDeviceName)
+		if err != nil {
+			if err == errBadHeader {
+				fatal(conn, fatalBadHeader, err.Error())
+			}
+			return
+		}
+		if tooLarge {
+			ss.writeAsync(message{typ: msgError, control: errTooLarge, payload: []byte("message too large")})
+			continue
+		}
+		ss.handleAsync(m)
+	}
+}
+
+// handleAsync handles a message on the asynchronous channel.
+func (ss *session) handleAsync(m message) {
+	dev := ss.dev
+	switch m.typ {
+	case msgAsyncMaximumMessageSize:
+		if len(m.payload) == 8 {
+			ss.maxSize.Store(max(binary.BigEndian.Uint64(m.payload), headerSize+1))
+		}
+		ss.writeAsync(message{typ: msgAsyncMaximumMessageSizeResponse, payload: binary.BigEndian.AppendUint64(nil, maxMessage)})
+
+	case msgAsyncDeviceClear:
+		ss.clearing.Store(true)
+		for len(ss.queue) > 0 {
+			select {
+			case <-ss.queue:
+			default:
+			}
+		}
+		var control byte
+		if ss.s.overlapped {
+			control = 1
+		}
+		ss.writeAsync(message{typ: msgAsyncDeviceClearAcknowledge, control: control})
+
+	case msgAsyncStatusQuery:
+		ss.s.mu.Lock()
+		stb, kept := dev.stb, dev.haveSTB
+		dev.haveSTB = false
+		ss.s.mu.Unlock()
+		if !kept {
+			var err error
+			stb, err = dev.d.Spoll()
+			if err != nil {
+				ss.s.logf("Failed to poll %q for session %d: %v", dev.name, ss.id, err)
+			}
+		}
+		ss.writeAsync(message{typ: msgAsyncStatusResponse, control: stb})
+
+	case msgAsyncRemoteLocalControl:
+		var err error
+		switch m.control {
+		case 0, 2, 4:
+			err = dev.d.Local()
+		case 1, 3, 5, 6:
+			err = dev.d.Remote()
+		}
+		if err != nil {
+			ss.s.logf("Failed remote/local control %d of %q for session %d: %v", m.control, dev.name, ss.id, err)
+		}
+		ss.writeAsync(message{typ: msgAsyncRemoteLocalResponse})
+
+	case msgAsyncLock:
+		var control byte
+		switch {
+		case m.control == 0 && ss.unlock():
+			control = 1
+		case m.control == 0:
+			control = 3
+		case len(m.payload) != 0:
+			// Shared locks are not supported.
+			control = 3
+		case ss.lock(time.Duration(m.param) * time.Millisecond):
+			control = 1
+		}
+		ss.writeAsync(message{typ: msgAsyncLockResponse, control: control})
+
+	case msgAsyncLockInfo:
+		ss.s.mu.Lock()
+		var control byte
+		if dev.owner != nil {
+			control = 1
+		}
+		ss.s.mu.Unlock()
+		ss.writeAsync(message{typ: msgAsyncLockInfoResponse, control: control})
+
+	default:
+		ss.writeAsync(message{typ: msgError, control: errUnrecognizedType, payload: []byte("unrecognized message type")})
+	}
+}
+
+// watchSRQ forwards service requests from a device to its sessions until stop
+// is closed. The status byte taken by polling the device is kept for the next
+// status query, so that the request is not lost to it.
+func (s *Server) watchSRQ(dev *device, stop chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+	for {
+		stb, err := dev.d.WaitSRQContext(ctx)
+		var t interface{ Timeout() bool }
+		switch {
+		case err == nil:
+			s.mu.Lock()
+			dev.stb, dev.haveSTB = stb, true
+			var ss []*session
+			for k := range dev.sessions {
+				ss = append(ss, k)
+			}
+			s.mu.Unlock()
+			for _, k := range ss {
+				k.writeAsync(message{typ: msgAsyncServiceRequest, control: stb})
+			}
+		case ctx.Err() != nil:
+			return
+		case errors.As(err, &t) && t.Timeout():
+		default:
+			s.logf("Failed to wait for service request from %q: %v", dev.name, err)
+			time.Sleep(time.Second)
+		}
+	}
+}
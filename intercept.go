@@ -0,0 +1,206 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Operation describes an operation on a board or device, for an Interceptor.
+type Operation struct {
+	// Context is initially empty. Before may replace it, such as with one
+	// holding a tracing span, for use by After.
+	Context context.Context
+	// Kind is the kind of operation. Those on a device are "open", "close",
+	// "read", "write", "query", "spoll", "waitsrq", "trigger", "clear",
+	// "local", "remote", "timeout" and "rebind", where the streaming and file
+	// methods are reported as reads and writes. A "waitsrq" covers waiting for
+	// a service request, and the serial poll which follows is a "spoll" of
+	// its own. Those on a board are "ifc", "ren", "dcl", "reset", "enumerate"
+	// and "command".
+	Kind  string
+	Board int
+	// Device is true if the operation is on the device at Address, rather
+	// than on the board.
+	Device  bool
+	Address Address
+	// Payload is the data to be written, the command of a query, or the
+	// command bytes sent on the board. It is nil for the streaming and file
+	// methods, whose data is not held in memory.
+	Payload []byte
+	Started time.Time
+
+	// The remaining fields are set before After is called. Result is the data
+	// read, or the status byte of a serial poll, and is nil for the streaming
+	// and file methods. Bytes is the number transferred in either direction,
+	// including by a failed operation.
+	Result   []byte
+	Bytes    int64
+	Duration time.Duration
+	Err      error
+
+	// outer holds the interceptors added by Intercept, which are called
+	// without mu, and inner those for the Activity and Observe options,
+	// which are called with it held. Each is trimmed to the interceptors
+	// whose Before succeeded.
+	outer, inner []Interceptor
+}
+
+// An Interceptor is informed before and after each operation on a board or
+// device, for tracing, auditing, fault injection or rate limiting. Its
+// methods are called without the global GPIB lock, so that a delay holds up
+// only the operation being intercepted, and they may be called concurrently
+// for operations in different goroutines.
+type Interceptor interface {
+	// Before is called before an operation. If it returns an error, the
+	// operation is not performed and fails with that error, except that a
+	// device being closed is released anyway.
+	Before(op *Operation) error
+	// After is called after an operation, or after a later interceptor
+	// prevented it, if Before returned nil.
+	After(op *Operation)
+}
+
+// InterceptorFuncs is an Interceptor made of functions, either of which may be
+// nil.
+type InterceptorFuncs struct {
+	BeforeFunc func(op *Operation) error
+	AfterFunc  func(op *Operation)
+}
+
+func (f InterceptorFuncs) Before(op *Operation) error {
+	if f.BeforeFunc == nil {
+		return nil
+	}
+	return f.BeforeFunc(op)
+}
+
+func (f InterceptorFuncs) After(op *Operation) {
+	if f.AfterFunc != nil {
+		f.AfterFunc(op)
+	}
+}
+
+// Intercept adds an interceptor. Interceptors are called in the order they
+// were added before an operation, and in the reverse order after it. Those
+// added to a board apply to its devices, which may add more. The callbacks of
+// the Activity and Observe options are called inside all of them.
+func Intercept(i Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors[:len(o.interceptors):len(o.interceptors)], i)
+	}
+}
+
+// inner returns the interceptors for the Activity and Observe options,
+// outermost first.
+func (o *options) inner() []Interceptor {
+	var chain []Interceptor
+	if o.activity != nil {
+		f := o.activity
+		chain = append(chain, InterceptorFuncs{
+			BeforeFunc: func(*Operation) error { f(true); return nil },
+			AfterFunc:  func(*Operation) { f(false) },
+		})
+	}
+	if o.observe != nil {
+		chain = append(chain, observer(o.observe))
+	}
+	return chain
+}
+
+// begin starts an operation by calling the interceptors. If one fails, the
+// operation must not be performed, and the error is returned after the
+// interceptors already called have been told. The caller must hold mu, which
+// is released while the interceptors added by Intercept are called, so it
+// must check afterwards that what it operates on is still open.
+func (o *options) begin(op *Operation) error {
+	op.Context = context.Background()
+	op.Started = time.Now()
+	op.outer, op.inner = o.interceptors, o.inner()
+	var err error
+	if len(op.outer) > 0 {
+		mu.Unlock()
+		err = op.before(&op.outer)
+		mu.Lock()
+	}
+	if err != nil {
+		op.inner = nil
+	} else {
+		err = op.before(&op.inner)
+	}
+	if err != nil {
+		op.end(0, nil, err)
+		return err
+	}
+	return nil
+}
+
+// before calls Before on each interceptor in *chain. If one fails, it trims
+// *chain to those called before it and returns the error.
+func (op *Operation) before(chain *[]Interceptor) error {
+	for i, ic := range *chain {
+		if err := ic.Before(op); err != nil {
+			*chain = (*chain)[:i]
+			return err
+		}
+	}
+	return nil
+}
+
+// end completes an operation by calling the interceptors. The caller must hold
+// mu, which is released while the interceptors added by Intercept are called.
+func (op *Operation) end(n int64, result []byte, err error) {
+	op.Bytes, op.Result, op.Err = n, result, err
+	op.Duration = time.Since(op.Started)
+	for i := len(op.inner) - 1; i >= 0; i-- {
+		op.inner[i].After(op)
+	}
+	if len(op.outer) > 0 {
+		mu.Unlock()
+		for i := len(op.outer) - 1; i >= 0; i-- {
+			op.outer[i].After(op)
+		}
+		mu.Lock()
+	}
+}
+
+// begin starts an operation on the board, failing if the board was closed
+// while the interceptors were called.
+func (b *Board) begin(kind string, payload []byte) (*Operation, error) {
+	op := &Operation{Kind: kind, Board: b.index, Payload: payload}
+	if err := b.options.begin(op); err != nil {
+		return op, err
+	}
+	if b.isClosed() {
+		err := errors.New("already closed")
+		op.end(0, nil, err)
+		return op, err
+	}
+	return op, nil
+}
+
+// begin starts an operation on the device, failing if the device was closed
+// while the interceptors were called.
+func (d *Device) begin(kind string, payload []byte) (*Operation, error) {
+	op := &Operation{Kind: kind, Board: d.board.index, Device: true, Address: d.addr, Payload: payload}
+	if err := d.options.begin(op); err != nil {
+		return op, err
+	}
+	if d.isClosed {
+		err := errors.New("already closed")
+		op.end(0, nil, err)
+		return op, err
+	}
+	return op, nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+func TestIntercept(t *testing.T) {
+	be := sim.New()
+	inst := sim.NewSCPI("ACME,DMM,0,1.0")
+	be.Attach(22, inst)
+
+	var calls []string
+	record := func(name string) linuxgpib.Interceptor {
+		return linuxgpib.InterceptorFuncs{
+			BeforeFunc: func(op *linuxgpib.Operation) error {
+				calls = append(calls, fmt.Sprintf("%s before %s %q", name, op.Kind, op.Payload))
+				return nil
+			},
+			AfterFunc: func(op *linuxgpib.Operation) {
+				calls = append(calls, fmt.Sprintf("%s after %s %d %q %v", name, op.Kind, op.Bytes, op.Result, op.Err))
+			},
+		}
+	}
+	errInjected := errors.New("injected")
+	fail := linuxgpib.InterceptorFuncs{BeforeFunc: func(op *linuxgpib.Operation) error {
+		if string(op.Payload) == "FAIL" {
+			return errInjected
+		}
+		return nil
+	}}
+	activity := func(on bool) { calls = append(calls, fmt.Sprint("activity ", on)) }
+
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be), linuxgpib.Activity(activity), linuxgpib.Intercept(record("outer")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	d, err := b.NewDevice(22, linuxgpib.Intercept(record("inner")), linuxgpib.Intercept(fail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls = nil
+
+	if _, err := d.Query("*IDN?"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Write([]byte("FAIL")); err != errInjected {
+		t.Errorf("Write got error %v; want %v", err, errInjected)
+	}
+	if got := inst.Received(); !reflect.DeepEqual(got, []string{"*IDN?"}) {
+		t.Errorf("instrument received %q; want only the query", got)
+	}
+	if err := b.InterfaceClear(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`outer before query "*IDN?\n"`,
+		`inner before query "*IDN?\n"`,
+		`activity true`,
+		`activity false`,
+		`inner after query 21 "ACME,DMM,0,1.0\n" <nil>`,
+		`outer after query 21 "ACME,DMM,0,1.0\n" <nil>`,
+		`outer before write "FAIL"`,
+		`inner before write "FAIL"`,
+		`inner after write 0 "" injected`,
+		`outer after write 0 "" injected`,
+		`outer before ifc ""`,
+		`activity true`,
+		`activity false`,
+		`outer after ifc 0 "" <nil>`,
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls:\n%q\nwant:\n%q", calls, want)
+	}
+}
+
+func TestInterceptClose(t *testing.T) {
+	be := sim.New()
+	be.Attach(22, sim.NewSCPI("ACME,DMM,0,1.0"))
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	var after int
+	refuse := linuxgpib.InterceptorFuncs{
+		BeforeFunc: func(op *linuxgpib.Operation) error {
+			if op.Kind == "close" {
+				return errors.New("refused")
+			}
+			return nil
+		},
+		AfterFunc: func(op *linuxgpib.Operation) {
+			if op.Kind == "close" {
+				after++
+			}
+		},
+	}
+	d, err := b.NewDevice(22, linuxgpib.Intercept(refuse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if after != 0 {
+		t.Errorf("After was called %d times for a refused close; want 0", after)
+	}
+
+	// The address was released, and can be opened again.
+	d, err = b.NewDevice(22)
+	if err != nil {
+		t.Fatalf("NewDevice() after a refused close = %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
+
+func TestInterceptWaitSRQ(t *testing.T) {
+	be := sim.New()
+	inst := sim.NewSCPI("ACME,DMM,0,1.0")
+	be.Attach(22, inst)
+
+	var calls []string
+	record := linuxgpib.InterceptorFuncs{
+		BeforeFunc: func(op *linuxgpib.Operation) error {
+			calls = append(calls, "before "+op.Kind)
+			return nil
+		},
+		AfterFunc: func(op *linuxgpib.Operation) {
+			calls = append(calls, fmt.Sprintf("after %s %q %v", op.Kind, op.Result, op.Err))
+		},
+	}
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be), linuxgpib.Intercept(record))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	calls = nil
+
+	inst.RequestService(0x41)
+	if stb, err := d.WaitSRQ(); err != nil || stb != 0x41 {
+		t.Fatalf("WaitSRQ() = %02X, %v; want 41", stb, err)
+	}
+	want := []string{
+		`before waitsrq`,
+		`after waitsrq "" <nil>`,
+		`before spoll`,
+		`after spoll "A" <nil>`,
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls:\n%q\nwant:\n%q", calls, want)
+	}
+}
+
+func TestInterceptWithoutLock(t *testing.T) {
+	be := sim.New()
+	be.Attach(22, sim.NewSCPI("ACME,DMM,0,1.0"))
+	be.Attach(23, sim.NewSCPI("ACME,PSU,0,1.0"))
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	waiting, release := make(chan struct{}), make(chan struct{})
+	hold := linuxgpib.InterceptorFuncs{BeforeFunc: func(op *linuxgpib.Operation) error {
+		if op.Kind == "query" {
+			close(waiting)
+			<-release
+		}
+		return nil
+	}}
+	d1, err := b.NewDevice(22, linuxgpib.Intercept(hold))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d1.Close()
+	d2, err := b.NewDevice(23)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d2.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := d1.Query("*IDN?")
+		done <- err
+	}()
+	<-waiting
+
+	// Another device can be used while an interceptor delays the query.
+	if got, err := d2.Query("*IDN?"); err != nil || got != "ACME,PSU,0,1.0" {
+		t.Errorf("Query() = %q, %v; want %q", got, err, "ACME,PSU,0,1.0")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("delayed Query() = %v", err)
+	}
+}
+
+func TestInterceptCloses(t *testing.T) {
+	be := sim.New()
+	inst := sim.NewSCPI("ACME,DMM,0,1.0")
+	be.Attach(22, inst)
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	// An interceptor may close the device it intercepts, since it is called
+	// without the lock, and the operation then fails.
+	var d *linuxgpib.Device
+	closer := linuxgpib.InterceptorFuncs{BeforeFunc: func(op *linuxgpib.Operation) error {
+		if op.Kind == "clear" {
+			return d.Close()
+		}
+		return nil
+	}}
+	d, err = b.NewDevice(22, linuxgpib.Intercept(closer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Clear(); err == nil {
+		t.Error("Clear() of a device closed by an interceptor succeeded")
+	}
+	if inst.Clears() != 0 {
+		t.Errorf("instrument got %d clears; want 0", inst.Clears())
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package internal
+
+/*
+#cgo linux LDFLAGS: -lgpib
+#include <stdlib.h>
+#include <gpib/ib.h>
+#include "buffer.h"
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// bufPtr returns a pointer to the first byte of b for passing to a synchronous
+// C function. The cgo pointer rules allow this without a copy because a byte
+// slice contains no Go pointers, and the synchronous functions of the C library
+// do not retain the buffer after they return.
+func bufPtr(b []byte) unsafe.Pointer {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(&b[0])
+}
+
+// cBuffer is C memory that outlives the call which filled it.
+type cBuffer struct {
+	p unsafe.Pointer
+	n int
+}
+
+// asyncBufs holds one buffer per descriptor for asynchronous writes. The C
+// library keeps using the buffer after ibwrta or ibcmda return, so it can
+// neither be Go memory nor be freed until the descriptor is taken offline.
+// asyncMu guards the map, because callers of this package need not serialize
+// their calls on different descriptors.
+var (
+	asyncMu   sync.Mutex
+	asyncBufs = map[int]*cBuffer{}
+)
+
+// asyncBuf copies b into the C buffer belonging to ud, growing the buffer if
+// necessary, and returns a pointer to it. The previous contents are discarded,
+// so the caller must not start another asynchronous operation on ud until the
+// previous one has completed.
+func asyncBuf(ud int, b []byte) unsafe.Pointer {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	c := asyncBufs[ud]
+	if c == nil {
+		c = &cBuffer{}
+		asyncBufs[ud] = c
+	}
+	if c.n < len(b) || c.p == nil {
+		C.free(c.p)
+		n := len(b)
+		if n == 0 {
+			n = 1
+		}
+		c.p = C.malloc(C.size_t(n))
+		c.n = n
+	}
+	copy(unsafe.Slice((*byte)(c.p), c.n), b)
+	return c.p
+}
+
+// releaseAsyncBuf frees the C buffer belonging to ud, if there is one. It is
+// called when ud is taken offline, and when Ibdev opens a descriptor, whose
+// number may have belonged to one taken offline without calling Ibonl, such
+// as by C code in the same process.
+func releaseAsyncBuf(ud int) {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	if c := asyncBufs[ud]; c != nil {
+		C.free(c.p)
+		delete(asyncBufs, ud)
+	}
+}
+
+// Test helpers are defined here because test files cannot import C directly.
+
+func testWriteCopy(ud int, buf []byte) int {
+	bufPtr := C.CBytes(buf)
+	defer C.free(unsafe.Pointer(bufPtr))
+	return int(C.testFakeIbwrt(C.int(ud), unsafe.Pointer(bufPtr), C.long(len(buf))))
+}
+
+func testWriteDirect(ud int, buf []byte) int {
+	return int(C.testFakeIbwrt(C.int(ud), bufPtr(buf), C.long(len(buf))))
+}
+
+func testWriteAsync(ud int, buf []byte) int {
+	return int(C.testFakeIbwrt(C.int(ud), asyncBuf(ud, buf), C.long(len(buf))))
+}
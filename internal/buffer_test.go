@@ -0,0 +1,92 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package internal
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestAsyncBuf(t *testing.T) {
+	const ud = 12345
+	defer releaseAsyncBuf(ud)
+
+	big := asyncBuf(ud, make([]byte, 100))
+	if small := asyncBuf(ud, make([]byte, 10)); small != big {
+		t.Error("smaller write did not reuse the buffer")
+	}
+	asyncBuf(ud, make([]byte, 1000))
+	if g := asyncBufs[ud].n; g != 1000 {
+		t.Errorf("buffer did not grow: got %d bytes, want 1000", g)
+	}
+	releaseAsyncBuf(ud)
+	if _, ok := asyncBufs[ud]; ok {
+		t.Error("buffer was not released")
+	}
+}
+
+func TestAsyncBufOffline(t *testing.T) {
+	const ud = 12346
+	asyncBuf(ud, make([]byte, 10))
+	Ibonl(ud, 0)
+	if _, ok := asyncBufs[ud]; ok {
+		releaseAsyncBuf(ud)
+		t.Error("buffer was not released when the descriptor went offline")
+	}
+}
+
+func TestAsyncBufConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for ud := 100; ud < 108; ud++ {
+		wg.Add(1)
+		go func(ud int) {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				asyncBuf(ud, make([]byte, n))
+			}
+			releaseAsyncBuf(ud)
+		}(ud)
+	}
+	wg.Wait()
+}
+
+func TestBufPtrEmpty(t *testing.T) {
+	if g := testWriteDirect(0, nil); g != CMPL {
+		t.Errorf("got ibsta 0x%x; want 0x%x", g, CMPL)
+	}
+}
+
+var benchmarkSizes = []int{64, 64 * 1024, 4 * 1024 * 1024}
+
+func benchmarkWrite(b *testing.B, write func(int, []byte) int) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			buf := make([]byte, size)
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				write(1, buf)
+			}
+		})
+	}
+	releaseAsyncBuf(1)
+}
+
+// BenchmarkWriteCopy measures the former write path, which copied every
+// buffer to freshly allocated C memory.
+func BenchmarkWriteCopy(b *testing.B) { benchmarkWrite(b, testWriteCopy) }
+
+// BenchmarkWriteDirect measures the path used by Ibwrt, Ibcmd and SendList.
+func BenchmarkWriteDirect(b *testing.B) { benchmarkWrite(b, testWriteDirect) }
+
+// BenchmarkWriteAsync measures the path used by Ibwrta and Ibcmda.
+func BenchmarkWriteAsync(b *testing.B) { benchmarkWrite(b, testWriteAsync) }
@@ -113,23 +113,17 @@ func ResetSys(board_desc int, addressList []Address) {
 }
 
 func Send(board_desc int, address Address, buffer []byte, eot_mode int) {
-	bufferPtr := C.CBytes(buffer)
-	defer C.free(unsafe.Pointer(bufferPtr))
-	C.Send(C.int(board_desc), C.Addr4882_t(address), unsafe.Pointer(bufferPtr), C.long(len(buffer)), C.int(eot_mode))
+	C.Send(C.int(board_desc), C.Addr4882_t(address), bufPtr(buffer), C.long(len(buffer)), C.int(eot_mode))
 	return
 }
 
 func SendCmds(board_desc int, cmds []byte) {
-	cmdsPtr := C.CBytes(cmds)
-	defer C.free(unsafe.Pointer(cmdsPtr))
-	C.SendCmds(C.int(board_desc), unsafe.Pointer(cmdsPtr), C.long(len(cmds)))
+	C.SendCmds(C.int(board_desc), bufPtr(cmds), C.long(len(cmds)))
 	return
 }
 
 func SendDataBytes(board_desc int, buffer []byte, eotmode int) {
-	bufferPtr := C.CBytes(buffer)
-	defer C.free(unsafe.Pointer(bufferPtr))
-	C.SendDataBytes(C.int(board_desc), unsafe.Pointer(bufferPtr), C.long(len(buffer)), C.int(eotmode))
+	C.SendDataBytes(C.int(board_desc), bufPtr(buffer), C.long(len(buffer)), C.int(eotmode))
 	return
 }
 
@@ -145,9 +139,7 @@ func SendLLO(board_desc int) {
 
 func SendList(board_desc int, addressList []Address, buffer []byte, eotmode int) {
 	addressList2 := append(addressList, NOADDR)
-	bufferPtr := C.CBytes(buffer)
-	defer C.free(unsafe.Pointer(bufferPtr))
-	C.SendList(C.int(board_desc), (*C.Addr4882_t)(unsafe.Pointer(&addressList2[0])), unsafe.Pointer(bufferPtr), C.long(len(buffer)), C.int(eotmode))
+	C.SendList(C.int(board_desc), (*C.Addr4882_t)(unsafe.Pointer(&addressList2[0])), bufPtr(buffer), C.long(len(buffer)), C.int(eotmode))
 	return
 }
 
@@ -214,16 +206,12 @@ func Ibclr(ud int) (ibsta int) {
 }
 
 func Ibcmd(ud int, cmd []byte) (ibsta int) {
-	cmdPtr := C.CBytes(cmd)
-	defer C.free(unsafe.Pointer(cmdPtr))
-	ibsta = int(C.ibcmd(C.int(ud), unsafe.Pointer(cmdPtr), C.long(len(cmd))))
+	ibsta = int(C.ibcmd(C.int(ud), bufPtr(cmd), C.long(len(cmd))))
 	return
 }
 
 func Ibcmda(ud int, cmd []byte) (ibsta int) {
-	cmdPtr := C.CBytes(cmd)
-	defer C.free(unsafe.Pointer(cmdPtr))
-	ibsta = int(C.ibcmda(C.int(ud), unsafe.Pointer(cmdPtr), C.long(len(cmd))))
+	ibsta = int(C.ibcmda(C.int(ud), asyncBuf(ud, cmd), C.long(len(cmd))))
 	return
 }
 
@@ -234,6 +222,9 @@ func Ibconfig(ud int, option int, value int) (ibsta int) {
 
 func Ibdev(board_index int, pad int, sad int, timo int, send_eoi int, eosmode int) (ud int) {
 	ud = int(C.ibdev(C.int(board_index), C.int(pad), C.int(sad), C.int(timo), C.int(send_eoi), C.int(eosmode)))
+	if ud != -1 {
+		releaseAsyncBuf(ud)
+	}
 	return
 }
 
@@ -257,10 +248,10 @@ func Ibevent(ud int) (ibsta int, event int) {
 	return
 }
 
-func Ibfind(dev string) (ibsta int) {
+func Ibfind(dev string) (ud int) {
 	devPtr := C.CString(dev)
 	defer C.free(unsafe.Pointer(devPtr))
-	ibsta = int(C.ibfind(devPtr))
+	ud = int(C.ibfind(devPtr))
 	return
 }
 
@@ -291,6 +282,9 @@ func Ibloc(ud int) (ibsta int) {
 
 func Ibonl(ud int, onl int) (ibsta int) {
 	ibsta = int(C.ibonl(C.int(ud), C.int(onl)))
+	if onl == 0 {
+		releaseAsyncBuf(ud)
+	}
 	return
 }
 
@@ -394,16 +388,12 @@ func Ibwait(ud int, mask int) (ibsta int) {
 }
 
 func Ibwrt(ud int, buf []byte) (ibsta int) {
-	bufPtr := C.CBytes(buf)
-	defer C.free(unsafe.Pointer(bufPtr))
-	ibsta = int(C.ibwrt(C.int(ud), unsafe.Pointer(bufPtr), C.long(len(buf))))
+	ibsta = int(C.ibwrt(C.int(ud), bufPtr(buf), C.long(len(buf))))
 	return
 }
 
 func Ibwrta(ud int, buf []byte) (ibsta int) {
-	bufPtr := C.CBytes(buf)
-	defer C.free(unsafe.Pointer(bufPtr))
-	ibsta = int(C.ibwrta(C.int(ud), unsafe.Pointer(bufPtr), C.long(len(buf))))
+	ibsta = int(C.ibwrta(C.int(ud), asyncBuf(ud, buf), C.long(len(buf))))
 	return
 }
 
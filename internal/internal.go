@@ -29,6 +29,36 @@ type timeoutError struct{ error }
 
 func (t *timeoutError) Timeout() bool { return true }
 
+// Error is returned by Err for errors other than timeouts.
+type Error struct {
+	// Iberr is the error code, such as ENOL.
+	Iberr int
+	// Errno is the system error for EDVR and EFSO, and zero otherwise.
+	Errno syscall.Errno
+}
+
+func (e *Error) Error() string {
+	if e.Iberr == EDVR || e.Iberr == EFSO {
+		return fmt.Sprintf("%s: %v", FormatIberr(e.Iberr), e.Errno)
+	}
+	return FormatIberr(e.Iberr)
+}
+
+// Is reports whether target is an Error with the same code, so that
+// errors.Is(err, &Error{Iberr: ENOL}) matches any ENOL error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Iberr == e.Iberr && (t.Errno == 0 || t.Errno == e.Errno)
+}
+
+// Unwrap returns the system error, if there is one.
+func (e *Error) Unwrap() error {
+	if e.Errno == 0 {
+		return nil
+	}
+	return e.Errno
+}
+
 var (
 	// TimeoutErr is returned by Err if an operation timed out.
 	TimeoutErr = &timeoutError{errors.New("timed out")}
@@ -89,8 +119,8 @@ var (
 	}
 )
 
-// formatIberr returns the name of the error enum constant.
-func formatIberr(iberr int) string {
+// FormatIberr returns the name of the error enum constant, such as ENOL.
+func FormatIberr(iberr int) string {
 	if s, ok := iberrStrings[iberr]; ok {
 		return s
 	}
@@ -98,27 +128,27 @@ func formatIberr(iberr int) string {
 }
 
 // Err returns an error if ibsta has the ERR bit set, and nil otherwise. If the
-// TIMO bit is set, TimeoutErr is returned.
+// TIMO bit is set, TimeoutErr is returned, and otherwise an *Error.
 //
 // For non-timeout errors, Err accesses the iberr and ibcnt globals. It must
 // therefore be called prior to any subsequent operations which might overwrite
 // those globals.
 func Err(ibsta int) error {
+	return ErrFrom(ibsta, Iberr, Ibcnt)
+}
+
+// ErrFrom is like Err, but obtains iberr and ibcnt by calling the given
+// functions instead of reading the globals. They are only called if needed.
+func ErrFrom(ibsta int, iberr, ibcnt func() int) error {
 	if ibsta&TIMO != 0 {
 		return TimeoutErr
 	}
 	if ibsta&ERR != 0 {
-		v := Iberr()
-		switch v {
-		case EDVR:
-			errno := syscall.Errno(Ibcnt())
-			return fmt.Errorf("EDVR: %v", errno)
-		case EFSO:
-			errno := syscall.Errno(Ibcnt())
-			return fmt.Errorf("EFSO: %v", errno)
-		default:
-			return errors.New(formatIberr(int(v)))
+		e := &Error{Iberr: iberr()}
+		if e.Iberr == EDVR || e.Iberr == EFSO {
+			e.Errno = syscall.Errno(ibcnt())
 		}
+		return e
 	}
 	return nil
 }
@@ -175,35 +205,50 @@ func IblinesString(board int) string {
 	return FormatIblines(iblines)
 }
 
+// timeouts lists the timeout constants in increasing order of duration.
+var timeouts = []struct {
+	D time.Duration
+	C int
+}{
+	{0, TNONE},
+	{10 * time.Microsecond, T10us},
+	{30 * time.Microsecond, T30us},
+	{100 * time.Microsecond, T100us},
+	{300 * time.Microsecond, T300us},
+	{1 * time.Millisecond, T1ms},
+	{3 * time.Millisecond, T3ms},
+	{10 * time.Millisecond, T10ms},
+	{30 * time.Millisecond, T30ms},
+	{100 * time.Millisecond, T100ms},
+	{300 * time.Millisecond, T300ms},
+	{1 * time.Second, T1s},
+	{3 * time.Second, T3s},
+	{10 * time.Second, T10s},
+	{30 * time.Second, T30s},
+	{100 * time.Second, T100s},
+	{300 * time.Second, T300s},
+	{1000 * time.Second, T1000s},
+}
+
 // Timeout returns a timeout constant not shorter than the specified
 // duration. The returned timeout may be longer, up to the max supported by
 // GPIB.
 func Timeout(d time.Duration) int {
-	for _, c := range []struct {
-		D time.Duration
-		C int
-	}{
-		{0, TNONE},
-		{10 * time.Microsecond, T10us},
-		{30 * time.Microsecond, T30us},
-		{100 * time.Microsecond, T100us},
-		{300 * time.Microsecond, T300us},
-		{1 * time.Millisecond, T1ms},
-		{3 * time.Millisecond, T3ms},
-		{10 * time.Millisecond, T10ms},
-		{30 * time.Millisecond, T30ms},
-		{100 * time.Millisecond, T100ms},
-		{300 * time.Millisecond, T300ms},
-		{1 * time.Second, T1s},
-		{3 * time.Second, T3s},
-		{10 * time.Second, T10s},
-		{30 * time.Second, T30s},
-		{100 * time.Second, T100s},
-		{300 * time.Second, T300s},
-	} {
+	for _, c := range timeouts {
 		if d <= c.D {
 			return c.C
 		}
 	}
 	return T1000s
 }
+
+// Duration returns the duration of a timeout constant, or zero if the constant
+// is TNONE or unknown.
+func Duration(tmo int) time.Duration {
+	for _, c := range timeouts {
+		if tmo == c.C {
+			return c.D
+		}
+	}
+	return 0
+}
@@ -12,8 +12,11 @@
 package internal
 
 import (
+	"errors"
 	"os"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestTimeoutError(t *testing.T) {
@@ -22,6 +25,21 @@ func TestTimeoutError(t *testing.T) {
 	}
 }
 
+func TestErrFrom(t *testing.T) {
+	code := func(v int) func() int { return func() int { return v } }
+	err := ErrFrom(ERR|CMPL, code(ENOL), code(0))
+	if err.Error() != "ENOL" || !errors.Is(err, &Error{Iberr: ENOL}) || errors.Is(err, &Error{Iberr: EARG}) {
+		t.Errorf("ErrFrom(ENOL) = %v, which does not match as expected", err)
+	}
+	err = ErrFrom(ERR|CMPL, code(EDVR), code(int(syscall.EBUSY)))
+	if err.Error() != "EDVR: "+syscall.EBUSY.Error() || !errors.Is(err, syscall.EBUSY) || !errors.Is(err, &Error{Iberr: EDVR}) {
+		t.Errorf("ErrFrom(EDVR) = %v, which does not match as expected", err)
+	}
+	if err := ErrFrom(CMPL, code(ENOL), code(0)); err != nil {
+		t.Errorf("ErrFrom without ERR = %v, want nil", err)
+	}
+}
+
 func TestNOADDR(t *testing.T) {
 	if g := testNOADDR(); g != NOADDR {
 		t.Errorf("bad NOADDR: got 0x%x; want 0x%x", g, NOADDR)
@@ -33,3 +51,14 @@ func TestSTOPend(t *testing.T) {
 		t.Errorf("bad STOPend: got 0x%x; want 0x%x", g, STOPend)
 	}
 }
+
+func TestDuration(t *testing.T) {
+	for _, d := range []time.Duration{0, 10 * time.Microsecond, 3 * time.Second, 1000 * time.Second} {
+		if g := Duration(Timeout(d)); g != d {
+			t.Errorf("Duration(Timeout(%v)) = %v", d, g)
+		}
+	}
+	if g, w := Duration(Timeout(2*time.Second)), 3*time.Second; g != w {
+		t.Errorf("Duration(Timeout(2s)) = %v; want %v", g, w)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package internal
+
+// Result holds the ibsta, iberr and ibcnt values of the last operation, for
+// backends which are not the C library and therefore do not set its globals.
+// It is embedded to provide the Ibsta, Iberr and Ibcnt methods of a backend.
+type Result struct {
+	Sta, Err, Cnt int
+}
+
+func (r *Result) Ibsta() int { return r.Sta }
+func (r *Result) Iberr() int { return r.Err }
+func (r *Result) Ibcnt() int { return r.Cnt }
+
+// Done records a successful operation which transferred cnt bytes, and
+// returns ibsta. The CMPL bit is always set.
+func (r *Result) Done(ibsta, cnt int) int {
+	r.Sta, r.Cnt = ibsta|CMPL, cnt
+	return r.Sta
+}
+
+// Fail records a failed operation with the given iberr, and returns ibsta.
+func (r *Result) Fail(iberr int) int {
+	r.Sta, r.Err, r.Cnt = ERR|CMPL, iberr, 0
+	return r.Sta
+}
+
+// FailErrno records an EDVR error with the given errno, and returns ibsta.
+func (r *Result) FailErrno(errno int) int {
+	r.Sta, r.Err, r.Cnt = ERR|CMPL, EDVR, errno
+	return r.Sta
+}
+
+// Timeout records an operation which timed out after transferring cnt bytes,
+// and returns ibsta.
+func (r *Result) Timeout(cnt int) int {
+	r.Sta, r.Err, r.Cnt = ERR|TIMO|CMPL, EABO, cnt
+	return r.Sta
+}
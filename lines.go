@@ -0,0 +1,201 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// LineState is the state of a single bus control line.
+type LineState struct {
+	// Valid is false if the board is unable to monitor the line, in which case
+	// Asserted is meaningless.
+	Valid bool
+	// Asserted is true if the line is driven low by any device on the bus.
+	Asserted bool
+}
+
+// Lines is a snapshot of the bus control lines.
+type Lines struct {
+	EOI  LineState // end or identify
+	ATN  LineState // attention
+	SRQ  LineState // service request
+	REN  LineState // remote enable
+	IFC  LineState // interface clear
+	NRFD LineState // not ready for data
+	NDAC LineState // not data accepted
+	DAV  LineState // data valid
+}
+
+// lineBit associates a field of Lines with its bits in the value reported by
+// iblines.
+type lineBit struct {
+	s           *LineState
+	valid, line int
+}
+
+func (l *Lines) lineBits() []lineBit {
+	return []lineBit{
+		{&l.EOI, internal.ValidEOI, internal.BusEOI},
+		{&l.ATN, internal.ValidATN, internal.BusATN},
+		{&l.SRQ, internal.ValidSRQ, internal.BusSRQ},
+		{&l.REN, internal.ValidREN, internal.BusREN},
+		{&l.IFC, internal.ValidIFC, internal.BusIFC},
+		{&l.NRFD, internal.ValidNRFD, internal.BusNRFD},
+		{&l.NDAC, internal.ValidNDAC, internal.BusNDAC},
+		{&l.DAV, internal.ValidDAV, internal.BusDAV},
+	}
+}
+
+func newLines(iblines int) Lines {
+	var l Lines
+	for _, b := range l.lineBits() {
+		b.s.Valid = iblines&b.valid != 0
+		b.s.Asserted = b.s.Valid && iblines&b.line != 0
+	}
+	return l
+}
+
+func (l Lines) iblines() int {
+	var v int
+	for _, b := range l.lineBits() {
+		if b.s.Valid {
+			v |= b.valid
+			if b.s.Asserted {
+				v |= b.line
+			}
+		}
+	}
+	return v
+}
+
+// String returns the line states in human readable form. Asserted lines are
+// shown in upper case, unasserted lines in lower case, and lines that cannot be
+// monitored are omitted.
+func (l Lines) String() string {
+	return internal.FormatIblines(l.iblines())
+}
+
+// Lines returns the current state of the bus control lines.
+//
+// Sampling the lines does not drive the bus, so the activity callback and
+// interceptors are not informed.
+func (b *Board) Lines() (Lines, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if b.isClosed() {
+		return Lines{}, errors.New("already closed")
+	}
+
+	ibsta, iblines := b.be.Iblines(b.index)
+	if err := b.err(ibsta); err != nil {
+		b.logErr("lines", err, "Board %d returned iblines error: %v", b.index, err)
+		return Lines{}, err
+	}
+	return newLines(iblines), nil
+}
+
+// A LineChange reports a change observed by MonitorLines.
+type LineChange struct {
+	// Time is when the change was observed.
+	Time time.Time
+	// Lines is the new state of the bus control lines. It is the zero value if
+	// Err is not nil.
+	Lines Lines
+	// Held is how long the previous state lasted, as far as can be determined
+	// from the sampling interval. It is zero for the first event.
+	Held time.Duration
+	// Err is the error encountered while sampling, if any.
+	Err error
+}
+
+// MonitorLines samples the bus control lines at the given interval and sends
+// an event on the returned channel whenever they change, beginning with their
+// initial state. Errors are reported as events too, and sampling continues
+// until the context is done or the board is closed, at which point the channel
+// is closed. An interval that is not positive is reported as an error event.
+//
+// A line that stays asserted for much longer than expected indicates a fault:
+// SRQ held by a device that is never serviced, NRFD or NDAC held by a device
+// that has wedged the handshake, or all lines unasserted despite devices being
+// powered on when a cable is loose.
+func (b *Board) MonitorLines(ctx context.Context, interval time.Duration) <-chan LineChange {
+	ch := make(chan LineChange)
+	go func() {
+		defer close(ch)
+		if interval <= 0 {
+			select {
+			case ch <- LineChange{Time: time.Now(), Err: fmt.Errorf("invalid sampling interval %v", interval)}:
+			case <-ctx.Done():
+			case <-b.closed:
+			}
+			return
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		var last LineChange
+		first := true
+		for {
+			now := time.Now()
+			l, err := b.Lines()
+			if err != nil {
+				// The board was closed while waiting to sample.
+				select {
+				case <-b.closed:
+					return
+				default:
+				}
+			}
+			if first || l != last.Lines || !sameError(err, last.Err) {
+				c := LineChange{
+					Time:  now,
+					Lines: l,
+					Err:   err,
+				}
+				if !first {
+					c.Held = now.Sub(last.Time)
+				}
+				select {
+				case ch <- c:
+				case <-ctx.Done():
+					return
+				case <-b.closed:
+					return
+				}
+				last = c
+				first = false
+			}
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			case <-b.closed:
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// sameError returns true if a and b are both nil or have the same message.
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
@@ -0,0 +1,56 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+func TestMonitorLines(t *testing.T) {
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(sim.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	ch := b.MonitorLines(ctx, 0)
+	if c := <-ch; c.Err == nil {
+		t.Errorf("MonitorLines with zero interval got %+v; want an error", c)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("MonitorLines with zero interval did not close the channel")
+	}
+
+	ch = b.MonitorLines(ctx, time.Millisecond)
+	if c := <-ch; c.Err != nil {
+		t.Errorf("MonitorLines got error %v", c.Err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("MonitorLines did not stop after the board closed")
+		}
+	}
+}
@@ -17,9 +17,7 @@ package linuxgpib
 import (
 	"errors"
 	"fmt"
-	"path/filepath"
-	"runtime"
-	"strconv"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -38,9 +36,37 @@ var (
 	// OS thread do not step on each others' ibsta, iberr, and ibcnt values.
 	mu sync.Mutex
 	// Keep a map of boards that are in use to prevent duplicate instances.
-	activeBoards = map[int]bool{}
+	activeBoards = map[boardKey]*Board{}
 )
 
+// ErrNoListeners is matched by errors.Is when an operation fails with ENOL,
+// because no device responded at the address. Timeouts are reported by errors
+// with a Timeout method that returns true, as with os.IsTimeout.
+var ErrNoListeners error = &internal.Error{Iberr: internal.ENOL}
+
+// Errors matched by errors.Is for other failures reported by iberr.
+var (
+	// ErrAborted is matched when an operation fails with EABO, because it
+	// was aborted.
+	ErrAborted error = &internal.Error{Iberr: internal.EABO}
+	// ErrBusError is matched when an operation fails with EBUS, because
+	// command bytes were not accepted on the bus.
+	ErrBusError error = &internal.Error{Iberr: internal.EBUS}
+	// ErrNoCapability is matched when an operation fails with ECAP, because
+	// the board or backend does not support it.
+	ErrNoCapability error = &internal.Error{Iberr: internal.ECAP}
+	// ErrSystem is matched when an operation fails with EDVR, because of a
+	// system error, whatever its errno. The errno itself is matched by
+	// errors.Is with a syscall.Errno.
+	ErrSystem error = &internal.Error{Iberr: internal.EDVR}
+)
+
+// boardKey identifies a board, which is only unique within its backend.
+type boardKey struct {
+	be    Backend
+	index int
+}
+
 // Logger writes lines of output for debug purposes.
 type Logger interface {
 	Printf(string, ...interface{})
@@ -50,12 +76,23 @@ type options struct {
 	timeout  time.Duration
 	readEOS  string
 	logger   Logger
+	handler  slog.Handler
 	activity func(bool)
+	progress func(int64)
+	observe  func(Observation)
+	backend  Backend
+
+	interceptors []Interceptor
+
+	lockScope LockScope
+	lockWait  time.Duration
+	lockDir   string
 }
 
 func newOptions() *options {
 	return &options{
 		timeout: defaultTimeout,
+		backend: libgpib{},
 	}
 }
 
@@ -64,15 +101,6 @@ func cloneOptions(o *options) *options {
 	return &n
 }
 
-func (o *options) logf(format string, v ...interface{}) {
-	if o.logger != nil {
-		if _, f, l, ok := runtime.Caller(1); ok {
-			format = filepath.Base(f) + ":" + strconv.Itoa(l) + " " + format
-		}
-		o.logger.Printf(format, v...)
-	}
-}
-
 // An Option configures GPIB communication with the device.
 type Option func(*options)
 
@@ -111,11 +139,26 @@ func Activity(f func(bool)) Option {
 	}
 }
 
+// Progress registers a callback which is informed of the total number of bytes
+// transferred so far by the streaming methods such as ReadTo and WriteFrom.
+func Progress(f func(int64)) Option {
+	return func(o *options) {
+		o.progress = f
+	}
+}
+
 // Board is a GPIB interface board.
 type Board struct {
 	index         int
+	be            Backend
 	options       *options
-	activeDevices map[int]bool
+	activeDevices map[Address]bool
+	lock          *procLock
+	// closed is closed when the board is.
+	closed chan struct{}
+	// held counts the devices which opened the board implicitly, and close
+	// it when the last of them is closed.
+	held int
 }
 
 func NewBoard(index int, opts ...Option) (*Board, error) {
@@ -125,114 +168,259 @@ func NewBoard(index int, opts ...Option) (*Board, error) {
 	}
 	mu.Lock()
 	defer mu.Unlock()
-	if activeBoards[index] {
+	return newBoard(index, o)
+}
+
+// newBoard implements NewBoard. The caller must hold mu.
+func newBoard(index int, o *options) (*Board, error) {
+	b, existing, err := openBoard(index, o)
+	if err != nil {
+		return nil, err
+	}
+	if existing {
 		return nil, fmt.Errorf("board in use: %d", index)
 	}
-	activeBoards[index] = true
-	o.logf("Opened board %d with version %v", index, internal.Ibvers())
-	return &Board{
+	return b, nil
+}
+
+// openBoard returns the board with the given index, registering it if it is
+// not yet in use, in which case existing is false. The caller must hold mu,
+// which is released while waiting for an inter-process lock.
+func openBoard(index int, o *options) (b *Board, existing bool, err error) {
+	key := boardKey{o.backend, index}
+	if b := activeBoards[key]; b != nil {
+		return b, true, nil
+	}
+	mu.Unlock()
+	lock, err := o.lockBoard(index)
+	mu.Lock()
+	if err != nil {
+		return nil, false, err
+	}
+	if b := activeBoards[key]; b != nil {
+		// Another goroutine opened the board while we were waiting.
+		lock.release()
+		return b, true, nil
+	}
+
+	b = &Board{
 		index:         index,
+		be:            o.backend,
 		options:       o,
-		activeDevices: map[int]bool{},
-	}, nil
+		activeDevices: map[Address]bool{},
+		lock:          lock,
+		closed:        make(chan struct{}),
+	}
+	activeBoards[key] = b
+	o.logf(slog.LevelInfo, boardAttrs(index, "open"), "Opened board %d with version %v", index, o.backend.Ibvers())
+	return b, false, nil
+}
+
+func (b *Board) key() boardKey {
+	return boardKey{b.be, b.index}
+}
+
+// isClosed reports whether the board has been closed. The caller must hold mu.
+func (b *Board) isClosed() bool {
+	return activeBoards[b.key()] != b
+}
+
+// hold records that a device is being opened on the board implicitly, if the
+// board was opened for it or is already held by other devices opened that
+// way, and reports whether it did. The caller must hold mu, and must not have
+// released it since openBoard returned, so that the board cannot be found by
+// another caller before it is held. If opening the device fails, the hold is
+// released by unhold.
+func (b *Board) hold(existing bool) bool {
+	if existing && b.held == 0 {
+		return false
+	}
+	b.held++
+	return true
+}
+
+// unhold releases a hold taken by hold, closing the board if it was the last.
+// The caller must hold mu.
+func (b *Board) unhold() error {
+	b.held--
+	if b.held > 0 {
+		return nil
+	}
+	return b.close()
+}
+
+// Close releases the board so that it may be opened again, along with its
+// inter-process lock if ProcessLock was used. All devices on the board must be
+// closed first.
+func (b *Board) Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	return b.close()
+}
+
+// close implements Close. The caller must hold mu.
+func (b *Board) close() error {
+	if b.isClosed() {
+		return errors.New("already closed")
+	}
+	if n := len(b.activeDevices); n > 0 {
+		return fmt.Errorf("board %d has %d open devices", b.index, n)
+	}
+	delete(activeBoards, b.key())
+	b.lock.release()
+	close(b.closed)
+	b.logf(slog.LevelInfo, "close", nil, "Closed board %d", b.index)
+	return nil
 }
 
 // Device is a connection to a single GPIB device.
 //
 // All methods acquire the global GPIB lock for the duration of their
-// execution, making it safe to use multiple devices each from a different
-// goroutine.
+// execution, apart from calls to interceptors, making it safe to use multiple
+// devices each from a different goroutine.
 type Device struct {
-	addr     int
+	addr     Address
 	board    *Board
 	ud       int
+	be       Backend // the board's, kept for Abort, which does not lock mu
 	options  *options
+	lock     *procLock
 	isClosed bool
+	// ownsBoard is true if the device holds its board open, as described by
+	// Board.hold.
+	ownsBoard bool
+}
+
+// eosMode returns the ibeos setting for the given ReadEOS option.
+func eosMode(readEOS string) (int, error) {
+	switch len(readEOS) {
+	case 0:
+		return 0, nil
+	case 1:
+		return internal.BIN | internal.REOS | int(readEOS[0]), nil
+	default:
+		return 0, errors.New("invalid read eos: must be a single character")
+	}
 }
 
 // NewDevice returns a GPIB device.
 //
-// Board is the board index, 0 for the first board. Address is normally the
-// primary address of the device. Secondary addresses are supported by casting
-// a internal.Address to an int.
-func (b *Board) NewDevice(addr int, opts ...Option) (*Device, error) {
-	a := internal.Address(addr)
+// Address is normally the primary address of the device, given as an untyped
+// constant such as 22. Secondary addresses are supported by NewAddress.
+func (b *Board) NewDevice(addr Address, opts ...Option) (_ *Device, err error) {
+	if err := addr.Validate(); err != nil {
+		return nil, err
+	}
 	o := cloneOptions(b.options)
 	for _, opt := range opts {
 		opt(o)
 	}
 
-	var eos int
-	switch len(o.readEOS) {
-	case 0:
-		break
-	case 1:
-		eos = internal.BIN | internal.REOS | int(o.readEOS[0])
-	default:
-		return nil, errors.New("invalid read eos: must be a single character")
+	eos, err := eosMode(o.readEOS)
+	if err != nil {
+		return nil, err
 	}
 
 	mu.Lock()
 	defer mu.Unlock()
 
+	if b.isClosed() {
+		return nil, errors.New("already closed")
+	}
 	if b.activeDevices[addr] {
-		return nil, fmt.Errorf("device already in use: %d", addr)
+		return nil, fmt.Errorf("device already in use: %v", addr)
 	}
 
-	if b.options.activity != nil {
-		b.options.activity(true)
-		defer b.options.activity(false)
+	// Wait for any other process to finish with the address.
+	mu.Unlock()
+	lock, err := o.lockAddress(b.index, addr)
+	mu.Lock()
+	if err != nil {
+		return nil, err
 	}
-
-	if len(b.activeDevices) == 0 {
-		if err := internal.Err(internal.Ibsre(b.index, 1)); err != nil {
-			o.logf("Failed to enable remote mode on board %d", b.index)
-			return nil, errors.New("ibsre failed")
+	if b.isClosed() {
+		lock.release()
+		return nil, errors.New("already closed")
+	}
+	if b.activeDevices[addr] {
+		lock.release()
+		return nil, fmt.Errorf("device already in use: %v", addr)
+	}
+	opened := false
+	defer func() {
+		if !opened {
+			lock.release()
 		}
+	}()
+
+	op := &Operation{Kind: "open", Board: b.index, Device: true, Address: addr}
+	if err := o.begin(op); err != nil {
+		return nil, err
+	}
+	defer func() { op.end(0, nil, err) }()
+	if b.isClosed() {
+		return nil, errors.New("already closed")
+	}
+	if b.activeDevices[addr] {
+		return nil, fmt.Errorf("device already in use: %v", addr)
 	}
 
 	// Open the device.
-	pad := a.Primary()
-	sad := a.Secondary()
+	pad := addr.Primary()
+	sad := addr.Secondary()
 	tmo := internal.Timeout(o.timeout)
-	ud := internal.Ibdev(b.index, pad, sad, tmo, 1 /*eoi*/, eos)
+	ud := b.be.Ibdev(b.index, pad, sad, tmo, 1 /*eoi*/, eos)
 	if ud == -1 {
-		if err := internal.Err(internal.Ibsta()); err != nil {
-			o.logf("Failed to open address %d (%d/%d) on board %d: %v", addr, pad, sad, b.index, err)
+		if err := b.err(b.be.Ibsta()); err != nil {
+			o.logf(errLevel(err), append(deviceAttrs(b.index, addr, -1, "open"), errAttrs(b.be, err)...), "Failed to open address %v on board %d: %v", addr, b.index, err)
 			return nil, err
 		}
-		o.logf("Failed to open address %d (%d/%d) on board %d: unknown error", addr, pad, sad, b.index)
+		o.logf(slog.LevelError, deviceAttrs(b.index, addr, -1, "open"), "Failed to open address %v on board %d: unknown error", addr, b.index)
 		return nil, errors.New("ibdev failed without setting an error")
 	}
 
+	// Remote enable is asserted only once the device is open, because a
+	// shared backend such as gpibd accepts board operations only from clients
+	// with a device on the board.
+	if len(b.activeDevices) == 0 {
+		if err := b.err(b.be.Ibsre(b.index, 1)); err != nil {
+			o.logf(errLevel(err), append(boardAttrs(b.index, "open"), errAttrs(b.be, err)...), "Failed to enable remote mode on board %d", b.index)
+			b.be.Ibonl(ud, 0)
+			return nil, errors.New("ibsre failed")
+		}
+	}
+
 	b.activeDevices[addr] = true
+	opened = true
 
-	o.logf("Opened address %d (%d/%d) on board %d as device %d", addr, pad, sad, b.index, ud)
+	o.logf(slog.LevelInfo, deviceAttrs(b.index, addr, ud, "open"), "Opened address %v on board %d as device %d", addr, b.index, ud)
 	return &Device{
 		addr:    addr,
 		board:   b,
 		ud:      ud,
+		be:      b.be,
 		options: o,
+		lock:    lock,
 	}, nil
 }
 
 // Clear issues a GPIB device clear command.
-func (d *Device) Clear() error {
+func (d *Device) Clear() (err error) {
 	mu.Lock()
 	defer mu.Unlock()
 	if d.isClosed {
 		return errors.New("already closed")
 	}
-
-	if d.options.activity != nil {
-		d.options.activity(true)
-		defer d.options.activity(false)
+	op, err := d.begin("clear", nil)
+	if err != nil {
+		return err
 	}
+	defer func() { op.end(0, nil, err) }()
 
 	// Clear the device.
-	d.options.logf("Clearing device at address %d", d.addr)
-	if err := internal.Err(internal.Ibclr(d.ud)); err != nil {
-		d.options.logf("Failed to clear device %d: %v", d.ud, err)
+	d.logf(slog.LevelInfo, "clear", nil, "Clearing device at address %v", d.addr)
+	if err := d.err(d.board.be.Ibclr(d.ud)); err != nil {
+		d.logErr("clear", err, "Failed to clear device %d: %v", d.ud, err)
 		return err
 	}
 
@@ -242,9 +430,9 @@ func (d *Device) Clear() error {
 	cleared := time.Now()
 	for {
 		time.Sleep(50 * time.Millisecond)
-		ibsta, lines := internal.Iblines(d.board.index)
-		if err := internal.Err(ibsta); err != nil {
-			d.options.logf("Failed to monitor iblines after clearing device %d: %v", d.ud, err)
+		ibsta, lines := d.board.be.Iblines(d.board.index)
+		if err := d.err(ibsta); err != nil {
+			d.logErr("clear", err, "Failed to monitor iblines after clearing device %d: %v", d.ud, err)
 			return err
 		}
 		if lines&internal.ValidNRFD == 0 {
@@ -258,7 +446,7 @@ func (d *Device) Clear() error {
 			break
 		}
 		if d.options.timeout != 0 && time.Now().Sub(cleared) > d.options.timeout {
-			d.options.logf("Timed out after clearing device %d", d.ud)
+			d.logErr("clear", internal.TimeoutErr, "Timed out after clearing device %d", d.ud)
 			return internal.TimeoutErr
 		}
 	}
@@ -266,8 +454,22 @@ func (d *Device) Clear() error {
 	return nil
 }
 
+// Abort aborts an operation in progress on the device in another goroutine,
+// which then fails with EABO. Unlike the other methods, it does not acquire the
+// global GPIB lock. Only backends which implement Aborter, such as the VXI-11
+// client, can abort operations.
+func (d *Device) Abort() error {
+	// The descriptor and backend do not change while the device is open, so
+	// they may be used without the lock.
+	a, ok := d.be.(Aborter)
+	if !ok {
+		return errors.New("backend cannot abort operations")
+	}
+	return a.Abort(d.ud)
+}
+
 // Close releases resources associated with the GPIB device.
-func (d *Device) Close() error {
+func (d *Device) Close() (err error) {
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -275,23 +477,37 @@ func (d *Device) Close() error {
 		return errors.New("already closed")
 	}
 
-	if d.options.activity != nil {
-		d.options.activity(true)
-		defer d.options.activity(false)
+	// An interceptor cannot prevent the device from being released, since
+	// the caller has no way to retry.
+	op, berr := d.begin("close", nil)
+	if d.isClosed {
+		// Closed by another caller while the interceptors were called.
+		return errors.New("already closed")
+	}
+	if berr == nil {
+		defer func() { op.end(0, nil, err) }()
 	}
 
 	d.isClosed = true
 	delete(d.board.activeDevices, d.addr)
-
-	d.options.logf("Closing address %d", d.addr)
-	if err := internal.Err(internal.Ibonl(d.ud, 0)); err != nil {
-		d.options.logf("Failed to close address %d device %d: %v", d.addr, d.ud, err)
+	defer d.lock.release()
+	if d.ownsBoard {
+		defer func() {
+			if uerr := d.board.unhold(); err == nil {
+				err = uerr
+			}
+		}()
+	}
+
+	d.logf(slog.LevelInfo, "close", nil, "Closing address %v", d.addr)
+	if err := d.err(d.board.be.Ibonl(d.ud, 0)); err != nil {
+		d.logErr("close", err, "Failed to close address %v device %d: %v", d.addr, d.ud, err)
 		return err
 	}
 
 	if len(d.board.activeDevices) == 0 {
-		if err := internal.Err(internal.Ibsre(d.board.index, 0)); err != nil {
-			d.options.logf("Failed to disable remote mode on board %d", d.board.index)
+		if err := d.err(d.board.be.Ibsre(d.board.index, 0)); err != nil {
+			d.logErr("close", err, "Failed to disable remote mode on board %d", d.board.index)
 			return errors.New("ibsre failed")
 		}
 	}
@@ -302,35 +518,54 @@ func (d *Device) Close() error {
 // formatLog returns a possibly shortened representation of the input data for
 // logging.
 func formatLog(b []byte) string {
-	if len(b) > maxLogData {
-		return fmt.Sprintf("%q...(%d bytes total)", string(b[:maxLogData-minLogHide]), len(b))
+	return formatLogTotal(b, int64(len(b)))
+}
+
+// formatLogTotal is like formatLog, but for data of which only the first few
+// bytes are available.
+func formatLogTotal(b []byte, total int64) string {
+	if total > maxLogData {
+		if len(b) > maxLogData-minLogHide {
+			b = b[:maxLogData-minLogHide]
+		}
+		return fmt.Sprintf("%q...(%d bytes total)", string(b), total)
 	}
 	return fmt.Sprintf("%q", string(b))
 }
 
 // Read gets data from the GPIB device.
 func (d *Device) Read(b []byte) (n int, err error) {
+	n, _, err = d.ReadEnd(b)
+	return
+}
+
+// ReadEnd is like Read, but also reports whether the read ended because the
+// device asserted EOI or sent the character configured by ReadEOS, rather than
+// because b was filled.
+func (d *Device) ReadEnd(b []byte) (n int, end bool, err error) {
 	mu.Lock()
 	defer mu.Unlock()
 	if d.isClosed {
-		return 0, errors.New("already closed")
+		return 0, false, errors.New("already closed")
 	}
 
-	if d.options.activity != nil {
-		d.options.activity(true)
-		defer d.options.activity(false)
+	op, err := d.begin("read", nil)
+	if err != nil {
+		return 0, false, err
 	}
+	defer func() { op.end(int64(n), b[:min(max(n, 0), len(b))], err) }()
 
 	started := time.Now()
-	ibsta := internal.Ibrd(d.ud, b)
+	ibsta := d.board.be.Ibrd(d.ud, b)
 	took := time.Since(started)
-	err = internal.Err(ibsta)
-	n = internal.Ibcnt()
+	err = d.err(ibsta)
+	n = d.board.be.Ibcnt()
+	end = ibsta&internal.END != 0
 
 	if err != nil {
-		d.options.logf("Failed to read from address %d device %d: %v", d.addr, d.ud, err)
+		d.logErr("read", err, "Failed to read from address %v device %d: %v", d.addr, d.ud, err)
 	} else {
-		d.options.logf("Read %s in %v from address %d", formatLog(b[:n]), took.Truncate(time.Millisecond), d.addr)
+		d.logf(slog.LevelDebug, "read", transferAttrs(int64(n), took), "Read %s in %v from address %v", formatLog(b[:n]), took.Truncate(time.Millisecond), d.addr)
 	}
 	return
 }
@@ -339,112 +574,210 @@ func (d *Device) Read(b []byte) (n int, err error) {
 //
 // The duration will be rounded up to one of the discrete values in
 // https://linux-gpib.sourceforge.io/doc_html/reference-function-ibtmo.html
-func (d *Device) SetTimeout(t time.Duration) error {
+func (d *Device) SetTimeout(t time.Duration) (err error) {
 	mu.Lock()
 	defer mu.Unlock()
 	if d.isClosed {
 		return errors.New("already closed")
 	}
 
-	if d.options.activity != nil {
-		d.options.activity(true)
-		defer d.options.activity(false)
+	op, err := d.begin("timeout", nil)
+	if err != nil {
+		return err
 	}
+	defer func() { op.end(0, nil, err) }()
 
-	d.options.logf("Setting timeout to %v on address %d", t, d.addr)
-	if err := internal.Err(internal.Ibtmo(d.ud, internal.Timeout(t))); err != nil {
-		d.options.logf("Failed to set timeout on address %d device %d: %v", d.addr, d.ud, err)
+	d.logf(slog.LevelInfo, "timeout", []slog.Attr{slog.Duration("timeout", t)}, "Setting timeout to %v on address %v", t, d.addr)
+	if err := d.err(d.board.be.Ibtmo(d.ud, internal.Timeout(t))); err != nil {
+		d.logErr("timeout", err, "Failed to set timeout on address %v device %d: %v", d.addr, d.ud, err)
 		return err
 	}
+	d.options.timeout = t
 	return nil
 }
 
+// Timeout returns the timeout for GPIB operations, or zero if there is none.
+func (d *Device) Timeout() time.Duration {
+	mu.Lock()
+	defer mu.Unlock()
+	return d.options.timeout
+}
+
 // Spoll gets the status byte from a device via serial poll.
-func (d *Device) Spoll() (byte, error) {
+func (d *Device) Spoll() (spr byte, err error) {
 	mu.Lock()
 	defer mu.Unlock()
 	if d.isClosed {
 		return 0, errors.New("already closed")
 	}
 
-	if d.options.activity != nil {
-		d.options.activity(true)
-		defer d.options.activity(false)
+	op, err := d.begin("spoll", nil)
+	if err != nil {
+		return 0, err
 	}
+	defer func() {
+		var result []byte
+		if err == nil {
+			result = []byte{spr}
+		}
+		op.end(0, result, err)
+	}()
 
 	started := time.Now()
-	ibsta, spr := internal.Ibrsp(d.ud)
+	ibsta, spr := d.board.be.Ibrsp(d.ud)
 	took := time.Since(started)
-	if err := internal.Err(ibsta); err != nil {
-		d.options.logf("Failed to poll address %d device %d: %v", d.addr, d.ud, err)
+	err = d.err(ibsta)
+	if err != nil {
+		d.logErr("spoll", err, "Failed to poll address %v device %d: %v", d.addr, d.ud, err)
 		return 0, err
 	}
-	d.options.logf("Polled status %02X in %v from address %d", spr, took.Truncate(time.Millisecond), d.addr)
+	d.logf(slog.LevelDebug, "spoll", []slog.Attr{slog.Int("status", int(spr)), slog.Duration("duration", took)}, "Polled status %02X in %v from address %v", spr, took.Truncate(time.Millisecond), d.addr)
 
 	return spr, nil
 }
 
 // Trigger sends a GET (group execute trigger) command to the device.
-func (d *Device) Trigger() error {
+func (d *Device) Trigger() (err error) {
 	mu.Lock()
 	defer mu.Unlock()
 	if d.isClosed {
 		return errors.New("already closed")
 	}
 
-	if d.options.activity != nil {
-		d.options.activity(true)
-		defer d.options.activity(false)
+	op, err := d.begin("trigger", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { op.end(0, nil, err) }()
+
+	d.logf(slog.LevelInfo, "trigger", nil, "Triggering device at address %v", d.addr)
+	if err := d.err(d.board.be.Ibtrg(d.ud)); err != nil {
+		d.logErr("trigger", err, "Failed to trigger address %v device %d: %v", d.addr, d.ud, err)
+		return err
+	}
+
+	return nil
+}
+
+// Local returns the device to local control, using ibloc.
+func (d *Device) Local() (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.isClosed {
+		return errors.New("already closed")
+	}
+
+	op, err := d.begin("local", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { op.end(0, nil, err) }()
+
+	d.logf(slog.LevelInfo, "local", nil, "Returning device at address %v to local control", d.addr)
+	if err := d.err(d.board.be.Ibloc(d.ud)); err != nil {
+		d.logErr("local", err, "Failed to return address %v device %d to local: %v", d.addr, d.ud, err)
+		return err
 	}
+	return nil
+}
 
-	d.options.logf("Triggering device at address %d", d.addr)
-	ibsta := internal.Ibtrg(d.ud)
-	if err := internal.Err(ibsta); err != nil {
-		d.options.logf("Failed to trigger address %d device %d: %v", d.addr, d.ud, err)
+// Remote places the device under remote control, by asserting REN and
+// addressing the device to listen.
+func (d *Device) Remote() (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.isClosed {
+		return errors.New("already closed")
+	}
+
+	op, err := d.begin("remote", nil)
+	if err != nil {
 		return err
 	}
+	defer func() { op.end(0, nil, err) }()
 
+	// The address was validated when the device was opened.
+	cmds := Commands{UNL, Command(internal.LAD | d.addr.Primary())}
+	if sad := d.addr.Secondary(); sad != 0 {
+		cmds = append(cmds, Command(sad))
+	}
+	cmds = append(cmds, UNL)
+
+	d.logf(slog.LevelInfo, "remote", nil, "Placing device at address %v under remote control", d.addr)
+	be := d.board.be
+	if err := d.err(be.Ibsre(d.board.index, 1)); err != nil {
+		d.logErr("remote", err, "Failed to enable remote mode on board %d: %v", d.board.index, err)
+		return err
+	}
+	if err := d.err(be.Ibcmd(d.board.index, cmds.Bytes())); err != nil {
+		d.logErr("remote", err, "Failed to send %v to board %d: %v", cmds, d.board.index, err)
+		return err
+	}
 	return nil
 }
 
-// Write sends data to the GPIB device.
+// Write sends data to the GPIB device, asserting EOI with the last byte.
 func (d *Device) Write(b []byte) (n int, err error) {
+	return d.WriteEnd(b, true)
+}
+
+// WriteEnd is like Write, but asserts EOI with the last byte only if end is
+// true. Otherwise the device waits for the rest of the message in a later
+// write.
+func (d *Device) WriteEnd(b []byte, end bool) (n int, err error) {
 	mu.Lock()
 	defer mu.Unlock()
 	if d.isClosed {
 		return 0, errors.New("already closed")
 	}
 
-	if d.options.activity != nil {
-		d.options.activity(true)
-		defer d.options.activity(false)
+	op, err := d.begin("write", b)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { op.end(int64(n), nil, err) }()
+
+	if !end {
+		if err := d.err(d.board.be.Ibeot(d.ud, 0)); err != nil {
+			d.logErr("write", err, "Failed to set EOI mode on address %v device %d: %v", d.addr, d.ud, err)
+			return 0, err
+		}
+		defer func() {
+			if err := d.err(d.board.be.Ibeot(d.ud, 1)); err != nil {
+				d.logErr("write", err, "Failed to restore EOI mode on address %v device %d: %v", d.addr, d.ud, err)
+			}
+		}()
 	}
 
 	started := time.Now()
-	ibsta := internal.Ibwrt(d.ud, b)
+	ibsta := d.board.be.Ibwrt(d.ud, b)
 	took := time.Since(started)
-	err = internal.Err(ibsta)
-	n = internal.Ibcnt()
+	err = d.err(ibsta)
+	n = d.board.be.Ibcnt()
 
 	if err != nil {
-		d.options.logf("Failed to write to address %d device %d: %v", d.addr, d.ud, err)
+		d.logErr("write", err, "Failed to write to address %v device %d: %v", d.addr, d.ud, err)
 		return
 	}
 
-	d.options.logf("Wrote %s in %v to address %d", formatLog(b), took.Truncate(time.Millisecond), d.addr)
+	d.logf(slog.LevelDebug, "write", transferAttrs(int64(n), took), "Wrote %s in %v to address %v", formatLog(b), took.Truncate(time.Millisecond), d.addr)
 
 	return
 }
 
 // Enumerate returns the primary addresses of all devices on the bus.
-func (b *Board) Enumerate() ([]int, error) {
+func (b *Board) Enumerate() (_ []Address, err error) {
 	mu.Lock()
 	defer mu.Unlock()
+	if b.isClosed() {
+		return nil, errors.New("already closed")
+	}
 
-	if b.options.activity != nil {
-		b.options.activity(true)
-		defer b.options.activity(false)
+	op, err := b.begin("enumerate", nil)
+	if err != nil {
+		return nil, err
 	}
+	defer func() { op.end(0, nil, err) }()
 
 	started := time.Now()
 
@@ -452,49 +785,68 @@ func (b *Board) Enumerate() ([]int, error) {
 	// necessary because some older devices like the HP 3478A, if previously
 	// addressed as talker, will write data to the bus as soon as another device
 	// is addressed as a listener by ibln.
-	ibsta := internal.Ibsic(b.index)
-	if err := internal.Err(ibsta); err != nil {
-		b.options.logf("Board %d returned ibsic error: %v", b.index, err)
+	ibsta := b.be.Ibsic(b.index)
+	if err := b.err(ibsta); err != nil {
+		b.logErr("enumerate", err, "Board %d returned ibsic error: %v", b.index, err)
 		return nil, err
 	}
 
 	// Verify that the board has the capabilities needed for enumeration.
-	ibsta, iblines := internal.Iblines(b.index)
-	if err := internal.Err(ibsta); err != nil {
-		b.options.logf("Board %d returned iblines error: %v", b.index, err)
+	ibsta, iblines := b.be.Iblines(b.index)
+	if err := b.err(ibsta); err != nil {
+		b.logErr("enumerate", err, "Board %d returned iblines error: %v", b.index, err)
 		return nil, err
 	}
 	if iblines&internal.ValidNDAC == 0 {
-		b.options.logf("Board %d does not support monitoring NDAC", b.index)
+		b.logf(slog.LevelError, "enumerate", nil, "Board %d does not support monitoring NDAC", b.index)
 		return nil, errors.New("board does not support monitoring NDAC")
 	}
 
 	// Check all the addresses to see if a listener is present, except address 0
 	// which is the controller.
-	var ds []int
-	for i := 1; i <= 30; i++ {
-		ibsta, found := internal.Ibln(b.index, i, 0)
-		if err := internal.Err(ibsta); err != nil {
-			b.options.logf("Failed to enumerate board %d address %d: %v", b.index, i, err)
+	var ds []Address
+	for i := Address(1); i <= 30; i++ {
+		ibsta, found := b.be.Ibln(b.index, i.Primary(), 0)
+		if err := b.err(ibsta); err != nil {
+			b.logErr("enumerate", err, "Failed to enumerate board %d address %v: %v", b.index, i, err)
 			return nil, err
 		}
 		if found != 0 {
-			b.options.logf("Found device at address %d on board %d", i, b.index)
+			b.logf(slog.LevelInfo, "enumerate", []slog.Attr{slog.String("address", i.String())}, "Found device at address %v on board %d", i, b.index)
 			ds = append(ds, i)
 		}
 	}
 
-	b.options.logf("Found %d devices in %v on board %d", len(ds), time.Since(started).Truncate(time.Millisecond), b.index)
+	took := time.Since(started)
+	b.logf(slog.LevelInfo, "enumerate", []slog.Attr{slog.Int("devices", len(ds)), slog.Duration("duration", took)}, "Found %d devices in %v on board %d", len(ds), took.Truncate(time.Millisecond), b.index)
 
 	return ds, nil
 }
 
 // NewDevice returns a GPIB device. See Board's NewDevice method for more
 // details.
-func NewDevice(board, addr int, opts ...Option) (*Device, error) {
-	b, err := NewBoard(board, opts...)
+func NewDevice(board int, addr Address, opts ...Option) (*Device, error) {
+	o := newOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	mu.Lock()
+	b, err := newBoard(board, o)
+	if err == nil {
+		b.hold(false)
+	}
+	mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := b.NewDevice(addr)
+	mu.Lock()
+	defer mu.Unlock()
 	if err != nil {
+		b.unhold()
 		return nil, err
 	}
-	return b.NewDevice(addr)
+	d.ownsBoard = true
+	return d, nil
 }
@@ -12,6 +12,7 @@
 package linuxgpib
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -39,3 +40,277 @@ func TestFormatLog(t *testing.T) {
 		}
 	}
 }
+
+func TestFormatLogTotal(t *testing.T) {
+	var h logHead
+	for i := 0; i < 10; i++ {
+		h.add([]byte("0123456789"))
+	}
+	if len(h) != maxLogData+1 {
+		t.Fatalf("head holds %d bytes, want %d", len(h), maxLogData+1)
+	}
+	for i, c := range []struct {
+		Head  []byte
+		Total int64
+		Want  string
+	}{
+		{
+			[]byte("0123456789"),
+			10,
+			"\"0123456789\"",
+		},
+		{
+			h,
+			100,
+			"\"0123456789012345678901234567890123456789\"...(100 bytes total)",
+		},
+		{
+			[]byte("0123456789"),
+			1000000,
+			"\"0123456789\"...(1000000 bytes total)",
+		},
+	} {
+		g := formatLogTotal(c.Head, c.Total)
+		if g != c.Want {
+			t.Errorf("%d: got %v, want %v", i, g, c.Want)
+		}
+	}
+}
+
+// mustCommand returns the command, panicking if there is an error.
+func mustCommand(c Command, err error) Command {
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestCommands(t *testing.T) {
+	for i, c := range []struct {
+		Input Commands
+		Want  string
+		Bytes string
+		Valid bool
+	}{
+		{
+			Commands{UNL, UNT, mustCommand(Talk(0)), mustCommand(Listen(22)), mustCommand(Secondary(0x63)), GET},
+			"UNL UNT TAD0 LAD22 SAD3 GET",
+			"\x3f\x5f\x40\x36\x63\x08",
+			true,
+		},
+		{
+			Commands{DCL},
+			"DCL",
+			"\x14",
+			true,
+		},
+		{
+			Commands{mustCommand(Listen(5)), PPC, mustCommand(PPE(3, true)), PPC, PPD},
+			"LAD5 PPC PPE3/1 PPC PPD",
+			"\x25\x05\x6a\x05\x70",
+			true,
+		},
+		{
+			Commands{UNL, mustCommand(Secondary(0x61))},
+			"UNL SAD1",
+			"\x3f\x61",
+			false,
+		},
+		{
+			Commands{mustCommand(Listen(5)), PPC, mustCommand(PPE(8, false))},
+			"LAD5 PPC PPE8/0",
+			"\x25\x05\x67",
+			true,
+		},
+		{
+			Commands{mustCommand(Talk(3)), Command(0x7f)},
+			"TAD3 SAD31",
+			"\x43\x7f",
+			false,
+		},
+		{
+			Commands{Command(0x00), Command(0x80)},
+			"0x00 0x80",
+			"\x00\x80",
+			false,
+		},
+	} {
+		if g := c.Input.String(); g != c.Want {
+			t.Errorf("%d: got %v, want %v", i, g, c.Want)
+		}
+		if g := string(c.Input.Bytes()); g != c.Bytes {
+			t.Errorf("%d: got bytes %q, want %q", i, g, c.Bytes)
+		}
+		if err := c.Input.Validate(); (err == nil) != c.Valid {
+			t.Errorf("%d: got error %v, want valid %v", i, err, c.Valid)
+		}
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	for name, f := range map[string]func() (Command, error){
+		"Listen(31)":     func() (Command, error) { return Listen(31) },
+		"Listen(-1)":     func() (Command, error) { return Listen(-1) },
+		"Talk(40)":       func() (Command, error) { return Talk(40) },
+		"Secondary(5)":   func() (Command, error) { return Secondary(5) },
+		"Secondary(127)": func() (Command, error) { return Secondary(127) },
+		"PPE(0)":         func() (Command, error) { return PPE(0, true) },
+		"PPE(9)":         func() (Command, error) { return PPE(9, false) },
+	} {
+		if c, err := f(); err == nil {
+			t.Errorf("%s = %v; want an error", name, c)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	for i, c := range []struct {
+		Iblines int
+		Want    string
+		Raw     int
+	}{
+		{0x0000, "0", 0x0000},
+		{0x20ff, "20ff eoi atn SRQ ren ifc nrfd ndac dav", 0x20ff},
+		{0x0606, "606 NRFD NDAC", 0x0606},
+		{0xff00, "0", 0x0000},
+	} {
+		l := newLines(c.Iblines)
+		if g := l.String(); g != c.Want {
+			t.Errorf("%d: got %v, want %v", i, g, c.Want)
+		}
+		if g := l.iblines(); g != c.Raw {
+			t.Errorf("%d: got iblines 0x%x, want 0x%x", i, g, c.Raw)
+		}
+	}
+	if l := newLines(0x20ff); !l.SRQ.Asserted || l.ATN.Asserted || !l.DAV.Valid {
+		t.Errorf("bad decoding of 0x20ff: %+v", l)
+	}
+}
+
+func TestParseResource(t *testing.T) {
+	for _, c := range []struct {
+		Input string
+		Want  string
+		Err   string
+	}{
+		{"GPIB0::22::INSTR", "GPIB0::22::INSTR", ""},
+		{"gpib1::5::3::instr", "GPIB1::5::3::INSTR", ""},
+		{"GPIB::7", "GPIB0::7::INSTR", ""},
+		{"GPIB2::INTFC", "GPIB2::INTFC", ""},
+		{"TCPIP0::1.2.3.4::INSTR", "", "must begin with GPIB"},
+		{"GPIBX::1::INSTR", "", `board "X" is not a number from 0 to`},
+		{"GPIB0", "", "missing address or INTFC"},
+		{"GPIB0::INSTR", "", "missing primary address"},
+		{"GPIB0::31::INSTR", "", `primary address "31" is not a number from 0 to 30`},
+		{"GPIB0::5::-1::INSTR", "", `secondary address "-1" is not a number from 0 to 30`},
+		{"GPIB0::5::3::4::INSTR", "", `unexpected "4" after addresses`},
+		{"GPIB0::5::INTFC", "", `secondary address "INTFC" is not`},
+	} {
+		r, err := ParseResource(c.Input)
+		if c.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.Err) {
+				t.Errorf("ParseResource(%q): got error %v, want %v", c.Input, err, c.Err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseResource(%q): %v", c.Input, err)
+			continue
+		}
+		if g := r.String(); g != c.Want {
+			t.Errorf("ParseResource(%q) = %v, want %v", c.Input, g, c.Want)
+		}
+	}
+}
+
+func TestAddress(t *testing.T) {
+	for _, c := range []struct {
+		Pad, Sad int
+		Want     string
+		Err      bool
+	}{
+		{22, 0, "22", false},
+		{5, 0x63, "5.3", false},
+		{0, 0x60, "0.0", false},
+		{30, 0x7e, "30.30", false},
+		{31, 0, "", true},
+		{5, 3, "", true},
+		{5, 0x7f, "", true},
+		{-1, 0, "", true},
+	} {
+		a, err := NewAddress(c.Pad, c.Sad)
+		if c.Err {
+			if err == nil {
+				t.Errorf("NewAddress(%d, 0x%x) = %v; want error", c.Pad, c.Sad, a)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewAddress(%d, 0x%x): %v", c.Pad, c.Sad, err)
+			continue
+		}
+		if g := a.String(); g != c.Want {
+			t.Errorf("NewAddress(%d, 0x%x) = %v; want %v", c.Pad, c.Sad, g, c.Want)
+		}
+		if a.Primary() != c.Pad || a.Secondary() != c.Sad {
+			t.Errorf("NewAddress(%d, 0x%x) has parts %d, 0x%x", c.Pad, c.Sad, a.Primary(), a.Secondary())
+		}
+		p, err := ParseAddress(c.Want)
+		if err != nil || p != a {
+			t.Errorf("ParseAddress(%q) = %v, %v; want %v", c.Want, p, err, a)
+		}
+	}
+	if a := Address(22); a.String() != "22" {
+		t.Errorf("Address(22) = %v", a)
+	}
+	for _, s := range []string{"", "x", "31", "5.", "5.31", "5.-1", "-1"} {
+		if a, err := ParseAddress(s); err == nil {
+			t.Errorf("ParseAddress(%q) = %v; want error", s, a)
+		}
+	}
+}
+
+func TestClosedBoard(t *testing.T) {
+	be := &fakeBackend{names: map[string]Resource{"dmm": {Address: 5}}}
+	b, err := NewBoard(0, UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The board may be opened again, but the closed one stays unusable.
+	again, err := NewBoard(0, UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+
+	if _, err := b.NewDevice(22); err == nil {
+		t.Error("NewDevice on a closed board succeeded")
+	}
+	if _, err := b.OpenByName("dmm"); err == nil {
+		t.Error("OpenByName on a closed board succeeded")
+	}
+	if _, err := b.Lines(); err == nil {
+		t.Error("Lines on a closed board succeeded")
+	}
+	if _, err := b.Enumerate(); err == nil {
+		t.Error("Enumerate on a closed board succeeded")
+	}
+	for name, f := range map[string]func() error{
+		"Command":         func() error { return b.Command(UNL) },
+		"InterfaceClear":  b.InterfaceClear,
+		"SetRemoteEnable": func() error { return b.SetRemoteEnable(true) },
+		"DeviceClearAll":  b.DeviceClearAll,
+		"Reset":           func() error { return b.Reset() },
+		"Close":           b.Close,
+	} {
+		if err := f(); err == nil {
+			t.Errorf("%s on a closed board succeeded", name)
+		}
+	}
+	if len(be.devices) != 0 {
+		t.Errorf("%d devices were opened on a closed board", len(be.devices))
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultLockDir = "/run/lock"
+	lockPoll       = 50 * time.Millisecond
+)
+
+// LockScope selects what a process locks against use by other processes.
+type LockScope int
+
+const (
+	// LockNone disables inter-process locking. This is the default.
+	LockNone LockScope = iota
+	// LockBoard gives the process exclusive use of the board for as long as it
+	// is open.
+	LockBoard
+	// LockAddress gives the process exclusive use of each address for as long
+	// as a device is open at that address. Other processes may use other
+	// addresses on the same board, unless they lock the whole board.
+	LockAddress
+)
+
+// ProcessLock enables advisory locking against other processes which use this
+// package, so that two programs cannot corrupt each other's transfers. Locks
+// are taken with flock on files in /run/lock, or the directory set by LockDir.
+//
+// If the lock is held by another process, opening the board or device waits
+// for up to the given duration for it to be released. A negative duration
+// waits forever. On failure the error is a *LockError naming the holder.
+func ProcessLock(scope LockScope, wait time.Duration) Option {
+	return func(o *options) {
+		o.lockScope = scope
+		o.lockWait = wait
+	}
+}
+
+// LockDir sets the directory for the lock files used by ProcessLock.
+func LockDir(dir string) Option {
+	return func(o *options) {
+		o.lockDir = dir
+	}
+}
+
+// A LockError reports that a board or address is locked by another process.
+type LockError struct {
+	// What describes what is locked, such as "board 0".
+	What string
+	// Path is the lock file.
+	Path string
+	// PIDs are the processes holding the lock, if they could be determined.
+	PIDs []int
+}
+
+func (e *LockError) Error() string {
+	switch len(e.PIDs) {
+	case 0:
+		return fmt.Sprintf("%s is locked by another process (%s)", e.What, e.Path)
+	case 1:
+		return fmt.Sprintf("%s is locked by process %d (%s)", e.What, e.PIDs[0], e.Path)
+	}
+	pids := make([]string, len(e.PIDs))
+	for i, p := range e.PIDs {
+		pids[i] = strconv.Itoa(p)
+	}
+	return fmt.Sprintf("%s is locked by processes %s (%s)", e.What, strings.Join(pids, ", "), e.Path)
+}
+
+// procLock is an advisory lock on a file, held until released or until the
+// process exits.
+type procLock struct {
+	f         *os.File
+	exclusive bool
+}
+
+// lockBoard takes the inter-process lock for a board, if enabled. It must not
+// be called with mu held, because it may wait for another process.
+func (o *options) lockBoard(index int) (*procLock, error) {
+	if o.lockScope == LockNone {
+		return nil, nil
+	}
+	// A board lock excludes address locks on the same board, which take it
+	// in shared mode.
+	return o.acquireLock(fmt.Sprintf("gpib%d", index), fmt.Sprintf("board %d", index), o.lockScope == LockBoard)
+}
+
+// lockAddress takes the inter-process lock for an address, if enabled. It must
+// not be called with mu held, because it may wait for another process.
+func (o *options) lockAddress(index int, addr Address) (*procLock, error) {
+	if o.lockScope != LockAddress {
+		return nil, nil
+	}
+	return o.acquireLock(fmt.Sprintf("gpib%d-%v", index, addr), fmt.Sprintf("address %v on board %d", addr, index), true)
+}
+
+func (o *options) acquireLock(name, what string, exclusive bool) (*procLock, error) {
+	dir := o.lockDir
+	if dir == "" {
+		dir = defaultLockDir
+	}
+	path := filepath.Join(dir, "linuxgpib-"+name+".lock")
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o666)
+	if errors.Is(err, os.ErrPermission) {
+		// Another user created the file. Locking does not require write access.
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	started := time.Now()
+	for {
+		err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+		}
+		if o.lockWait >= 0 && time.Since(started) >= o.lockWait {
+			e := &LockError{What: what, Path: path, PIDs: lockHolders(f)}
+			f.Close()
+			o.logf(slog.LevelWarn, []slog.Attr{slog.String("op", "lock"), slog.String("lock", what), slog.String("error", e.Error())}, "Failed to lock %s: %v", what, e)
+			return nil, e
+		}
+		time.Sleep(lockPoll)
+	}
+
+	if exclusive {
+		// Record the holder for systems where /proc/locks is unavailable.
+		if f.Truncate(0) == nil {
+			f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+		}
+	}
+	took := time.Since(started)
+	o.logf(slog.LevelInfo, []slog.Attr{slog.String("op", "lock"), slog.String("lock", what), slog.Duration("duration", took)}, "Locked %s in %v", what, took.Truncate(time.Millisecond))
+	return &procLock{f: f, exclusive: exclusive}, nil
+}
+
+// release unlocks the file. It is safe to call on a nil lock.
+func (l *procLock) release() {
+	if l == nil {
+		return
+	}
+	if l.exclusive {
+		l.f.Truncate(0)
+	}
+	// Closing the file releases the lock.
+	l.f.Close()
+}
+
+// lockHolders returns the processes that hold a lock on f, first according to
+// the kernel, or failing that according to the PID written by the holder.
+func lockHolders(f *os.File) []int {
+	if pids := procLockHolders(f); len(pids) > 0 {
+		return pids
+	}
+	b := make([]byte, 32)
+	n, _ := f.ReadAt(b, 0)
+	if pid, err := strconv.Atoi(string(bytes.TrimSpace(b[:n]))); err == nil {
+		return []int{pid}
+	}
+	return nil
+}
+
+// procLockHolders parses /proc/locks, which has lines such as
+//
+//	1: FLOCK  ADVISORY  WRITE 1234 08:01:5678 0 EOF
+//
+// for each lock held, where the sixth field identifies the file by device
+// major, minor and inode number.
+func procLockHolders(f *os.File) []int {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&0xfffff000
+	minor := dev&0xff | (dev>>12)&0xffffff00
+	id := fmt.Sprintf("%02x:%02x:%d", major, minor, st.Ino)
+
+	locks, err := os.Open("/proc/locks")
+	if err != nil {
+		return nil
+	}
+	defer locks.Close()
+	var pids []int
+	s := bufio.NewScanner(locks)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		// Skip processes that are waiting for a lock, marked by "->".
+		if len(fields) < 6 || fields[1] != "FLOCK" || fields[5] != id {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[4]); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
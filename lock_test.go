@@ -0,0 +1,80 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// Locks conflict between separately opened files even within one process, so
+// a single test process can stand in for several.
+
+func TestProcessLock(t *testing.T) {
+	o := newOptions()
+	LockDir(t.TempDir())(o)
+	ProcessLock(LockBoard, 0)(o)
+
+	l, err := o.lockBoard(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.lockBoard(0)
+	var le *LockError
+	if !errors.As(err, &le) {
+		t.Fatalf("second lock: got error %v, want a LockError", err)
+	}
+	if len(le.PIDs) != 1 || le.PIDs[0] != os.Getpid() {
+		t.Errorf("got holders %v, want [%d]", le.PIDs, os.Getpid())
+	}
+	if _, err := o.lockBoard(1); err != nil {
+		t.Errorf("lock of another board: %v", err)
+	}
+
+	// Address locks share the board, so they must wait for the board lock.
+	a := *o
+	ProcessLock(LockAddress, time.Second)(&a)
+	go func() {
+		time.Sleep(2 * lockPoll)
+		l.release()
+	}()
+	l1, err := a.lockBoard(0)
+	if err != nil {
+		t.Fatalf("waiting for lock: %v", err)
+	}
+	l2, err := a.lockBoard(0)
+	if err != nil {
+		t.Fatalf("second shared lock: %v", err)
+	}
+	if _, err := o.lockBoard(0); !errors.As(err, &le) {
+		t.Errorf("exclusive lock of shared board: got error %v, want a LockError", err)
+	}
+	l1.release()
+	l2.release()
+
+	ProcessLock(LockAddress, 0)(&a)
+	d1, err := a.lockAddress(0, 22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.lockAddress(0, 22); !errors.As(err, &le) {
+		t.Errorf("second lock of address: got error %v, want a LockError", err)
+	}
+	if d2, err := a.lockAddress(0, newAddress(22, 0x63)); err != nil {
+		t.Errorf("lock of another address: %v", err)
+	} else {
+		d2.release()
+	}
+	d1.release()
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// LogHandler enables logging of GPIB events as structured records. Data
+// transfers are logged at slog.LevelDebug, other events at slog.LevelInfo,
+// timeouts at slog.LevelWarn and failures at slog.LevelError.
+//
+// Records carry the attributes which apply to the event: board, address, ud
+// (the device descriptor), op (such as "write" or "spoll"), bytes, duration,
+// and for failed GPIB operations, ibsta (the status bits, such as
+// "ERR TIMO CMPL") and iberr (the error code, such as "EABO"). Log may be
+// used at the same time, and receives the same messages as text.
+func LogHandler(h slog.Handler) Option {
+	return func(o *options) {
+		o.handler = h
+	}
+}
+
+// logAt logs an event. The Logger receives the message prefixed with the
+// file and line of the caller calldepth frames up, and the Handler receives
+// it as a record with attrs.
+func (o *options) logAt(calldepth int, level slog.Level, attrs []slog.Attr, format string, v ...interface{}) {
+	if o.logger == nil && (o.handler == nil || !o.handler.Enabled(context.Background(), level)) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(calldepth+1, pcs[:])
+	msg := fmt.Sprintf(format, v...)
+	if o.logger != nil {
+		prefix := ""
+		if f, _ := runtime.CallersFrames(pcs[:]).Next(); f.File != "" {
+			prefix = filepath.Base(f.File) + ":" + strconv.Itoa(f.Line) + " "
+		}
+		o.logger.Printf("%s", prefix+msg)
+	}
+	if o.handler != nil && o.handler.Enabled(context.Background(), level) {
+		r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+		r.AddAttrs(attrs...)
+		o.handler.Handle(context.Background(), r)
+	}
+}
+
+// logf logs an event which is not about a particular board or device.
+func (o *options) logf(level slog.Level, attrs []slog.Attr, format string, v ...interface{}) {
+	o.logAt(2, level, attrs, format, v...)
+}
+
+// errLevel returns the level at which to log a failure.
+func errLevel(err error) slog.Level {
+	var t interface{ Timeout() bool }
+	if errors.As(err, &t) && t.Timeout() {
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+// errAttrs returns the attributes describing a failure. If it was a GPIB
+// error, they include the status of the last operation on the backend.
+func errAttrs(be Backend, err error) []slog.Attr {
+	attrs := []slog.Attr{slog.String("error", err.Error())}
+	var e *internal.Error
+	if errors.As(err, &e) || errors.Is(err, internal.TimeoutErr) {
+		ibsta := be.Ibsta()
+		attrs = append(attrs, slog.String("ibsta", internal.FormatIbsta(ibsta)))
+		if ibsta&internal.ERR != 0 {
+			attrs = append(attrs, slog.String("iberr", internal.FormatIberr(be.Iberr())))
+		}
+	}
+	return attrs
+}
+
+// transferAttrs returns the attributes describing a data transfer.
+func transferAttrs(n int64, took time.Duration) []slog.Attr {
+	return []slog.Attr{slog.Int64("bytes", n), slog.Duration("duration", took)}
+}
+
+// boardAttrs returns the attributes identifying an operation on a board.
+func boardAttrs(index int, op string) []slog.Attr {
+	return []slog.Attr{slog.Int("board", index), slog.String("op", op)}
+}
+
+// deviceAttrs returns the attributes identifying an operation on a device. The
+// ud is omitted if negative, before the device is open.
+func deviceAttrs(index int, addr Address, ud int, op string) []slog.Attr {
+	attrs := []slog.Attr{slog.Int("board", index), slog.String("address", addr.String())}
+	if ud >= 0 {
+		attrs = append(attrs, slog.Int("ud", ud))
+	}
+	return append(attrs, slog.String("op", op))
+}
+
+// logf logs an event on the board.
+func (b *Board) logf(level slog.Level, op string, attrs []slog.Attr, format string, v ...interface{}) {
+	b.options.logAt(2, level, append(boardAttrs(b.index, op), attrs...), format, v...)
+}
+
+// logErr logs a failed operation on the board.
+func (b *Board) logErr(op string, err error, format string, v ...interface{}) {
+	attrs := append(boardAttrs(b.index, op), errAttrs(b.be, err)...)
+	b.options.logAt(2, errLevel(err), attrs, format, v...)
+}
+
+// logf logs an event on the device.
+func (d *Device) logf(level slog.Level, op string, attrs []slog.Attr, format string, v ...interface{}) {
+	d.options.logAt(2, level, append(deviceAttrs(d.board.index, d.addr, d.ud, op), attrs...), format, v...)
+}
+
+// logErr logs a failed operation on the device.
+func (d *Device) logErr(op string, err error, format string, v ...interface{}) {
+	attrs := append(deviceAttrs(d.board.index, d.addr, d.ud, op), errAttrs(d.board.be, err)...)
+	d.options.logAt(2, errLevel(err), attrs, format, v...)
+}
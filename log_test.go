@@ -0,0 +1,71 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// bufLogger is a Logger which collects lines.
+type bufLogger struct{ lines []string }
+
+func (l *bufLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	lines := &bufLogger{}
+	o := newOptions()
+	LogHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))(o)
+	Log(lines)(o)
+	be := &fakeBackend{}
+	b := &Board{index: 1, be: be, options: o}
+	d := &Device{addr: 22, board: b, ud: 16, options: o}
+
+	d.logf(slog.LevelDebug, "write", transferAttrs(6, 3*time.Millisecond), "Wrote %q", "*IDN?\n")
+	be.res.Timeout(0)
+	d.logErr("read", internal.TimeoutErr, "Failed to read: %v", internal.TimeoutErr)
+	be.res.Fail(internal.ENOL)
+	b.logErr("command", &internal.Error{Iberr: internal.ENOL}, "Failed to send commands")
+	b.logErr("reset", errors.New("disk full"), "Failed to reset")
+
+	want := []string{
+		`level=DEBUG msg="Wrote \"*IDN?\\n\"" board=1 address=22 ud=16 op=write bytes=6 duration=3ms`,
+		`level=WARN msg="Failed to read: timed out" board=1 address=22 ud=16 op=read error="timed out" ibsta="ERR TIMO CMPL" iberr=EABO`,
+		`level=ERROR msg="Failed to send commands" board=1 op=command error=ENOL ibsta="ERR CMPL" iberr=ENOL`,
+		`level=ERROR msg="Failed to reset" board=1 op=reset error="disk full"`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got records:\n%s", buf.String())
+	}
+	for i := range want {
+		// Remove the time.
+		if _, rest, ok := strings.Cut(got[i], " "); !ok || rest != want[i] {
+			t.Errorf("record %d = %s\nwant %s", i, got[i], want[i])
+		}
+	}
+
+	// The Logger still gets the caller's position.
+	if len(lines.lines) != 4 || !strings.HasPrefix(lines.lines[0], "log_test.go:") {
+		t.Errorf("Logger got %q; want 4 lines from log_test.go", lines.lines)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package metrics collects statistics about GPIB operations and serves them
+// in the Prometheus text format.
+//
+// A Collector is registered with the linuxgpib.Observe option, and served
+// over HTTP:
+//
+//	c := metrics.New()
+//	b, err := linuxgpib.NewBoard(0, linuxgpib.Observe(c.Observe))
+//	http.Handle("/metrics", c)
+//
+// For each board, address and operation it exports:
+//
+//	gpib_operations_total            the number of operations
+//	gpib_operation_bytes_total       the number of bytes transferred
+//	gpib_operation_errors_total      the number of failures, by iberr code
+//	gpib_operation_duration_seconds  a histogram of the latency
+//
+// Timeouts are counted with the iberr code EABO, as linux-gpib reports them,
+// and errors which do not come from the bus with the code OTHER.
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the latency histogram.
+// GPIB operations range from well under a millisecond for a serial poll to
+// many seconds for a slow measurement.
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// key identifies a series.
+type key struct {
+	board   int
+	address linuxgpib.Address
+	op      string
+}
+
+// series holds the statistics of one board, address and operation.
+type series struct {
+	count  uint64
+	bytes  uint64
+	errors map[string]uint64
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+}
+
+// Collector accumulates statistics of operations. It is safe for concurrent
+// use.
+type Collector struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[key]*series
+}
+
+// New returns a Collector with DefaultBuckets.
+func New() *Collector {
+	return NewWithBuckets(DefaultBuckets)
+}
+
+// NewWithBuckets returns a Collector with the given histogram upper bounds in
+// seconds, which must be in increasing order.
+func NewWithBuckets(buckets []float64) *Collector {
+	return &Collector{
+		buckets: append([]float64(nil), buckets...),
+		series:  map[key]*series{},
+	}
+}
+
+// ErrorCode returns the label under which an error is counted.
+func ErrorCode(err error) string {
+	var e *internal.Error
+	var t interface{ Timeout() bool }
+	switch {
+	case errors.As(err, &e):
+		return internal.FormatIberr(e.Iberr)
+	case errors.As(err, &t) && t.Timeout():
+		return "EABO"
+	default:
+		return "OTHER"
+	}
+}
+
+// Observe records an operation. It is passed to the linuxgpib.Observe option.
+func (c *Collector) Observe(o linuxgpib.Observation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := key{o.Board, o.Address, o.Op}
+	s := c.series[k]
+	if s == nil {
+		s = &series{errors: map[string]uint64{}, counts: make([]uint64, len(c.buckets)+1)}
+		c.series[k] = s
+	}
+	s.count++
+	if o.Bytes > 0 {
+		s.bytes += uint64(o.Bytes)
+	}
+	if o.Err != nil {
+		s.errors[ErrorCode(o.Err)]++
+	}
+	secs := o.Duration.Seconds()
+	s.sum += secs
+	s.counts[sort.SearchFloat64s(c.buckets, secs)]++
+}
+
+// labels formats the labels of a series, with any extra ones appended.
+func (k key) labels(extra ...string) string {
+	s := fmt.Sprintf(`board="%d",address="%v",op="%s"`, k.board, k.address, k.op)
+	for i := 0; i+1 < len(extra); i += 2 {
+		s += fmt.Sprintf(",%s=%q", extra[i], extra[i+1])
+	}
+	return "{" + s + "}"
+}
+
+// formatFloat formats a number as Prometheus expects.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]key, 0, len(c.series))
+	for k := range c.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.board != b.board {
+			return a.board < b.board
+		}
+		if a.address != b.address {
+			return a.address < b.address
+		}
+		return a.op < b.op
+	})
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	fmt.Fprintln(cw, "# HELP gpib_operations_total Number of GPIB operations.")
+	fmt.Fprintln(cw, "# TYPE gpib_operations_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(cw, "gpib_operations_total%s %d\n", k.labels(), c.series[k].count)
+	}
+	fmt.Fprintln(cw, "# HELP gpib_operation_bytes_total Number of bytes transferred by GPIB operations.")
+	fmt.Fprintln(cw, "# TYPE gpib_operation_bytes_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(cw, "gpib_operation_bytes_total%s %d\n", k.labels(), c.series[k].bytes)
+	}
+	fmt.Fprintln(cw, "# HELP gpib_operation_errors_total Number of failed GPIB operations by iberr code.")
+	fmt.Fprintln(cw, "# TYPE gpib_operation_errors_total counter")
+	for _, k := range keys {
+		s := c.series[k]
+		codes := make([]string, 0, len(s.errors))
+		for code := range s.errors {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(cw, "gpib_operation_errors_total%s %d\n", k.labels("iberr", code), s.errors[code])
+		}
+	}
+	fmt.Fprintln(cw, "# HELP gpib_operation_duration_seconds Latency of GPIB operations.")
+	fmt.Fprintln(cw, "# TYPE gpib_operation_duration_seconds histogram")
+	for _, k := range keys {
+		s := c.series[k]
+		var cum uint64
+		for i, le := range c.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(cw, "gpib_operation_duration_seconds_bucket%s %d\n", k.labels("le", formatFloat(le)), cum)
+		}
+		fmt.Fprintf(cw, "gpib_operation_duration_seconds_bucket%s %d\n", k.labels("le", "+Inf"), s.count)
+		fmt.Fprintf(cw, "gpib_operation_duration_seconds_sum%s %s\n", k.labels(), formatFloat(s.sum))
+		fmt.Fprintf(cw, "gpib_operation_duration_seconds_count%s %d\n", k.labels(), s.count)
+	}
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// countWriter counts the bytes written, and remembers the first error.
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) Write(b []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+// ListenAndServe serves the metrics over HTTP at the path /metrics on the
+// given address, such as ":9488".
+func (c *Collector) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", c)
+	return http.ListenAndServe(addr, mux)
+}
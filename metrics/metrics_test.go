@@ -0,0 +1,72 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+func TestCollector(t *testing.T) {
+	be := sim.New()
+	be.Attach(22, sim.NewSCPI("ACME,DMM,0,1.0"))
+	c := NewWithBuckets([]float64{0.1, 1})
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be), linuxgpib.Observe(c.Observe))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	for _, addr := range []linuxgpib.Address{22, 7} {
+		d, err := b.NewDevice(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Query("*IDN?")
+		d.Spoll()
+		d.Read(make([]byte, 10))
+		d.Close()
+	}
+	c.Observe(linuxgpib.Observation{Board: 0, Address: 22, Op: "trigger", Duration: 2 * time.Second, Err: fmt.Errorf("wrapped: %w", io.EOF)})
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	got := rec.Body.String()
+	for _, want := range []string{
+		`# TYPE gpib_operations_total counter`,
+		`gpib_operations_total{board="0",address="7",op="query"} 1`,
+		`gpib_operation_bytes_total{board="0",address="22",op="query"} 21`,
+		`gpib_operation_errors_total{board="0",address="7",op="query",iberr="ENOL"} 1`,
+		`gpib_operation_errors_total{board="0",address="22",op="read",iberr="EABO"} 1`,
+		`gpib_operation_errors_total{board="0",address="22",op="trigger",iberr="OTHER"} 1`,
+		`gpib_operation_duration_seconds_bucket{board="0",address="22",op="spoll",le="0.1"} 1`,
+		`gpib_operation_duration_seconds_bucket{board="0",address="22",op="trigger",le="1"} 0`,
+		`gpib_operation_duration_seconds_bucket{board="0",address="22",op="trigger",le="+Inf"} 1`,
+		`gpib_operation_duration_seconds_sum{board="0",address="22",op="trigger"} 2`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+	if t.Failed() {
+		t.Log(got)
+	}
+	if strings.Index(got, `address="7",op="query"`) > strings.Index(got, `address="22",op="query"`) {
+		t.Error("series are not sorted by address")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"time"
+)
+
+// Observation describes a completed operation on a device, for the Observe
+// option.
+type Observation struct {
+	Board   int
+	Address Address
+	// Op is "read", "write", "query", "spoll", "trigger" or "clear". The
+	// streaming and file methods are reported as reads and writes.
+	Op string
+	// Bytes is the number of bytes transferred, including by a failed
+	// operation.
+	Bytes    int64
+	Duration time.Duration
+	Err      error
+}
+
+// Observe registers a callback which is informed of each read, write, query,
+// serial poll, trigger and clear, for collecting metrics such as with the
+// metrics package. It is called with the global GPIB lock held, so it must
+// not use the linuxgpib package. Intercept provides the same information for
+// every operation.
+func Observe(f func(Observation)) Option {
+	return func(o *options) {
+		o.observe = f
+	}
+}
+
+// observed lists the kinds of operation reported to Observe.
+var observed = map[string]bool{
+	"read":    true,
+	"write":   true,
+	"query":   true,
+	"spoll":   true,
+	"trigger": true,
+	"clear":   true,
+}
+
+// observer is an Interceptor which reports to an Observe callback.
+type observer func(Observation)
+
+func (f observer) Before(*Operation) error { return nil }
+
+func (f observer) After(op *Operation) {
+	if op.Device && observed[op.Kind] {
+		f(Observation{
+			Board:    op.Board,
+			Address:  op.Address,
+			Op:       op.Kind,
+			Bytes:    op.Bytes,
+			Duration: op.Duration,
+			Err:      op.Err,
+		})
+	}
+}
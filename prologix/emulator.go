@@ -0,0 +1,526 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package prologix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// EmulatorVersion is the reply of an Emulator to ++ver. Some programs check
+// that it starts with "Prologix GPIB".
+const EmulatorVersion = "Prologix GPIB-ETHERNET Controller version 01.06.06.00 (linuxgpib emulator)"
+
+// maxLine limits the length of a line from the host, so that a misbehaving
+// client cannot exhaust memory.
+const maxLine = 1 << 20
+
+// Emulator implements the command set of a Prologix adapter in controller
+// mode on top of a board, for programs which only know how to use one.
+//
+// Each connection has its own settings, as if it were a separate adapter, and
+// they start from the adapter's defaults. Devices are opened on first use and
+// shared by all connections, whose commands are handled one at a time.
+//
+// The read timeout set with ++read_tmo_ms applies to the whole read rather
+// than to each character. Each wait for the device is rounded up to a timeout
+// supported by linux-gpib, so a read may overrun it by a little. Device mode
+// (++mode 0) is not supported.
+type Emulator struct {
+	board   *linuxgpib.Board
+	logger  linuxgpib.Logger
+	mu      sync.Mutex // serializes commands from all connections
+	devices map[linuxgpib.Address]*linuxgpib.Device
+}
+
+// NewEmulator returns an emulator for the given board. The logger may be nil.
+func NewEmulator(board *linuxgpib.Board, logger linuxgpib.Logger) *Emulator {
+	return &Emulator{
+		board:   board,
+		logger:  logger,
+		devices: map[linuxgpib.Address]*linuxgpib.Device{},
+	}
+}
+
+func (e *Emulator) logf(format string, v ...interface{}) {
+	if e.logger != nil {
+		e.logger.Printf(format, v...)
+	}
+}
+
+// Close closes the devices opened by the emulator.
+func (e *Emulator) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var errs []error
+	for addr, d := range e.devices {
+		if err := d.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(e.devices, addr)
+	}
+	return errors.Join(errs...)
+}
+
+// Serve accepts connections on l and serves each in a new goroutine. It
+// returns when l is closed.
+func (e *Emulator) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go e.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a connection until the host closes it. The connection may
+// also be a pseudo-terminal, as returned by OpenPTY.
+func (e *Emulator) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	s := newSession(e, conn)
+	r := bufio.NewReader(conn)
+	for {
+		line, cmd, err := readLine(r)
+		if err != nil {
+			if err != io.EOF {
+				e.logf("Failed to receive from host: %v", err)
+			}
+			return
+		}
+		e.mu.Lock()
+		if cmd {
+			err = s.command(string(line))
+		} else {
+			err = s.data(line)
+		}
+		e.mu.Unlock()
+		if err != nil {
+			e.logf("Failed to send to host: %v", err)
+			return
+		}
+	}
+}
+
+// readLine reads a line from the host, removing escapes, and reports whether
+// it is a ++ command. Unescaped carriage returns and newlines end the line,
+// and unescaped plus signs are discarded from data.
+func readLine(r *bufio.Reader) (line []byte, cmd bool, err error) {
+	escaped, plus := false, 0
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, false, err
+		}
+		if len(line) > maxLine {
+			return nil, false, errors.New("line too long")
+		}
+		switch {
+		case escaped:
+			escaped = false
+			line = append(line, c)
+		case c == 0x1b:
+			escaped = true
+		case c == '\r' || c == '\n':
+			if len(line) > 0 || cmd {
+				return line, cmd, nil
+			}
+			plus = 0
+		case c == '+' && len(line) == 0 && !cmd:
+			plus++
+			cmd = plus == 2
+		case c == '+' && !cmd:
+		default:
+			line = append(line, c)
+		}
+	}
+}
+
+// session holds the settings of one connection.
+type session struct {
+	e       *Emulator
+	w       io.Writer
+	addr    linuxgpib.Address
+	auto    bool
+	eoi     bool
+	eos     int
+	eot     bool
+	eotChar byte
+	readTmo time.Duration
+}
+
+func newSession(e *Emulator, w io.Writer) *session {
+	s := &session{e: e, w: w}
+	s.reset()
+	return s
+}
+
+// reset restores the adapter's defaults.
+func (s *session) reset() {
+	s.addr = 0
+	s.auto = false
+	s.eoi = true
+	s.eos = 0
+	s.eot = false
+	s.eotChar = 0
+	s.readTmo = 500 * time.Millisecond
+}
+
+// reply sends a line to the host.
+func (s *session) reply(format string, v ...interface{}) error {
+	_, err := fmt.Fprintf(s.w, format+"\r\n", v...)
+	return err
+}
+
+// device returns the device at an address, opening it if needed.
+func (s *session) device(addr linuxgpib.Address) (*linuxgpib.Device, error) {
+	if d := s.e.devices[addr]; d != nil {
+		return d, nil
+	}
+	d, err := s.e.board.NewDevice(addr)
+	if err != nil {
+		return nil, err
+	}
+	s.e.devices[addr] = d
+	return d, nil
+}
+
+// data sends a line of data to the addressed device, followed by the
+// configured terminator.
+func (s *session) data(line []byte) error {
+	switch s.eos {
+	case 0:
+		line = append(line, '\r', '\n')
+	case 1:
+		line = append(line, '\r')
+	case 2:
+		line = append(line, '\n')
+	}
+	d, err := s.device(s.addr)
+	if err != nil {
+		s.e.logf("Failed to open device %v: %v", s.addr, err)
+		return nil
+	}
+	if _, err := d.WriteEnd(line, s.eoi); err != nil {
+		s.e.logf("Failed to write to device %v: %v", s.addr, err)
+		return nil
+	}
+	if s.auto {
+		return s.read("eoi")
+	}
+	return nil
+}
+
+// isTimeout reports whether err is a timeout.
+func isTimeout(err error) bool {
+	t, ok := err.(interface{ Timeout() bool })
+	return ok && t.Timeout()
+}
+
+// read reads from the addressed device until EOI, until the given character
+// in decimal, or until timeout if until is empty, and sends the data to the
+// host.
+func (s *session) read(until string) error {
+	stop := -1
+	if until != "" && until != "eoi" {
+		c, err := strconv.Atoi(until)
+		if err != nil || c < 0 || c > 255 {
+			s.e.logf("Invalid read terminator %q", until)
+			return nil
+		}
+		stop = c
+	}
+	d, err := s.device(s.addr)
+	if err != nil {
+		s.e.logf("Failed to open device %v: %v", s.addr, err)
+		return nil
+	}
+	if err := d.SetTimeout(s.readTmo); err != nil {
+		s.e.logf("Failed to set timeout of device %v: %v", s.addr, err)
+		return nil
+	}
+
+	// Reading byte by byte is the only way to stop at a character without
+	// changing the device's end of string mode.
+	buf := make([]byte, 4096)
+	if stop >= 0 {
+		buf = buf[:1]
+	}
+	// Each call waits for up to the time left, so that the timeout applies to
+	// the whole read. The device's timeout is only changed when the time left
+	// rounds to a shorter timeout constant, and is restored afterwards.
+	deadline := time.Now().Add(s.readTmo)
+	tmo := internal.Timeout(s.readTmo)
+	defer func() {
+		if tmo != internal.Timeout(s.readTmo) {
+			if err := d.SetTimeout(s.readTmo); err != nil {
+				s.e.logf("Failed to set timeout of device %v: %v", s.addr, err)
+			}
+		}
+	}()
+	var out []byte
+	var end bool
+	for {
+		left := time.Until(deadline)
+		if left <= 0 {
+			if until != "" {
+				s.e.logf("Failed to read from device %v: timed out after %v", s.addr, s.readTmo)
+			}
+			break
+		}
+		if t := internal.Timeout(left); t != tmo {
+			if err := d.SetTimeout(left); err != nil {
+				s.e.logf("Failed to set timeout of device %v: %v", s.addr, err)
+				break
+			}
+			tmo = t
+		}
+		n, e, err := d.ReadEnd(buf)
+		out = append(out, buf[:n]...)
+		end = e
+		if err != nil {
+			if !isTimeout(err) || until != "" {
+				s.e.logf("Failed to read from device %v: %v", s.addr, err)
+			}
+			break
+		}
+		if until != "" && (end || stop >= 0 && n > 0 && buf[0] == byte(stop)) {
+			break
+		}
+	}
+	if end && s.eot {
+		out = append(out, s.eotChar)
+	}
+	_, err = s.w.Write(out)
+	return err
+}
+
+// setting handles a command which reports a setting if it has no argument,
+// and otherwise changes it to a value from 0 to max.
+func (s *session) setting(args []string, v *int, max int) error {
+	if len(args) == 0 {
+		return s.reply("%d", *v)
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 || n > max {
+		s.e.logf("Invalid setting %q", args[0])
+		return nil
+	}
+	*v = n
+	return nil
+}
+
+// boolSetting is like setting, for a value which is 0 or 1.
+func (s *session) boolSetting(args []string, v *bool) error {
+	n := 0
+	if *v {
+		n = 1
+	}
+	err := s.setting(args, &n, 1)
+	*v = n != 0
+	return err
+}
+
+// localLockout addresses the current device as a listener and sends LLO.
+func (s *session) localLockout() error {
+	lad, err := linuxgpib.Listen(s.addr.Primary())
+	if err != nil {
+		return err
+	}
+	cmds := []linuxgpib.Command{linuxgpib.UNL, lad}
+	if sad := s.addr.Secondary(); sad != 0 {
+		c, err := linuxgpib.Secondary(sad)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, c)
+	}
+	cmds = append(cmds, linuxgpib.LLO, linuxgpib.UNL)
+	return s.e.board.Command(cmds...)
+}
+
+// parseAddrs parses a list of primary addresses, each optionally followed by
+// a secondary address from 96 to 126.
+func parseAddrs(args []string) ([]linuxgpib.Address, error) {
+	var addrs []linuxgpib.Address
+	for _, a := range args {
+		n, err := strconv.Atoi(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q", a)
+		}
+		if n >= 96 && len(addrs) > 0 && addrs[len(addrs)-1].Secondary() == 0 {
+			last := addrs[len(addrs)-1]
+			addrs[len(addrs)-1], err = linuxgpib.NewAddress(last.Primary(), n)
+		} else {
+			var addr linuxgpib.Address
+			addr, err = linuxgpib.NewAddress(n, 0)
+			addrs = append(addrs, addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return addrs, nil
+}
+
+// command performs a ++ command.
+func (s *session) command(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return s.reply("Unrecognized command")
+	}
+	name, args := strings.ToLower(fields[0]), fields[1:]
+	s.e.logf("Received command ++%s", line)
+
+	// addressed performs an operation on the addressed device.
+	addressed := func(op func(*linuxgpib.Device) error) error {
+		d, err := s.device(s.addr)
+		if err == nil {
+			err = op(d)
+		}
+		if err != nil {
+			s.e.logf("Failed ++%s on device %v: %v", name, s.addr, err)
+		}
+		return nil
+	}
+
+	switch name {
+	case "addr":
+		if len(args) == 0 {
+			if sad := s.addr.Secondary(); sad != 0 {
+				return s.reply("%d %d", s.addr.Primary(), sad)
+			}
+			return s.reply("%d", s.addr.Primary())
+		}
+		addrs, err := parseAddrs(args)
+		if err != nil || len(addrs) != 1 {
+			s.e.logf("Invalid address %q", strings.Join(args, " "))
+			return nil
+		}
+		s.addr = addrs[0]
+	case "auto":
+		return s.boolSetting(args, &s.auto)
+	case "clr":
+		return addressed((*linuxgpib.Device).Clear)
+	case "eoi":
+		return s.boolSetting(args, &s.eoi)
+	case "eos":
+		return s.setting(args, &s.eos, 3)
+	case "eot_enable":
+		return s.boolSetting(args, &s.eot)
+	case "eot_char":
+		c := int(s.eotChar)
+		err := s.setting(args, &c, 255)
+		s.eotChar = byte(c)
+		return err
+	case "ifc":
+		if err := s.e.board.InterfaceClear(); err != nil {
+			s.e.logf("Failed ++ifc: %v", err)
+		}
+	case "llo":
+		if err := s.localLockout(); err != nil {
+			s.e.logf("Failed ++llo: %v", err)
+		}
+	case "loc":
+		return addressed((*linuxgpib.Device).Local)
+	case "mode":
+		mode := 1
+		if err := s.setting(args, &mode, 1); err != nil || mode == 1 {
+			return err
+		}
+		s.e.logf("Device mode is not supported")
+	case "read":
+		until := ""
+		if len(args) > 0 {
+			until = strings.ToLower(args[0])
+		}
+		return s.read(until)
+	case "read_tmo_ms":
+		ms := int(s.readTmo / time.Millisecond)
+		err := s.setting(args, &ms, 3000)
+		s.readTmo = time.Duration(max(ms, 1)) * time.Millisecond
+		return err
+	case "rst":
+		s.reset()
+	case "savecfg":
+		save := 0
+		return s.setting(args, &save, 1)
+	case "spoll":
+		addr := s.addr
+		if len(args) > 0 {
+			addrs, err := parseAddrs(args)
+			if err != nil || len(addrs) != 1 {
+				s.e.logf("Invalid address %q", strings.Join(args, " "))
+				return nil
+			}
+			addr = addrs[0]
+		}
+		d, err := s.device(addr)
+		if err != nil {
+			s.e.logf("Failed to open device %v: %v", addr, err)
+			return nil
+		}
+		stb, err := d.Spoll()
+		if err != nil {
+			s.e.logf("Failed ++spoll on device %v: %v", addr, err)
+			return nil
+		}
+		return s.reply("%d", stb)
+	case "srq":
+		l, err := s.e.board.Lines()
+		if err != nil {
+			s.e.logf("Failed ++srq: %v", err)
+		}
+		if l.SRQ.Asserted {
+			return s.reply("1")
+		}
+		return s.reply("0")
+	case "trg":
+		addrs := []linuxgpib.Address{s.addr}
+		if len(args) > 0 {
+			var err error
+			if addrs, err = parseAddrs(args); err != nil || len(addrs) > 15 {
+				s.e.logf("Invalid addresses %q", strings.Join(args, " "))
+				return nil
+			}
+		}
+		for _, addr := range addrs {
+			d, err := s.device(addr)
+			if err == nil {
+				err = d.Trigger()
+			}
+			if err != nil {
+				s.e.logf("Failed ++trg on device %v: %v", addr, err)
+			}
+		}
+	case "ver":
+		return s.reply("%s", EmulatorVersion)
+	case "help":
+		return s.reply("%s", "Supported commands: addr auto clr eoi eos eot_enable eot_char help ifc llo loc mode read read_tmo_ms rst savecfg spoll srq trg ver")
+	default:
+		return s.reply("Unrecognized command")
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package prologix
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+func TestReadLine(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		line string
+		cmd  bool
+	}{
+		{"*IDN?\n", "*IDN?", false},
+		{"\r\n\r\nMEAS?\r\n", "MEAS?", false},
+		{"++addr 22\r\n", "addr 22", true},
+		{"a+b\x1b+c\n", "ab+c", false},
+		{"\x1b+\x1b+addr\n", "++addr", false},
+		{"\x1b\n\x1b\r\n", "\n\r", false},
+		{"+x\n", "x", false},
+	} {
+		line, cmd, err := readLine(bufio.NewReader(strings.NewReader(tc.in)))
+		if err != nil {
+			t.Errorf("readLine(%q) failed: %v", tc.in, err)
+			continue
+		}
+		if string(line) != tc.line || cmd != tc.cmd {
+			t.Errorf("readLine(%q) = %q, %v; want %q, %v", tc.in, line, cmd, tc.line, tc.cmd)
+		}
+	}
+}
+
+// startEmulator serves an emulator for a simulated board on a loopback port,
+// and returns its address.
+func startEmulator(t *testing.T, sb *sim.Backend) string {
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(sb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	e := NewEmulator(b, nil)
+	t.Cleanup(func() { e.Close() })
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go e.Serve(l)
+	return l.Addr().String()
+}
+
+func TestEmulator(t *testing.T) {
+	sb := sim.New()
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	sb.Attach(22, dmm)
+
+	// The emulator and the backend are not used through the same library, so
+	// that a call to the backend does not wait for the emulator's call.
+	be, err := Dial(startEmulator(t, sb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer be.Close()
+	if v := be.Ibvers(); v != EmulatorVersion {
+		t.Errorf("Ibvers = %q", v)
+	}
+
+	ud := be.Ibdev(0, 22, 0, internal.T1s, 1, 0)
+	if ud < 0 {
+		t.Fatalf("Ibdev failed: %v", backendErr(be))
+	}
+	msg := []byte("*IDN?\n")
+	if ibsta := be.Ibwrt(ud, msg); ibsta&internal.ERR != 0 {
+		t.Fatalf("Ibwrt failed: %v", backendErr(be))
+	}
+	buf := make([]byte, 100)
+	ibsta := be.Ibrd(ud, buf)
+	if ibsta&internal.ERR != 0 {
+		t.Fatalf("Ibrd failed: %v", backendErr(be))
+	}
+	if got := string(buf[:be.Ibcnt()]); got != "ACME,DMM,0,1.0\n" || ibsta&internal.END == 0 {
+		t.Errorf("Ibrd = %q, END %v", got, ibsta&internal.END != 0)
+	}
+
+	if ibsta := be.Ibtrg(ud); ibsta&internal.ERR != 0 {
+		t.Fatalf("Ibtrg failed: %v", backendErr(be))
+	}
+	dmm.RequestService(0x42)
+	if ibsta := be.Ibwait(ud, internal.RQS); ibsta&internal.RQS == 0 {
+		t.Errorf("Ibwait = %s, want RQS", internal.FormatIbsta(ibsta))
+	}
+	if ibsta, stb := be.Ibrsp(ud); ibsta&internal.ERR != 0 || stb != 0x42 {
+		t.Errorf("Ibrsp = %#x, %v", stb, backendErr(be))
+	}
+	if n := dmm.Triggers(); n != 1 {
+		t.Errorf("triggers = %d, want 1", n)
+	}
+}
+
+// backendErr returns the error of the last call to be.
+func backendErr(be *Backend) error {
+	return internal.ErrFrom(be.Ibsta(), be.Iberr, be.Ibcnt)
+}
+
+func TestEmulatorSettings(t *testing.T) {
+	sb := sim.New()
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	sb.Attach(5, dmm)
+	conn, err := net.Dial("tcp", startEmulator(t, sb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	exchange := func(send, want string) {
+		t.Helper()
+		if _, err := io.WriteString(conn, send); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("sent %q, got %q, want %q", send, got, want)
+		}
+	}
+
+	exchange("++addr 5\n++addr\n", "5\r\n")
+	exchange("++bogus\n", "Unrecognized command\r\n")
+
+	// Without EOI, a message is continued by the next line.
+	exchange("++eoi 0\nCONF:VOLT\n++eoi 1\nAUTO\n++ver\n", EmulatorVersion+"\r\n")
+	if got := dmm.Received(); len(got) != 1 || got[0] != "CONF:VOLT\r\nAUTO" {
+		t.Errorf("received %q", got)
+	}
+
+	// In auto mode, the response follows each message, with the EOT character
+	// appended when the device asserts EOI.
+	exchange("++eos 2\n++eot_enable 1\n++eot_char 33\n++auto 1\n*IDN?\n", "ACME,DMM,0,1.0\n")
+	if c, err := r.ReadByte(); err != nil || c != '!' {
+		t.Errorf("read %q, %v after response, want '!'", c, err)
+	}
+	exchange("++auto 0\n*OPC?\n++read 10\n", "1\n")
+}
+
+func TestPTY(t *testing.T) {
+	pty, path, err := OpenPTY()
+	if err != nil {
+		t.Skipf("pseudo-terminals are unavailable: %v", err)
+	}
+	sb := sim.New()
+	sb.Attach(22, sim.NewSCPI("ACME,DMM,0,1.0"))
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(sb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	e := NewEmulator(b, nil)
+	defer e.Close()
+	go e.ServeConn(pty)
+	defer pty.Close()
+
+	be, err := OpenSerial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer be.Close()
+	if v := be.Ibvers(); v != EmulatorVersion {
+		t.Errorf("Ibvers = %q", v)
+	}
+}
+
+// tickBackend has a device which sends one 'x' every tick, never asserting
+// EOI, and records the timeouts set on it. Other methods are not implemented.
+type tickBackend struct {
+	linuxgpib.Backend
+	res  internal.Result
+	tick time.Duration
+	tmos []int
+}
+
+func (b *tickBackend) Ibvers() string         { return "tick" }
+func (b *tickBackend) Ibsre(board, v int) int { return b.res.Done(0, 0) }
+func (b *tickBackend) Ibonl(ud, v int) int    { return b.res.Done(0, 0) }
+func (b *tickBackend) Ibsta() int             { return b.res.Sta }
+func (b *tickBackend) Iberr() int             { return b.res.Err }
+func (b *tickBackend) Ibcnt() int             { return b.res.Cnt }
+
+func (b *tickBackend) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	b.res.Done(0, 0)
+	return internal.GPIB_MAX_NUM_BOARDS
+}
+
+func (b *tickBackend) Ibtmo(ud, tmo int) int {
+	b.tmos = append(b.tmos, tmo)
+	return b.res.Done(0, 0)
+}
+
+func (b *tickBackend) Ibrd(ud int, buf []byte) int {
+	time.Sleep(b.tick)
+	buf[0] = 'x'
+	return b.res.Done(0, 1)
+}
+
+func TestEmulatorReadTimeout(t *testing.T) {
+	be := &tickBackend{tick: 10 * time.Millisecond}
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	e := NewEmulator(b, nil)
+	defer e.Close()
+
+	// The terminator never arrives, so the read ends when its timeout runs out
+	// even though every character arrives in time.
+	var out strings.Builder
+	s := newSession(e, &out)
+	s.readTmo = 200 * time.Millisecond
+	started := time.Now()
+	if err := s.read("10"); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(started); took > time.Second {
+		t.Errorf("++read 10 took %v; want about %v", took, s.readTmo)
+	}
+	if out.Len() == 0 || strings.Trim(out.String(), "x") != "" {
+		t.Errorf("++read 10 sent %q", out.String())
+	}
+
+	// The device's timeout was lowered as the read ran out of time, and then
+	// restored.
+	if n := len(be.tmos); n < 3 || be.tmos[0] != internal.T300ms || be.tmos[n-1] != internal.T300ms || be.tmos[1] == internal.T300ms {
+		t.Errorf("timeouts set = %v", be.tmos)
+	}
+}
@@ -0,0 +1,765 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package prologix controls GPIB devices through a Prologix GPIB-USB or
+// GPIB-ETHERNET adapter, instead of a linux-gpib board.
+//
+// A Backend is passed to linuxgpib.UseBackend, after which Board and Device
+// work as they do with linux-gpib, with board number 0 standing for the
+// adapter:
+//
+//	be, err := prologix.Dial("192.168.1.50")
+//	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+//
+// The adapter cannot send arbitrary bus commands, monitor bus lines other than
+// SRQ, or find listeners, so Board's Command, DeviceClearAll, Reset, Enumerate
+// and Device's Remote return an ECAP error. Remote enable is always asserted
+// by the adapter. Reads are limited by the adapter to an inter-character
+// timeout of at most 3 seconds, but are retried until the device's timeout if
+// no data arrives. If the adapter stops responding altogether, every later
+// operation fails with EDVR and errno EIO, and a new Backend is needed.
+//
+// In the other direction, an Emulator implements the adapter's command set on
+// top of a Board, so that programs written for an adapter can use a linux-gpib
+// board instead.
+package prologix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+const (
+	// DefaultPort is the TCP port of the GPIB-ETHERNET adapter.
+	DefaultPort = 1234
+
+	// eotChar is appended by the adapter to data which ended with EOI.
+	eotChar = 0x04
+	// maxReadTimeout is the longest read timeout supported by the adapter.
+	maxReadTimeout = 3 * time.Second
+	// slack is added to the adapter's timeout when waiting for its output.
+	slack = 2 * time.Second
+	// chunkSize is the size of each transfer in Ibrdf.
+	chunkSize = 64 * 1024
+)
+
+// Conn is a connection to an adapter, such as a net.Conn or the *os.File of a
+// serial port.
+type Conn interface {
+	io.ReadWriteCloser
+	SetReadDeadline(time.Time) error
+}
+
+// Backend is a linuxgpib.Backend for a Prologix adapter. It is safe for
+// concurrent use.
+type Backend struct {
+	mu       sync.Mutex
+	res      internal.Result
+	conn     Conn
+	r        *bufio.Reader
+	sentinel []byte // the adapter's version line, which marks the end of output
+	broken   error  // set if the adapter's output can no longer be followed
+	devices  map[int]*device
+	next     int
+
+	// Adapter settings, to avoid repeating commands.
+	addr    linuxgpib.Address
+	eoi     int
+	readTmo time.Duration
+}
+
+// device is an open device descriptor.
+type device struct {
+	addr      linuxgpib.Address
+	tmo       int
+	eot       int
+	eos       int
+	unread    []byte // data received but not yet returned by Ibrd
+	unreadEnd bool   // whether unread ended with EOI or EOS
+	stb       int    // status byte obtained by Ibwait, or -1
+}
+
+// Dial connects to a GPIB-ETHERNET adapter at the given host, with an optional
+// port which defaults to 1234.
+func Dial(addr string) (*Backend, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(DefaultPort))
+	}
+	c, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	b, err := New(c)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// New initializes an adapter on an existing connection, and returns a backend
+// for it. The adapter is placed in controller mode, and told not to save its
+// settings.
+func New(c Conn) (*Backend, error) {
+	b := &Backend{
+		conn:    c,
+		r:       bufio.NewReader(c),
+		devices: map[int]*device{},
+		next:    internal.GPIB_MAX_NUM_BOARDS,
+		addr:    -1,
+		eoi:     -1,
+	}
+	init := "++savecfg 0\n++mode 1\n++auto 0\n++eos 3\n++eot_enable 1\n" +
+		fmt.Sprintf("++eot_char %d\n", eotChar)
+	if _, err := io.WriteString(c, init); err != nil {
+		return nil, err
+	}
+
+	// Discard any output left from a previous session before learning the
+	// version line.
+	c.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	io.Copy(io.Discard, b.r)
+	if _, err := io.WriteString(c, "++ver\n"); err != nil {
+		return nil, err
+	}
+	c.SetReadDeadline(time.Now().Add(slack))
+	line, err := b.r.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("prologix: no response to ++ver: %v", err)
+	}
+	b.sentinel = line
+	return b, nil
+}
+
+// Close closes the connection to the adapter.
+func (b *Backend) Close() error {
+	return b.conn.Close()
+}
+
+// escape prepares data to be sent, by escaping the characters which the
+// adapter would otherwise interpret, and adds the terminating newline.
+func escape(data []byte) []byte {
+	out := make([]byte, 0, len(data)+8)
+	for _, c := range data {
+		switch c {
+		case '\r', '\n', 0x1b, '+':
+			out = append(out, 0x1b)
+		}
+		out = append(out, c)
+	}
+	return append(out, '\n')
+}
+
+// send writes commands to the adapter.
+func (b *Backend) send(cmds string) error {
+	if b.broken != nil {
+		return b.broken
+	}
+	_, err := io.WriteString(b.conn, cmds)
+	return err
+}
+
+// output sends commands followed by ++ver, and returns their output up to the
+// version line, waiting for up to timeout for each part of it to arrive.
+//
+// If the output does not arrive in time, it is read and discarded so that it
+// is not taken for the output of later commands. If it still does not arrive,
+// the backend gives up on the adapter, and later operations fail.
+func (b *Backend) output(cmds string, timeout time.Duration) ([]byte, error) {
+	if err := b.send(cmds + "++ver\n"); err != nil {
+		return nil, err
+	}
+	out, err := b.readOutput(nil, timeout)
+	if err != nil {
+		if _, derr := b.readOutput(out, timeout); derr != nil {
+			b.broken = fmt.Errorf("prologix: lost track of the adapter's output: %v", derr)
+		}
+		return nil, err
+	}
+	return out[:len(out)-len(b.sentinel)], nil
+}
+
+// readOutput appends output to out until it ends with the version line. The
+// deadline is extended whenever more output arrives.
+func (b *Backend) readOutput(out []byte, timeout time.Duration) ([]byte, error) {
+	for !bytes.HasSuffix(out, b.sentinel) {
+		if b.r.Buffered() == 0 {
+			b.conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+		c, err := b.r.ReadByte()
+		if err != nil {
+			return out, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// fail records an error communicating with the adapter, and returns ibsta.
+func (b *Backend) fail(err error) int {
+	var errno syscall.Errno
+	switch {
+	case errors.As(err, &errno):
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		errno = syscall.ETIMEDOUT
+	default:
+		errno = syscall.EIO
+	}
+	return b.res.FailErrno(int(errno))
+}
+
+// address selects the device to be addressed by subsequent commands.
+func (b *Backend) address(addr linuxgpib.Address) error {
+	if addr == b.addr {
+		return nil
+	}
+	if err := b.send("++addr " + addrArgs(addr) + "\n"); err != nil {
+		return err
+	}
+	b.addr = addr
+	return nil
+}
+
+// addrArgs returns the arguments for a command which takes an address.
+func addrArgs(addr linuxgpib.Address) string {
+	s := strconv.Itoa(addr.Primary())
+	if sad := addr.Secondary(); sad != 0 {
+		s += " " + strconv.Itoa(sad)
+	}
+	return s
+}
+
+// device returns an open device, or nil if the descriptor is invalid.
+func (b *Backend) device(ud int) *device {
+	d := b.devices[ud]
+	if d == nil {
+		b.res.Fail(internal.EARG)
+	}
+	return d
+}
+
+// board checks that a board descriptor is valid.
+func (b *Backend) board(board int) bool {
+	if board != 0 {
+		b.res.Fail(internal.ENEB)
+		return false
+	}
+	return true
+}
+
+func (b *Backend) Ibvers() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(string(b.sentinel))
+}
+
+func (b *Backend) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return -1
+	}
+	addr, err := linuxgpib.NewAddress(pad, sad)
+	if err != nil {
+		b.res.Fail(internal.EARG)
+		return -1
+	}
+	ud := b.next
+	b.next++
+	b.devices[ud] = &device{addr: addr, tmo: tmo, eot: eot, eos: eos, stb: -1}
+	b.res.Done(0, 0)
+	return ud
+}
+
+func (b *Backend) Ibfind(name string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.res.FailErrno(int(syscall.ENOENT))
+	return -1
+}
+
+func (b *Backend) Ibonl(ud, v int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ud < internal.GPIB_MAX_NUM_BOARDS {
+		if !b.board(ud) {
+			return b.res.Sta
+		}
+		return b.res.Done(0, 0)
+	}
+	if b.device(ud) == nil {
+		return b.res.Sta
+	}
+	if v == 0 {
+		delete(b.devices, ud)
+	}
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibask(ud, option int) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta, 0
+	}
+	var v int
+	switch option {
+	case internal.IbaPAD:
+		v = d.addr.Primary()
+	case internal.IbaSAD:
+		v = d.addr.Secondary()
+	case internal.IbaTMO:
+		v = d.tmo
+	case internal.IbaEOT:
+		v = d.eot
+	case internal.IbaEOSrd:
+		v = d.eos & internal.REOS
+	case internal.IbaEOSchar:
+		v = d.eos & 0xff
+	case internal.IbaBNA:
+		v = 0
+	default:
+		return b.res.Fail(internal.ECAP), 0
+	}
+	return b.res.Done(0, 0), v
+}
+
+func (b *Backend) Ibconfig(ud, option, value int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	switch option {
+	case internal.IbcTMO:
+		d.tmo = value
+	case internal.IbcEOT:
+		d.eot = value
+	case internal.IbcBNA:
+		if !b.board(value) {
+			return b.res.Sta
+		}
+	default:
+		return b.res.Fail(internal.ECAP)
+	}
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibbna(ud int, name string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.FailErrno(int(syscall.ENOENT))
+}
+
+func (b *Backend) Ibtmo(ud, v int) int {
+	return b.Ibconfig(ud, internal.IbcTMO, v)
+}
+
+func (b *Backend) Ibeot(ud, v int) int {
+	return b.Ibconfig(ud, internal.IbcEOT, v)
+}
+
+func (b *Backend) Ibeos(ud, v int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	d.eos = v
+	return b.res.Done(0, 0)
+}
+
+// timeout returns the duration of a device's timeout, which is unlimited for
+// TNONE.
+func timeout(tmo int) time.Duration {
+	if tmo == internal.TNONE {
+		return 1<<63 - 1
+	}
+	return internal.Duration(tmo)
+}
+
+// readMessage reads from a device until EOI or the EOS character, or until
+// it stops sending.
+func (b *Backend) readMessage(d *device) (data []byte, end bool, err error) {
+	if err := b.address(d.addr); err != nil {
+		return nil, false, err
+	}
+	total := timeout(d.tmo)
+	rt := min(max(total, time.Millisecond), maxReadTimeout)
+	if rt != b.readTmo {
+		if err := b.send(fmt.Sprintf("++read_tmo_ms %d\n", rt.Milliseconds())); err != nil {
+			return nil, false, err
+		}
+		b.readTmo = rt
+	}
+	cmd := "++read eoi\n"
+	reos := d.eos&internal.REOS != 0
+	if reos {
+		cmd = fmt.Sprintf("++read %d\n", byte(d.eos))
+	}
+
+	started := time.Now()
+	for {
+		out, err := b.output(cmd, rt+slack)
+		if err != nil {
+			return nil, false, err
+		}
+		// The adapter appends eotChar whenever the data ended with EOI, even
+		// if it also ended with the EOS character.
+		switch {
+		case len(out) > 0 && out[len(out)-1] == eotChar:
+			return out[:len(out)-1], true, nil
+		case reos && len(out) > 0 && out[len(out)-1] == byte(d.eos):
+			return out, true, nil
+		case len(out) > 0 || time.Since(started) >= total:
+			return out, false, nil
+		}
+	}
+}
+
+func (b *Backend) Ibrd(ud int, buf []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	if d.unread == nil {
+		data, end, err := b.readMessage(d)
+		if err != nil {
+			return b.fail(err)
+		}
+		if len(data) == 0 {
+			return b.res.Timeout(0)
+		}
+		d.unread, d.unreadEnd = data, end
+	}
+	n := copy(buf, d.unread)
+	d.unread = d.unread[n:]
+	if len(d.unread) > 0 {
+		return b.res.Done(0, n)
+	}
+	d.unread = nil
+	if !d.unreadEnd {
+		return b.res.Done(0, n)
+	}
+	return b.res.Done(internal.END, n)
+}
+
+func (b *Backend) Ibwrt(ud int, buf []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	if err := b.write(d.addr, buf, d.eot); err != nil {
+		return b.fail(err)
+	}
+	return b.res.Done(0, len(buf))
+}
+
+// write sends data to a device, asserting EOI with the last byte if eoi is
+// nonzero.
+func (b *Backend) write(addr linuxgpib.Address, data []byte, eoi int) error {
+	if err := b.address(addr); err != nil {
+		return err
+	}
+	if eoi != 0 {
+		eoi = 1
+	}
+	if eoi != b.eoi {
+		if err := b.send(fmt.Sprintf("++eoi %d\n", eoi)); err != nil {
+			return err
+		}
+		b.eoi = eoi
+	}
+	return b.send(string(escape(data)))
+}
+
+func (b *Backend) Ibrdf(ud int, path string) int {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return b.fileErr(err)
+	}
+	defer f.Close()
+	buf := make([]byte, chunkSize)
+	total := 0
+	for {
+		ibsta := b.Ibrd(ud, buf)
+		n := b.Ibcnt()
+		if ibsta&internal.ERR != 0 {
+			return ibsta
+		}
+		if _, err := f.Write(buf[:n]); err != nil {
+			return b.fileErr(err)
+		}
+		total += n
+		if ibsta&internal.END != 0 {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			return b.res.Done(internal.END, total)
+		}
+	}
+}
+
+func (b *Backend) Ibwrtf(ud int, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b.fileErr(err)
+	}
+	return b.Ibwrt(ud, data)
+}
+
+// fileErr records a file system error, and returns ibsta.
+func (b *Backend) fileErr(err error) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	errno := syscall.EIO
+	errors.As(err, &errno)
+	b.res.Sta, b.res.Err, b.res.Cnt = internal.ERR|internal.CMPL, internal.EFSO, int(errno)
+	return b.res.Sta
+}
+
+func (b *Backend) Ibclr(ud int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	d.unread, d.stb = nil, -1
+	if err := b.address(d.addr); err != nil {
+		return b.fail(err)
+	}
+	if err := b.send("++clr\n"); err != nil {
+		return b.fail(err)
+	}
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibtrg(ud int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	if err := b.send("++trg " + addrArgs(d.addr) + "\n"); err != nil {
+		return b.fail(err)
+	}
+	return b.res.Done(0, 0)
+}
+
+// spoll serial polls a device, returning -1 if it did not respond.
+func (b *Backend) spoll(d *device) (int, error) {
+	out, err := b.output("++spoll "+addrArgs(d.addr)+"\n", maxReadTimeout+slack)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(out))
+	if s == "" {
+		return -1, nil
+	}
+	stb, err := strconv.Atoi(s)
+	if err != nil || stb < 0 || stb > 255 {
+		return 0, fmt.Errorf("prologix: bad serial poll response %q", s)
+	}
+	return stb, nil
+}
+
+func (b *Backend) Ibrsp(ud int) (int, byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta, 0
+	}
+	stb := d.stb
+	d.stb = -1
+	if stb < 0 {
+		var err error
+		if stb, err = b.spoll(d); err != nil {
+			return b.fail(err), 0
+		}
+		if stb < 0 {
+			return b.res.Timeout(0), 0
+		}
+	}
+	return b.res.Done(0, 0), byte(stb)
+}
+
+func (b *Backend) Ibloc(ud int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	if err := b.address(d.addr); err != nil {
+		return b.fail(err)
+	}
+	if err := b.send("++loc\n"); err != nil {
+		return b.fail(err)
+	}
+	return b.res.Done(0, 0)
+}
+
+// srq reports whether SRQ is asserted.
+func (b *Backend) srq() (bool, error) {
+	out, err := b.output("++srq\n", slack)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) == "1", nil
+}
+
+// Ibwait returns the current status without waiting. RQS is detected by
+// serial polling the device while SRQ is asserted, and the status byte is
+// kept for the next Ibrsp. If the mask includes a condition which is not met,
+// the status includes TIMO.
+func (b *Backend) Ibwait(ud, mask int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	if d.stb < 0 {
+		srq, err := b.srq()
+		if err != nil {
+			return b.fail(err)
+		}
+		if srq {
+			stb, err := b.spoll(d)
+			if err != nil {
+				return b.fail(err)
+			}
+			if stb&0x40 != 0 {
+				d.stb = stb
+			}
+		}
+	}
+	ibsta := 0
+	if d.stb >= 0 {
+		ibsta |= internal.RQS
+	}
+	if mask&^internal.TIMO != 0 && ibsta&mask == 0 {
+		ibsta |= internal.TIMO
+	}
+	return b.res.Done(ibsta, 0)
+}
+
+func (b *Backend) Ibcmd(board int, cmd []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.Fail(internal.ECAP)
+}
+
+func (b *Backend) Ibsic(board int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta
+	}
+	if err := b.send("++ifc\n"); err != nil {
+		return b.fail(err)
+	}
+	return b.res.Done(0, 0)
+}
+
+// Ibsre succeeds without effect, because the adapter always asserts REN.
+func (b *Backend) Ibsre(board, v int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta
+	}
+	return b.res.Done(0, 0)
+}
+
+// Iblines reports only the SRQ line, which is the only one the adapter can
+// monitor.
+func (b *Backend) Iblines(board int) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta, 0
+	}
+	srq, err := b.srq()
+	if err != nil {
+		return b.fail(err), 0
+	}
+	lines := internal.ValidSRQ
+	if srq {
+		lines |= internal.BusSRQ
+	}
+	return b.res.Done(0, 0), lines
+}
+
+func (b *Backend) Ibln(board, pad, sad int) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.Fail(internal.ECAP), 0
+}
+
+func (b *Backend) SendList(board int, addrs []linuxgpib.Address, buf []byte, eotmode int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta
+	}
+	if eotmode == internal.NLend {
+		buf = append(buf[:len(buf):len(buf)], '\n')
+	}
+	for _, a := range addrs {
+		if err := b.write(a, buf, eotmode); err != nil {
+			return b.fail(err)
+		}
+	}
+	return b.res.Done(0, len(buf))
+}
+
+func (b *Backend) Ibsta() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.Sta
+}
+
+func (b *Backend) Iberr() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.Err
+}
+
+func (b *Backend) Ibcnt() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.Cnt
+}
+
+var _ linuxgpib.Backend = (*Backend)(nil)
@@ -0,0 +1,371 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package prologix
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+// fakeAdapter imitates an adapter in controller mode, with instruments
+// attached at primary addresses.
+type fakeAdapter struct {
+	instruments map[int]sim.Instrument
+	commands    chan string // every ++ command received
+}
+
+// serve handles one connection until it is closed.
+func (f *fakeAdapter) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	addr, eoi, eot := 0, 1, false
+	var pending []byte
+	for {
+		// Read one line, removing escapes.
+		var line []byte
+		escaped, raw := false, true
+		for {
+			c, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			if !escaped && c == 0x1b {
+				escaped, raw = true, false
+				continue
+			}
+			if !escaped && c == '\n' {
+				break
+			}
+			if !escaped && c == '\r' {
+				continue
+			}
+			escaped = false
+			line = append(line, c)
+		}
+		in := f.instruments[addr]
+		if !raw || !strings.HasPrefix(string(line), "++") {
+			pending = append(pending, line...)
+			if eoi != 0 && in != nil {
+				in.Receive(pending)
+				pending = nil
+			}
+			continue
+		}
+		select {
+		case f.commands <- string(line):
+		default:
+		}
+		fields := strings.Fields(string(line[2:]))
+		arg := func(i int) int {
+			if i >= len(fields) {
+				return 0
+			}
+			v, _ := strconv.Atoi(fields[i])
+			return v
+		}
+		switch fields[0] {
+		case "ver":
+			fmt.Fprint(conn, "Fake GPIB-ETHERNET version 1.0\r\n")
+		case "addr":
+			addr = arg(1)
+		case "eoi":
+			eoi = arg(1)
+		case "eot_enable":
+			eot = arg(1) != 0
+		case "read":
+			if in == nil {
+				continue
+			}
+			// Messages from instruments always end with EOI, after which
+			// the adapter sends the EOT character, whatever the read ends
+			// at.
+			out := in.Send()
+			if len(out) > 0 && eot {
+				out = append(out, eotChar)
+			}
+			conn.Write(out)
+		case "spoll":
+			if in := f.instruments[arg(1)]; in != nil {
+				fmt.Fprintf(conn, "%d\r\n", in.Poll())
+			}
+		case "srq":
+			srq := 0
+			for _, in := range f.instruments {
+				if in.Requesting() {
+					srq = 1
+				}
+			}
+			fmt.Fprintf(conn, "%d\r\n", srq)
+		case "trg":
+			if in := f.instruments[arg(1)]; in != nil {
+				in.Trigger()
+			}
+		case "clr":
+			if in != nil {
+				in.Clear()
+			}
+		}
+	}
+}
+
+// startAdapter serves a fake adapter on a loopback port and returns a backend
+// connected to it.
+func startAdapter(t *testing.T, f *fakeAdapter) *Backend {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	be, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { be.Close() })
+	return be
+}
+
+// eventually waits for a condition to become true, because commands which
+// produce no output are not acknowledged by the adapter.
+func eventually(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+// drain returns the commands received so far.
+func (f *fakeAdapter) drain() []string {
+	var cmds []string
+	for {
+		select {
+		case c := <-f.commands:
+			cmds = append(cmds, c)
+		default:
+			return cmds
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	got := string(escape([]byte("a+b\r\n\x1b")))
+	want := "a\x1b+b\x1b\r\x1b\n\x1b\x1b\n"
+	if got != want {
+		t.Errorf("escape = %q, want %q", got, want)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	dmm.Respond("MEAS:VOLT?", "+1.2345E+00")
+	dmm2 := sim.NewSCPI("ACME,DMM,0,2.0")
+	f := &fakeAdapter{
+		instruments: map[int]sim.Instrument{22: dmm, 23: dmm2},
+		commands:    make(chan string, 1000),
+	}
+	be := startAdapter(t, f)
+	if v := be.Ibvers(); v != "Fake GPIB-ETHERNET version 1.0" {
+		t.Errorf("Ibvers = %q", v)
+	}
+	init := f.drain()
+	for _, want := range []string{"++mode 1", "++auto 0", "++eot_enable 1"} {
+		found := false
+		for _, c := range init {
+			found = found || c == want
+		}
+		if !found {
+			t.Errorf("initialization %q did not include %q", init, want)
+		}
+	}
+
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be), linuxgpib.Timeout(300*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	got, err := d.Query("*IDN?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "ACME,DMM,0,1.0" {
+		t.Errorf("Query(*IDN?) = %q", got)
+	}
+
+	// Data with characters special to the adapter arrives intact, and a
+	// response is returned in pieces when the buffer is small.
+	if _, err := d.Write([]byte("CONF:VOLT +1;MEAS:VOLT?\n")); err != nil {
+		t.Fatal(err)
+	}
+	var resp []byte
+	for {
+		buf := make([]byte, 4)
+		n, end, err := d.ReadEnd(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp = append(resp, buf[:n]...)
+		if end {
+			break
+		}
+	}
+	if string(resp) != "+1.2345E+00\n" {
+		t.Errorf("read %q", resp)
+	}
+	if r := dmm.Received(); r[len(r)-1] != "CONF:VOLT +1;MEAS:VOLT?" {
+		t.Errorf("received %q", r[len(r)-1])
+	}
+
+	// A read which ends at the EOS character still ends with EOI, which the
+	// adapter marks with the EOT character.
+	d2, err := d.Board().NewDevice(23, linuxgpib.ReadEOS("\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := d2.Query("*IDN?"); err != nil || got != "ACME,DMM,0,2.0" {
+		t.Errorf("Query(*IDN?) with ReadEOS = %q, %v", got, err)
+	}
+	d2.Close()
+
+	// A query with no response times out.
+	_, err = d.Query("BOGUS?")
+	if te, ok := err.(interface{ Timeout() bool }); !ok || !te.Timeout() {
+		t.Errorf("Query(BOGUS?) = %v, want a timeout", err)
+	}
+
+	if err := d.Trigger(); err != nil {
+		t.Fatal(err)
+	}
+	if !eventually(t, func() bool { return dmm.Triggers() == 1 }) {
+		t.Errorf("triggers = %d, want 1", dmm.Triggers())
+	}
+	if err := d.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if !eventually(t, func() bool { return dmm.Clears() == 1 }) {
+		t.Errorf("clears = %d, want 1", dmm.Clears())
+	}
+	f.drain()
+	if err := d.Local(); err != nil {
+		t.Fatal(err)
+	}
+	if !eventually(t, func() bool {
+		for _, c := range f.drain() {
+			if c == "++loc" {
+				return true
+			}
+		}
+		return false
+	}) {
+		t.Error("Local did not send ++loc")
+	}
+
+	dmm.RequestService(0x41)
+	stb, err := d.WaitSRQ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stb != 0x41 {
+		t.Errorf("WaitSRQ = %#x, want 0x41", stb)
+	}
+	stb, err = d.Spoll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stb != 0x01 {
+		t.Errorf("Spoll = %#x, want 0x01", stb)
+	}
+	if err := d.Remote(); err == nil {
+		t.Error("Remote succeeded, want ECAP")
+	}
+}
+
+// pipeAdapter returns a backend connected to an adapter which answers each
+// ++ver with reply, called with the number of earlier ++ver commands.
+func pipeAdapter(t *testing.T, reply func(n int, conn net.Conn)) *Backend {
+	c, conn := net.Pipe()
+	t.Cleanup(func() { c.Close(); conn.Close() })
+	go func() {
+		r := bufio.NewReader(conn)
+		for n := 0; ; {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if line == "++ver\n" {
+				reply(n, conn)
+				n++
+			}
+		}
+	}()
+	return &Backend{conn: c, r: bufio.NewReader(c), sentinel: []byte("ver\r\n")}
+}
+
+func TestOutput(t *testing.T) {
+	const tmo = 100 * time.Millisecond
+
+	// Output which keeps arriving may take longer than the timeout.
+	be := pipeAdapter(t, func(n int, conn net.Conn) {
+		for _, c := range "slow\r\n" {
+			time.Sleep(tmo / 3)
+			fmt.Fprintf(conn, "%c", c)
+		}
+		fmt.Fprint(conn, "ver\r\n")
+	})
+	if out, err := be.output("++read eoi\n", tmo); err != nil || string(out) != "slow\r\n" {
+		t.Errorf("output with slow data = %q, %v; want %q", out, err, "slow\r\n")
+	}
+
+	// Output which arrives late is not taken for that of the next commands.
+	be = pipeAdapter(t, func(n int, conn net.Conn) {
+		if n == 0 {
+			time.Sleep(tmo * 3 / 2)
+		}
+		fmt.Fprintf(conn, "%d\r\nver\r\n", n)
+	})
+	if _, err := be.output("++spoll\n", tmo); err == nil {
+		t.Error("output with late data succeeded")
+	}
+	if out, err := be.output("++spoll\n", tmo); err != nil || string(out) != "1\r\n" {
+		t.Errorf("output after late data = %q, %v; want %q", out, err, "1\r\n")
+	}
+
+	// An adapter which stops responding is given up on.
+	be = pipeAdapter(t, func(n int, conn net.Conn) {})
+	if _, err := be.output("++spoll\n", tmo); err == nil {
+		t.Error("output with no data succeeded")
+	}
+	started := time.Now()
+	if _, err := be.output("++spoll\n", tmo); err == nil || time.Since(started) >= tmo {
+		t.Errorf("output after the adapter stopped responding = %v after %v; want an immediate error", err, time.Since(started))
+	}
+}
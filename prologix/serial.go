@@ -0,0 +1,130 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package prologix
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// OpenSerial opens a GPIB-USB adapter at the given serial device, such as
+// /dev/ttyUSB0. The port is placed in raw mode; its baud rate is ignored by
+// the adapter.
+func OpenSerial(path string) (*Backend, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := makeRaw(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("prologix: %s: %v", path, err)
+	}
+	b, err := New(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// makeRaw disables all input and output processing on a terminal, like
+// cfmakeraw.
+func makeRaw(f *os.File) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		var t syscall.Termios
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+			serr = errno
+			return
+		}
+		t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+			syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+		t.Oflag &^= syscall.OPOST
+		t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+		t.Cflag &^= syscall.CSIZE | syscall.PARENB
+		t.Cflag |= syscall.CS8 | syscall.CREAD | syscall.CLOCAL
+		t.Cc[syscall.VMIN] = 1
+		t.Cc[syscall.VTIME] = 0
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+			serr = errno
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
+
+// pty is the master side of a pseudo-terminal, which keeps the slave side open
+// so that reads do not fail while no program has it open.
+type pty struct {
+	*os.File
+	slave *os.File
+}
+
+func (p *pty) Close() error {
+	p.slave.Close()
+	return p.File.Close()
+}
+
+// OpenPTY creates a pseudo-terminal in raw mode, for serving an Emulator to
+// programs which expect a GPIB-USB adapter. It returns the master side, and
+// the path of the slave side for those programs to open.
+func OpenPTY() (io.ReadWriteCloser, string, error) {
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	var n uint32
+	unlock := int32(0)
+	rc, err := m.SyscallConn()
+	if err != nil {
+		m.Close()
+		return nil, "", err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+			serr = errno
+			return
+		}
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+			serr = errno
+		}
+	})
+	if err == nil {
+		err = serr
+	}
+	if err != nil {
+		m.Close()
+		return nil, "", fmt.Errorf("prologix: creating pty: %v", err)
+	}
+	path := fmt.Sprintf("/dev/pts/%d", n)
+	s, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		m.Close()
+		return nil, "", err
+	}
+	if err := makeRaw(s); err != nil {
+		s.Close()
+		m.Close()
+		return nil, "", fmt.Errorf("prologix: %s: %v", path, err)
+	}
+	return &pty{File: m, slave: s}, path, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+const (
+	// queryChunk is the size of each read in a query response.
+	queryChunk = 4096
+	// srqPollInterval is how often WaitSRQ checks for a service request.
+	srqPollInterval = 20 * time.Millisecond
+)
+
+// Query sends cmd followed by a newline to the GPIB device and returns its
+// response, without the trailing newline. The write and read are performed
+// without any other operation in between.
+func (d *Device) Query(cmd string) (_ string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.isClosed {
+		return "", errors.New("already closed")
+	}
+
+	op, err := d.begin("query", []byte(cmd+"\n"))
+	if err != nil {
+		return "", err
+	}
+	be := d.board.be
+	started := time.Now()
+	var n int64
+	var resp []byte
+	defer func() { op.end(n, resp, err) }()
+	if err := d.err(be.Ibwrt(d.ud, []byte(cmd+"\n"))); err != nil {
+		d.logErr("query", err, "Failed to write query to address %v device %d: %v", d.addr, d.ud, err)
+		return "", err
+	}
+	n = int64(len(cmd) + 1)
+	buf := make([]byte, queryChunk)
+	for {
+		ibsta := be.Ibrd(d.ud, buf)
+		if err := d.err(ibsta); err != nil {
+			d.logErr("query", err, "Failed to read query response from address %v device %d: %v", d.addr, d.ud, err)
+			return "", err
+		}
+		resp = append(resp, buf[:be.Ibcnt()]...)
+		n += int64(be.Ibcnt())
+		if ibsta&internal.END != 0 {
+			break
+		}
+	}
+
+	took := time.Since(started)
+	d.logf(slog.LevelDebug, "query", transferAttrs(int64(len(resp)), took), "Queried %q with response %s in %v from address %v", cmd, formatLog(resp), took.Truncate(time.Millisecond), d.addr)
+	return strings.TrimRight(string(resp), "\r\n"), nil
+}
+
+// WaitSRQ waits for the device to request service, and returns its status byte
+// obtained by serial poll. It returns a timeout error if no request is made
+// within the device's timeout.
+//
+// Service requests are only detected if autopolling is enabled for the board,
+// which is the linux-gpib default. Other operations may be performed while
+// waiting.
+func (d *Device) WaitSRQ() (byte, error) {
+	return d.WaitSRQContext(context.Background())
+}
+
+// WaitSRQContext is like WaitSRQ, but gives up and returns the context's error
+// if it is done before the device requests service. The device is not polled
+// once that has happened, so its request is left for the next caller.
+func (d *Device) WaitSRQContext(ctx context.Context) (byte, error) {
+	mu.Lock()
+	if d.isClosed {
+		mu.Unlock()
+		return 0, errors.New("already closed")
+	}
+	timeout := d.options.timeout
+	op, err := d.begin("waitsrq", nil)
+	mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	err = d.waitSRQ(ctx, timeout)
+	mu.Lock()
+	op.end(0, nil, err)
+	mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return d.Spoll()
+}
+
+// waitSRQ waits for the device to request service, without polling it.
+func (d *Device) waitSRQ(ctx context.Context, timeout time.Duration) error {
+	started := time.Now()
+	for {
+		mu.Lock()
+		if d.isClosed {
+			mu.Unlock()
+			return errors.New("already closed")
+		}
+		// A mask of zero returns the current status immediately.
+		ibsta := d.board.be.Ibwait(d.ud, 0)
+		err := d.err(ibsta)
+		if err != nil {
+			d.logErr("waitsrq", err, "Failed to wait for service request from address %v device %d: %v", d.addr, d.ud, err)
+		}
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if ibsta&internal.RQS != 0 {
+			return nil
+		}
+		if timeout != 0 && time.Since(started) > timeout {
+			d.logf(slog.LevelWarn, "waitsrq", []slog.Attr{slog.String("error", internal.TimeoutErr.Error())}, "Timed out waiting for service request from address %v", d.addr)
+			return internal.TimeoutErr
+		}
+		t := time.NewTimer(srqPollInterval)
+		select {
+		case <-ctx.Done():
+		case <-t.C:
+		}
+		t.Stop()
+	}
+}
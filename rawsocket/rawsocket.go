@@ -0,0 +1,195 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package rawsocket serves GPIB devices as LXI-style raw socket instruments,
+// which accept SCPI commands over TCP, conventionally on port 5025.
+//
+// Each newline-terminated message received on a data connection is written to
+// the device. If the message contains a query, indicated by a question mark,
+// the device's response is read and sent back unchanged, followed by a newline
+// if it does not already end with one. Several connections may share a device,
+// and their messages are handled one at a time.
+//
+// A control connection accepts the following newline-terminated commands:
+//
+//	DCL     clears the device, replying "DCL" when done; it does not wait
+//	        behind messages from data connections, but an operation already
+//	        on the bus, such as reading a query's response, must complete or
+//	        time out first
+//	SPOLL   serial polls the device, replying with the status byte in decimal
+//
+// Errors are reported on the control connection with a line starting with
+// "ERROR". On the data connection they are only logged, so the client sees a
+// query time out as it would with a network instrument.
+package rawsocket
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/msiegen/linuxgpib"
+)
+
+// DefaultPort is the conventional port of raw socket instruments.
+const DefaultPort = 5025
+
+// maxLine limits the length of a message, so that a misbehaving client cannot
+// exhaust memory.
+const maxLine = 1 << 20
+
+// Instrument serves a single GPIB device.
+//
+// A message is taken to be a query, whose response is read from the device,
+// if it contains a question mark anywhere, as SCPI queries do. A message
+// whose only question mark is in a string or block argument is therefore
+// also treated as a query, and its absent response times out. The response
+// is read until the device asserts EOI, so binary blocks are passed through
+// intact.
+type Instrument struct {
+	d      *linuxgpib.Device
+	logger linuxgpib.Logger
+	mu     sync.Mutex // serializes messages from all connections
+}
+
+// NewInstrument returns an instrument for the given device. The logger may be
+// nil.
+func NewInstrument(d *linuxgpib.Device, logger linuxgpib.Logger) *Instrument {
+	return &Instrument{d: d, logger: logger}
+}
+
+func (in *Instrument) logf(format string, v ...interface{}) {
+	if in.logger != nil {
+		in.logger.Printf(format, v...)
+	}
+}
+
+// Serve accepts data connections on l and serves each in a new goroutine. It
+// returns when l is closed.
+func (in *Instrument) Serve(l net.Listener) error {
+	return serve(l, in.ServeConn)
+}
+
+// ServeControl accepts control connections on l and serves each in a new
+// goroutine. It returns when l is closed.
+func (in *Instrument) ServeControl(l net.Listener) error {
+	return serve(l, in.ServeControlConn)
+}
+
+func serve(l net.Listener, f func(io.ReadWriteCloser)) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go f(conn)
+	}
+}
+
+// ServeConn serves a data connection until the client closes it.
+func (in *Instrument) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	s := bufio.NewScanner(conn)
+	s.Buffer(nil, maxLine)
+	for s.Scan() {
+		msg := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(msg) == "" {
+			continue
+		}
+		resp, err := in.handle(msg)
+		if err != nil {
+			in.logf("Failed to handle %q: %v", msg, err)
+			continue
+		}
+		if resp == nil {
+			continue
+		}
+		if _, err := conn.Write(resp); err != nil {
+			in.logf("Failed to send response: %v", err)
+			return
+		}
+	}
+	if err := s.Err(); err != nil {
+		in.logf("Failed to receive message: %v", err)
+	}
+}
+
+// handle sends a message to the device, and returns the response if it is a
+// query.
+func (in *Instrument) handle(msg string) ([]byte, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if _, err := in.d.Write([]byte(msg + "\n")); err != nil {
+		return nil, err
+	}
+	if !strings.Contains(msg, "?") {
+		return nil, nil
+	}
+	var resp bytes.Buffer
+	if _, err := in.d.ReadTo(&resp); err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(resp.Bytes(), []byte("\n")) {
+		resp.WriteByte('\n')
+	}
+	return resp.Bytes(), nil
+}
+
+// ServeControlConn serves a control connection until the client closes it.
+func (in *Instrument) ServeControlConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	s := bufio.NewScanner(conn)
+	for s.Scan() {
+		cmd := strings.ToUpper(strings.TrimSpace(s.Text()))
+		if cmd == "" {
+			continue
+		}
+		reply := in.control(cmd)
+		if _, err := io.WriteString(conn, reply+"\n"); err != nil {
+			in.logf("Failed to send control reply: %v", err)
+			return
+		}
+	}
+}
+
+// control performs a control command and returns the reply. A device clear
+// does not take in.mu, so it is not queued behind messages from data
+// connections, which is how a client recovers when one of them is stuck. It
+// still waits for the global GPIB lock, which an operation on the bus holds
+// until it completes or times out.
+func (in *Instrument) control(cmd string) string {
+	switch cmd {
+	case "DCL":
+		if err := in.d.Clear(); err != nil {
+			return fmt.Sprintf("ERROR %v", err)
+		}
+		return "DCL"
+	case "SPOLL":
+		in.mu.Lock()
+		defer in.mu.Unlock()
+		stb, err := in.d.Spoll()
+		if err != nil {
+			return fmt.Sprintf("ERROR %v", err)
+		}
+		return strconv.Itoa(int(stb))
+	default:
+		return fmt.Sprintf("ERROR unknown command %q", cmd)
+	}
+}
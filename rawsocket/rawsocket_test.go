@@ -0,0 +1,221 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package rawsocket
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+// listen starts serving on a loopback port and returns its address.
+func listen(t *testing.T, serve func(net.Listener) error) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go serve(l)
+	return l.Addr().String()
+}
+
+// exchange sends a line and reads a line in reply.
+func exchange(t *testing.T, conn net.Conn, r *bufio.Reader, msg string) string {
+	t.Helper()
+	if _, err := fmt.Fprintln(conn, msg); err != nil {
+		t.Fatal(err)
+	}
+	s, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestInstrument(t *testing.T) {
+	be := sim.New()
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	dmm.Respond("MEAS:VOLT?", "+1.2345E+00")
+	be.Attach(22, dmm)
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	in := NewInstrument(d, nil)
+	data := listen(t, in.Serve)
+	control := listen(t, in.ServeControl)
+
+	// Concurrent clients each get their own responses.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", data)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for j := 0; j < 10; j++ {
+				if _, err := fmt.Fprintln(conn, "CONF:VOLT"); err != nil {
+					t.Error(err)
+					return
+				}
+				fmt.Fprintln(conn, "*IDN?")
+				if s, err := r.ReadString('\n'); err != nil || s != "ACME,DMM,0,1.0\n" {
+					t.Errorf("*IDN? = %q, %v", s, err)
+					return
+				}
+				fmt.Fprintln(conn, "MEAS:VOLT?")
+				if s, err := r.ReadString('\n'); err != nil || s != "+1.2345E+00\n" {
+					t.Errorf("MEAS:VOLT? = %q, %v", s, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := len(dmm.Received()), 4*10*3; got != want {
+		t.Errorf("instrument received %d messages; want %d", got, want)
+	}
+
+	conn, err := net.Dial("tcp", control)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	dmm.RequestService(0x50)
+	if got := exchange(t, conn, r, "SPOLL"); got != "80\n" {
+		t.Errorf("SPOLL = %q; want 80", got)
+	}
+	if got := exchange(t, conn, r, "DCL"); got != "DCL\n" {
+		t.Errorf("DCL = %q", got)
+	}
+	if dmm.Clears() != 1 {
+		t.Errorf("instrument was cleared %d times; want 1", dmm.Clears())
+	}
+	if got := exchange(t, conn, r, "BOGUS"); got[:5] != "ERROR" {
+		t.Errorf("BOGUS = %q; want an error", got)
+	}
+}
+
+func TestBinaryBlock(t *testing.T) {
+	// The block's data ends with bytes which look like a terminator.
+	block := "#14ab\r\n"
+	be := sim.New()
+	scope := sim.NewSCPI("ACME,SCOPE,0,1.0")
+	scope.Respond("CURV?", block)
+	be.Attach(22, scope)
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	data := listen(t, NewInstrument(d, nil).Serve)
+
+	conn, err := net.Dial("tcp", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintln(conn, "CURV?")
+	got := make([]byte, len(block)+1)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if want := block + "\n"; string(got) != want {
+		t.Errorf("CURV? = %q; want %q", got, want)
+	}
+}
+
+// stalled is an instrument whose reads wait until release is closed. Each
+// read first sends on waiting.
+type stalled struct {
+	*sim.SCPI
+	waiting chan struct{}
+	release chan struct{}
+}
+
+func (s *stalled) Send() []byte {
+	s.waiting <- struct{}{}
+	<-s.release
+	return s.SCPI.Send()
+}
+
+func TestClearWhileBusy(t *testing.T) {
+	be := sim.New()
+	dmm := &stalled{sim.NewSCPI("ACME,DMM,0,1.0"), make(chan struct{}, 1), make(chan struct{})}
+	be.Attach(22, dmm)
+	var once sync.Once
+	release := func() { once.Do(func() { close(dmm.release) }) }
+	defer release()
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	in := NewInstrument(d, nil)
+	data := listen(t, in.Serve)
+	control := listen(t, in.ServeControl)
+
+	// A query is stuck reading its response from the device.
+	dconn, err := net.Dial("tcp", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dconn.Close()
+	dconn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintln(dconn, "*IDN?")
+	<-dmm.waiting
+
+	// A device clear waits for the read on the bus, but not for the data
+	// connection, which still holds the instrument when the read completes.
+	conn, err := net.Dial("tcp", control)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reply := make(chan string, 1)
+	go func() {
+		fmt.Fprintln(conn, "DCL")
+		s, _ := bufio.NewReader(conn).ReadString('\n')
+		reply <- s
+	}()
+	select {
+	case got := <-reply:
+		t.Fatalf("DCL = %q while a read was on the bus", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	if got := <-reply; got != "DCL\n" {
+		t.Errorf("DCL = %q", got)
+	}
+	if dmm.Clears() != 1 {
+		t.Errorf("instrument was cleared %d times; want 1", dmm.Clears())
+	}
+	if got, err := bufio.NewReader(dconn).ReadString('\n'); got != "ACME,DMM,0,1.0\n" {
+		t.Errorf("*IDN? = %q, %v", got, err)
+	}
+}
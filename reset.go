@@ -0,0 +1,156 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// InterfaceClear pulses the IFC line, which causes all devices to stop talking
+// or listening and returns control of the bus to the board. It is the usual way
+// to recover when a device has wedged the handshake.
+func (b *Board) InterfaceClear() (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if b.isClosed() {
+		return errors.New("already closed")
+	}
+
+	op, err := b.begin("ifc", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { op.end(0, nil, err) }()
+
+	b.logf(slog.LevelInfo, "ifc", nil, "Sending interface clear on board %d", b.index)
+	return b.interfaceClear()
+}
+
+func (b *Board) interfaceClear() error {
+	if err := b.err(b.be.Ibsic(b.index)); err != nil {
+		b.logErr("ifc", err, "Board %d returned ibsic error: %v", b.index, err)
+		return err
+	}
+	return nil
+}
+
+// SetRemoteEnable asserts or unasserts the REN line. Devices that are addressed
+// while REN is asserted enter the remote state and ignore their front panels,
+// and all devices return to local when it is unasserted.
+//
+// REN is asserted automatically when the first device on the board is opened,
+// and unasserted when the last one is closed.
+func (b *Board) SetRemoteEnable(enable bool) (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if b.isClosed() {
+		return errors.New("already closed")
+	}
+
+	op, err := b.begin("ren", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { op.end(0, nil, err) }()
+
+	b.logf(slog.LevelInfo, "ren", []slog.Attr{slog.Bool("enable", enable)}, "Setting remote enable to %v on board %d", enable, b.index)
+	return b.setRemoteEnable(enable)
+}
+
+func (b *Board) setRemoteEnable(enable bool) error {
+	v := 0
+	if enable {
+		v = 1
+	}
+	if err := b.err(b.be.Ibsre(b.index, v)); err != nil {
+		b.logErr("ren", err, "Board %d returned ibsre error: %v", b.index, err)
+		return err
+	}
+	return nil
+}
+
+// DeviceClearAll sends the universal device clear (DCL) command, which resets
+// the message exchange of every device on the bus.
+func (b *Board) DeviceClearAll() (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if b.isClosed() {
+		return errors.New("already closed")
+	}
+
+	op, err := b.begin("dcl", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { op.end(0, nil, err) }()
+
+	b.logf(slog.LevelInfo, "dcl", nil, "Clearing all devices on board %d", b.index)
+	return b.deviceClearAll()
+}
+
+func (b *Board) deviceClearAll() error {
+	if err := b.err(b.be.Ibcmd(b.index, []byte{byte(DCL)})); err != nil {
+		b.logErr("dcl", err, "Failed to send DCL on board %d: %v", b.index, err)
+		return err
+	}
+	return nil
+}
+
+// Reset brings the bus to a known state in the manner of the IEEE 488.2
+// ResetSys procedure: it sends an interface clear, asserts REN, clears all
+// devices, and then sends "*RST" to each of the given addresses. Addresses may
+// be omitted if the devices do not understand IEEE 488.2 common commands.
+func (b *Board) Reset(addrs ...Address) (err error) {
+	for _, addr := range addrs {
+		if err := addr.Validate(); err != nil {
+			return err
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if b.isClosed() {
+		return errors.New("already closed")
+	}
+
+	op, err := b.begin("reset", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { op.end(0, nil, err) }()
+
+	started := time.Now()
+	b.logf(slog.LevelInfo, "reset", nil, "Resetting board %d", b.index)
+	if err := b.interfaceClear(); err != nil {
+		return err
+	}
+	if err := b.setRemoteEnable(true); err != nil {
+		return err
+	}
+	if err := b.deviceClearAll(); err != nil {
+		return err
+	}
+	if len(addrs) > 0 {
+		if err := b.err(b.be.SendList(b.index, addrs, []byte("*RST"), internal.NLend)); err != nil {
+			b.logErr("reset", err, "Failed to send *RST to addresses %v on board %d: %v", addrs, b.index, err)
+			return err
+		}
+	}
+
+	took := time.Since(started)
+	b.logf(slog.LevelInfo, "reset", []slog.Attr{slog.Int("devices", len(addrs)), slog.Duration("duration", took)}, "Reset %d devices in %v on board %d", len(addrs), took.Truncate(time.Millisecond), b.index)
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/fault"
+	"github.com/msiegen/linuxgpib/internal"
+	"github.com/msiegen/linuxgpib/sim"
+	"github.com/msiegen/linuxgpib/transcript"
+)
+
+// busRecorder opens board 0 of a simulated bus with instruments at addresses 5
+// and 22, which fails according to the rules, and records what is sent.
+type busRecorder struct {
+	b        *linuxgpib.Board
+	sim      *sim.Backend
+	dmm, psu *sim.SCPI
+	buf      bytes.Buffer
+}
+
+func newBusRecorder(t *testing.T, rules ...fault.Rule) *busRecorder {
+	t.Helper()
+	r := &busRecorder{
+		sim: sim.New(),
+		dmm: sim.NewSCPI("ACME,DMM,0,1.0"),
+		psu: sim.NewSCPI("ACME,PSU,0,1.0"),
+	}
+	r.sim.Attach(22, r.dmm)
+	r.sim.Attach(5, r.psu)
+	fb, err := fault.New(r.sim, 1, rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	be := transcript.NewRecorder(fb, &r.buf)
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	r.b = b
+	r.buf.Reset()
+	return r
+}
+
+// sent returns the bus operations recorded since the last call, with their
+// arguments and data, and clears the record.
+func (r *busRecorder) sent(t *testing.T) []string {
+	t.Helper()
+	var ops []string
+	dec := json.NewDecoder(&r.buf)
+	for {
+		var e transcript.Entry
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		s := fmt.Sprintf("%s %v", e.Op, e.Args)
+		if len(e.Data) > 0 {
+			s += fmt.Sprintf(" %q", e.Data)
+		}
+		if e.Ibsta&internal.ERR != 0 {
+			s += " ERR"
+		}
+		ops = append(ops, s)
+	}
+	r.buf.Reset()
+	return ops
+}
+
+func TestReset(t *testing.T) {
+	r := newBusRecorder(t)
+	if err := r.b.Reset(22, 5); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Ibsic []",
+		"Ibsre [1]",
+		`Ibcmd [] "\x14"`,
+		fmt.Sprintf(`SendList [%d 22 5] "*RST"`, internal.NLend),
+	}
+	if got := r.sent(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reset sent %q; want %q", got, want)
+	}
+	if !r.sim.RemoteEnable() {
+		t.Error("REN is not asserted after Reset")
+	}
+	for _, in := range []*sim.SCPI{r.dmm, r.psu} {
+		if got := in.Received(); !reflect.DeepEqual(got, []string{"*RST"}) {
+			t.Errorf("instrument received %q; want *RST", got)
+		}
+	}
+
+	// Without addresses, nothing is sent after the device clear.
+	if err := r.b.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.sent(t); len(got) != 3 {
+		t.Errorf("Reset with no addresses sent %q", got)
+	}
+
+	// A missing device is reported.
+	if err := r.b.Reset(22, 9); !errors.Is(err, linuxgpib.ErrNoListeners) {
+		t.Errorf("Reset with a missing device got error %v; want ENOL", err)
+	}
+	r.sent(t)
+
+	// An invalid address is refused before anything is sent.
+	if err := r.b.Reset(22, 31); err == nil {
+		t.Error("Reset with an invalid address succeeded")
+	}
+	if got := r.sent(t); len(got) != 0 {
+		t.Errorf("Reset with an invalid address sent %q", got)
+	}
+}
+
+func TestResetErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fail string
+		want []string
+	}{
+		{"ifc", "Ibsic", []string{"Ibsic [] ERR"}},
+		{"ren", "Ibsre", []string{"Ibsic []", "Ibsre [1] ERR"}},
+		{"dcl", "Ibcmd", []string{"Ibsic []", "Ibsre [1]", `Ibcmd [] "\x14" ERR`}},
+		{"rst", "SendList", []string{"Ibsic []", "Ibsre [1]", `Ibcmd [] "\x14"`, fmt.Sprintf(`SendList [%d 22] "*RST" ERR`, internal.NLend)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newBusRecorder(t, fault.Rule{Kind: fault.BusError, Ops: []string{tc.fail}})
+			if err := r.b.Reset(22); err == nil || err.Error() != "EBUS" {
+				t.Errorf("Reset got error %v; want EBUS", err)
+			}
+			if got := r.sent(t); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Reset sent %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBusControl(t *testing.T) {
+	r := newBusRecorder(t)
+	if err := r.b.InterfaceClear(); err != nil {
+		t.Error(err)
+	}
+	if err := r.b.SetRemoteEnable(true); err != nil {
+		t.Error(err)
+	}
+	if !r.sim.RemoteEnable() {
+		t.Error("REN is not asserted")
+	}
+	if err := r.b.SetRemoteEnable(false); err != nil {
+		t.Error(err)
+	}
+	if r.sim.RemoteEnable() {
+		t.Error("REN is still asserted")
+	}
+	if err := r.b.DeviceClearAll(); err != nil {
+		t.Error(err)
+	}
+	want := []string{"Ibsic []", "Ibsre [1]", "Ibsre [0]", `Ibcmd [] "\x14"`}
+	if got := r.sent(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %q; want %q", got, want)
+	}
+
+	r = newBusRecorder(t, fault.Rule{Kind: fault.Abort, Ops: []string{"Ibsic", "Ibsre", "Ibcmd"}})
+	for name, f := range map[string]func() error{
+		"InterfaceClear":  r.b.InterfaceClear,
+		"SetRemoteEnable": func() error { return r.b.SetRemoteEnable(true) },
+		"DeviceClearAll":  r.b.DeviceClearAll,
+	} {
+		if err := f(); err == nil || err.Error() != "EABO" {
+			t.Errorf("%s got error %v; want EABO", name, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// A Resource identifies a board or device in the syntax used by VISA, such as
+// "GPIB0::22::INSTR" for the device at primary address 22 on board 0,
+// "GPIB1::5::3::INSTR" for the device at primary address 5 and secondary
+// address 3 on board 1, or "GPIB0::INTFC" for board 0 itself.
+type Resource struct {
+	Board int
+	// Interface is true if the resource is the board itself, in which case
+	// Address is not used.
+	Interface bool
+	Address   Address
+}
+
+// ParseResource parses a VISA resource string for a GPIB instrument or
+// interface. As in VISA, the string is case insensitive, the board number
+// defaults to zero, and the INSTR suffix is optional.
+func ParseResource(s string) (Resource, error) {
+	var r Resource
+	fail := func(format string, v ...interface{}) (Resource, error) {
+		return Resource{}, fmt.Errorf("invalid resource %q: %s", s, fmt.Sprintf(format, v...))
+	}
+
+	parts := strings.Split(strings.ToUpper(s), "::")
+	if !strings.HasPrefix(parts[0], "GPIB") {
+		return fail("must begin with GPIB")
+	}
+	if n := parts[0][len("GPIB"):]; n != "" {
+		b, err := strconv.Atoi(n)
+		if err != nil || b < 0 || b >= internal.GPIB_MAX_NUM_BOARDS {
+			return fail("board %q is not a number from 0 to %d", n, internal.GPIB_MAX_NUM_BOARDS-1)
+		}
+		r.Board = b
+	}
+	parts = parts[1:]
+
+	switch {
+	case len(parts) == 0:
+		return fail("missing address or INTFC")
+	case len(parts) == 1 && parts[0] == "INTFC":
+		r.Interface = true
+		return r, nil
+	}
+
+	if parts[len(parts)-1] == "INSTR" {
+		parts = parts[:len(parts)-1]
+	}
+	switch len(parts) {
+	case 0:
+		return fail("missing primary address")
+	case 1, 2:
+	default:
+		return fail("unexpected %q after addresses", strings.Join(parts[2:], "::"))
+	}
+	addr := func(kind, a string) (int, error) {
+		n, err := strconv.Atoi(a)
+		if err != nil || n < 0 || n > 30 {
+			return 0, fmt.Errorf("%s address %q is not a number from 0 to 30", kind, a)
+		}
+		return n, nil
+	}
+	pad, err := addr("primary", parts[0])
+	if err != nil {
+		return fail("%v", err)
+	}
+	sad := 0
+	if len(parts) == 2 {
+		n, err := addr("secondary", parts[1])
+		if err != nil {
+			return fail("%v", err)
+		}
+		sad = minSecondary + n
+	}
+	r.Address = newAddress(pad, sad)
+	return r, nil
+}
+
+// String returns the resource in canonical VISA syntax.
+func (r Resource) String() string {
+	switch {
+	case r.Interface:
+		return fmt.Sprintf("GPIB%d::INTFC", r.Board)
+	case r.Address.Secondary() != 0:
+		return fmt.Sprintf("GPIB%d::%d::%d::INSTR", r.Board, r.Address.Primary(), r.Address.Secondary()-minSecondary)
+	}
+	return fmt.Sprintf("GPIB%d::%d::INSTR", r.Board, r.Address.Primary())
+}
+
+// Open returns the device identified by a VISA resource string such as
+// "GPIB0::22::INSTR". The board is opened with the given options, unless it is
+// already in use in which case it is shared. A board opened here, or by
+// another call to Open, OpenByName or NewDevice, is closed along with the last
+// device opened that way.
+//
+// To open a board itself, as identified by a resource string such as
+// "GPIB0::INTFC", use OpenBoard.
+func Open(resource string, opts ...Option) (*Device, error) {
+	r, err := ParseResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	if r.Interface {
+		return nil, fmt.Errorf("resource %q is an interface: use OpenBoard", resource)
+	}
+
+	o := newOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	mu.Lock()
+	b, existing, err := openBoard(r.Board, o)
+	owns := false
+	if err == nil {
+		owns = b.hold(existing)
+	}
+	mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// A board opened here already has the options, which its devices inherit,
+	// so they are only applied to a device on an existing board. Applying them
+	// twice would add interceptors twice.
+	var devOpts []Option
+	if existing {
+		devOpts = opts
+	}
+	d, err := b.NewDevice(r.Address, devOpts...)
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil {
+		if owns {
+			b.unhold()
+		}
+		return nil, err
+	}
+	d.ownsBoard = owns
+	return d, nil
+}
+
+// OpenBoard returns the board identified by a VISA resource string such as
+// "GPIB0::INTFC".
+func OpenBoard(resource string, opts ...Option) (*Board, error) {
+	r, err := ParseResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	if !r.Interface {
+		return nil, fmt.Errorf("resource %q is an instrument: use Open", resource)
+	}
+	return NewBoard(r.Board, opts...)
+}
@@ -0,0 +1,377 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package script
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// Record is a value captured by a script.
+type Record struct {
+	Time      time.Time
+	Script    string // the name given to Parse
+	Line      int
+	Address   linuxgpib.Address
+	Statement string // the statement after expanding variables
+	Name      string // the variable named after "->", if any
+	Value     string
+}
+
+// Error reports the statement at which a script failed.
+type Error struct {
+	Name string
+	Line int
+	Text string // the statement as written
+	// Status is the decoded ibsta after a failed GPIB operation, such as
+	// "ibsta 0xc100 [ERR TIMO CMPL]".
+	Status string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	s := fmt.Sprintf("%s:%d: %s: %v", e.Name, e.Line, e.Text, e.Err)
+	if e.Status != "" {
+		s += " (" + e.Status + ")"
+	}
+	return s
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Runner runs scripts on the devices of a board. Devices are opened when first
+// used, and stay open until Close so that variables and devices carry over
+// from one script to the next.
+type Runner struct {
+	Board *linuxgpib.Board
+	// Vars holds the variables, and may be set before running a script.
+	Vars map[string]string
+	// Record, if set, is called with each captured value.
+	Record func(Record)
+	// Out receives the output of PRINT. It is discarded if nil.
+	Out io.Writer
+	// Trace, if set, is called with each statement before it runs, after
+	// expanding variables.
+	Trace func(line int, text string)
+
+	devices map[linuxgpib.Address]*linuxgpib.Device
+}
+
+// Close closes the devices opened by the runner.
+func (r *Runner) Close() error {
+	var err error
+	for addr, d := range r.devices {
+		if cerr := d.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(r.devices, addr)
+	}
+	return err
+}
+
+// Run runs a script, stopping at the first failure or when ctx is done.
+func (r *Runner) Run(ctx context.Context, s *Script) error {
+	if r.Vars == nil {
+		r.Vars = map[string]string{}
+	}
+	if r.devices == nil {
+		r.devices = map[linuxgpib.Address]*linuxgpib.Device{}
+	}
+	return r.block(ctx, s, s.stmts)
+}
+
+func (r *Runner) block(ctx context.Context, s *Script, stmts []*stmt) error {
+	for _, st := range stmts {
+		if err := ctx.Err(); err != nil {
+			return &Error{Name: s.name, Line: st.line, Text: st.text, Err: err}
+		}
+		gpib, err := r.exec(ctx, s, st)
+		if e, ok := err.(*Error); ok {
+			return e // from a nested block
+		}
+		if err != nil {
+			e := &Error{Name: s.name, Line: st.line, Text: st.text, Err: err}
+			if gpib {
+				ibsta := r.Board.Backend().Ibsta()
+				e.Status = fmt.Sprintf("ibsta 0x%04x [%s]", ibsta, internal.FormatIbsta(ibsta))
+			}
+			return e
+		}
+	}
+	return nil
+}
+
+// address returns the device address given by an argument.
+func (r *Runner) address(arg string) (linuxgpib.Address, error) {
+	s, err := expand(arg, r.Vars)
+	if err != nil {
+		return 0, err
+	}
+	return linuxgpib.ParseAddress(s)
+}
+
+// device returns the device at an address, opening it if needed.
+func (r *Runner) device(addr linuxgpib.Address) (*linuxgpib.Device, error) {
+	if d, ok := r.devices[addr]; ok {
+		return d, nil
+	}
+	d, err := r.Board.NewDevice(addr)
+	if err != nil {
+		return nil, err
+	}
+	r.devices[addr] = d
+	return d, nil
+}
+
+// exec runs a statement. It reports whether an error came from a GPIB
+// operation, as opposed to the script itself.
+func (r *Runner) exec(ctx context.Context, s *Script, st *stmt) (gpib bool, err error) {
+	rest, err := expand(st.rest, r.Vars)
+	if err != nil {
+		return false, err
+	}
+	if r.Trace != nil {
+		text, err := expand(st.text, r.Vars)
+		if err != nil {
+			return false, err
+		}
+		r.Trace(st.line, text)
+	}
+
+	switch st.keyword {
+	case "SET":
+		r.Vars[st.args[0]] = rest
+		return false, nil
+	case "SLEEP":
+		d, err := r.duration(st.args[0])
+		if err != nil {
+			return false, err
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	case "PRINT":
+		if r.Out != nil {
+			fmt.Fprintln(r.Out, rest)
+		}
+		return false, nil
+	case "EXPECT":
+		return false, r.expect(st)
+	case "LOOP":
+		return false, r.loop(ctx, s, st)
+	case "FOR":
+		for _, arg := range st.args[2:] {
+			v, err := expand(arg, r.Vars)
+			if err != nil {
+				return false, err
+			}
+			r.Vars[st.args[0]] = v
+			if err := r.block(ctx, s, st.body); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	// The remaining statements operate on a device.
+	addrArg := st.args[0]
+	if st.keyword == "WAIT" {
+		addrArg = st.args[1]
+	}
+	addr, err := r.address(addrArg)
+	if err != nil {
+		return false, err
+	}
+	d, err := r.device(addr)
+	if err != nil {
+		return true, err
+	}
+	var value string
+	switch st.keyword {
+	case "WRITE":
+		_, err = d.Write([]byte(rest + "\n"))
+	case "QUERY":
+		value, err = d.Query(rest)
+	case "READ":
+		value, err = readAll(d)
+	case "SPOLL":
+		var stb byte
+		stb, err = d.Spoll()
+		value = strconv.Itoa(int(stb))
+	case "WAIT":
+		var stb byte
+		stb, err = d.WaitSRQ()
+		value = strconv.Itoa(int(stb))
+	case "TRIGGER":
+		err = d.Trigger()
+	case "CLEAR":
+		err = d.Clear()
+	case "LOCAL":
+		err = d.Local()
+	case "REMOTE":
+		err = d.Remote()
+	case "TIMEOUT":
+		var t time.Duration
+		if t, err = r.duration(st.args[1]); err != nil {
+			return false, err
+		}
+		err = d.SetTimeout(t)
+	}
+	if err != nil {
+		return true, err
+	}
+	if captures[st.keyword] {
+		r.capture(s, st, addr, rest, value)
+	}
+	return false, nil
+}
+
+// capture stores a captured value and passes it to the Record callback.
+func (r *Runner) capture(s *Script, st *stmt, addr linuxgpib.Address, rest, value string) {
+	r.Vars["RESULT"] = value
+	if st.capture != "" {
+		r.Vars[st.capture] = value
+	}
+	if r.Record == nil {
+		return
+	}
+	text := st.keyword
+	if st.keyword == "WAIT" {
+		text += " SRQ"
+	}
+	text += " " + addr.String()
+	if rest != "" {
+		text += " " + rest
+	}
+	r.Record(Record{
+		Time:      time.Now(),
+		Script:    s.name,
+		Line:      st.line,
+		Address:   addr,
+		Statement: text,
+		Name:      st.capture,
+		Value:     value,
+	})
+}
+
+// readAll reads until the device asserts END, and removes the trailing newline
+// as Query does.
+func readAll(d *linuxgpib.Device) (string, error) {
+	var resp []byte
+	buf := make([]byte, 4096)
+	for {
+		n, end, err := d.ReadEnd(buf)
+		resp = append(resp, buf[:n]...)
+		if err != nil {
+			return "", err
+		}
+		if end {
+			return strings.TrimRight(string(resp), "\r\n"), nil
+		}
+	}
+}
+
+func (r *Runner) duration(arg string) (time.Duration, error) {
+	s, err := expand(arg, r.Vars)
+	if err != nil {
+		return 0, err
+	}
+	return time.ParseDuration(s)
+}
+
+func (r *Runner) loop(ctx context.Context, s *Script, st *stmt) error {
+	c, err := expand(st.args[0], r.Vars)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(c)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid count %q", c)
+	}
+	for i := 1; i <= n; i++ {
+		if len(st.args) == 2 {
+			r.Vars[st.args[1]] = strconv.Itoa(i)
+		}
+		if err := r.block(ctx, s, st.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operators maps the operators of EXPECT to functions comparing two values.
+var operators = map[string]func(a, b string) (bool, error){
+	"==":       func(a, b string) (bool, error) { return compare(a, b) == 0, nil },
+	"!=":       func(a, b string) (bool, error) { return compare(a, b) != 0, nil },
+	"<":        func(a, b string) (bool, error) { return compare(a, b) < 0, nil },
+	"<=":       func(a, b string) (bool, error) { return compare(a, b) <= 0, nil },
+	">":        func(a, b string) (bool, error) { return compare(a, b) > 0, nil },
+	">=":       func(a, b string) (bool, error) { return compare(a, b) >= 0, nil },
+	"contains": func(a, b string) (bool, error) { return strings.Contains(a, b), nil },
+	"matches": func(a, b string) (bool, error) {
+		re, err := regexp.Compile(b)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(a), nil
+	},
+}
+
+// compare compares two values as numbers if both are numbers, and as strings
+// otherwise.
+func compare(a, b string) int {
+	x, errx := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, erry := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if errx != nil || erry != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func (r *Runner) expect(st *stmt) error {
+	a, err := expand(st.args[0], r.Vars)
+	if err != nil {
+		return err
+	}
+	b, err := expand(strings.Join(st.args[2:], " "), r.Vars)
+	if err != nil {
+		return err
+	}
+	op := strings.ToLower(st.args[1])
+	ok, err := operators[op](a, b)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("expected %q %s %q", a, op, b)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package script runs plain-text procedures which control GPIB devices.
+//
+// A script has one statement per line. Keywords are not case sensitive, and
+// lines starting with # are comments. ADDRESS is a device address such as 22
+// or 5.3, and may be given by a variable.
+//
+//	SET NAME VALUE           set a variable, referenced as $NAME or ${NAME}
+//	WRITE ADDRESS TEXT       send TEXT followed by a newline
+//	QUERY ADDRESS TEXT       send TEXT and capture the response
+//	READ ADDRESS             capture a response
+//	SPOLL ADDRESS            capture the status byte from a serial poll
+//	WAIT SRQ ADDRESS         wait for the device to request service, and
+//	                         capture its status byte
+//	TRIGGER ADDRESS          trigger the device
+//	CLEAR ADDRESS            clear the device
+//	LOCAL ADDRESS            return the device to local control
+//	REMOTE ADDRESS           place the device under remote control
+//	TIMEOUT ADDRESS DURATION change the device's timeout, such as 3s
+//	SLEEP DURATION           pause, such as 500ms
+//	EXPECT VALUE OP VALUE    stop unless the comparison holds
+//	PRINT TEXT               write TEXT to the runner's output
+//	LOOP COUNT [NAME]        repeat up to END, counting from 1 in NAME
+//	FOR NAME IN VALUE...     repeat up to END, for each value in NAME
+//	END                      end a LOOP or FOR
+//
+// Captured values are stored in the variable RESULT, and in the variable named
+// after "->" at the end of the line if there is one, as in
+// "QUERY $dmm MEAS:VOLT? -> volts". They are also passed to the runner's
+// Record callback.
+//
+// The operators of EXPECT are ==, !=, <, <=, > and >=, which compare as
+// numbers if both values are numbers and as strings otherwise, and contains
+// and matches, the latter with a regular expression.
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// stmt is a parsed statement.
+type stmt struct {
+	line    int
+	text    string   // the line as written, for diagnostics
+	keyword string   // in upper case
+	args    []string // fields after the keyword
+	rest    string   // the text after the address, for WRITE, QUERY and PRINT
+	capture string   // the variable after "->", if any
+	body    []*stmt  // for LOOP and FOR
+}
+
+// Script is a parsed script.
+type Script struct {
+	name  string
+	stmts []*stmt
+}
+
+// SyntaxError reports a mistake in a script.
+type SyntaxError struct {
+	Name string
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Name, e.Line, e.Msg)
+}
+
+// minArgs is the number of fields required after each keyword.
+var minArgs = map[string]int{
+	"SET":     2,
+	"WRITE":   2,
+	"QUERY":   2,
+	"READ":    1,
+	"SPOLL":   1,
+	"WAIT":    2,
+	"TRIGGER": 1,
+	"CLEAR":   1,
+	"LOCAL":   1,
+	"REMOTE":  1,
+	"TIMEOUT": 2,
+	"SLEEP":   1,
+	"EXPECT":  3,
+	"PRINT":   0,
+	"LOOP":    1,
+	"FOR":     3,
+	"END":     0,
+}
+
+// captures lists the keywords which capture a value.
+var captures = map[string]bool{"QUERY": true, "READ": true, "SPOLL": true, "WAIT": true}
+
+// Parse reads a script. The name is used in error messages.
+func Parse(r io.Reader, name string) (*Script, error) {
+	s := bufio.NewScanner(r)
+	// Blocks being parsed, innermost last. The first is the script itself.
+	blocks := []*stmt{{}}
+	n := 0
+	for s.Scan() {
+		n++
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		st, err := parseLine(text, n)
+		if err != nil {
+			return nil, &SyntaxError{name, n, err.Error()}
+		}
+		top := blocks[len(blocks)-1]
+		switch st.keyword {
+		case "END":
+			if len(blocks) == 1 {
+				return nil, &SyntaxError{name, n, "END without LOOP or FOR"}
+			}
+			blocks = blocks[:len(blocks)-1]
+		case "LOOP", "FOR":
+			top.body = append(top.body, st)
+			blocks = append(blocks, st)
+		default:
+			top.body = append(top.body, st)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(blocks) > 1 {
+		open := blocks[len(blocks)-1]
+		return nil, &SyntaxError{name, open.line, open.keyword + " without END"}
+	}
+	return &Script{name: name, stmts: blocks[0].body}, nil
+}
+
+// parseLine parses a statement.
+func parseLine(text string, line int) (*stmt, error) {
+	st := &stmt{line: line, text: text}
+	keyword, rest := cutSpace(text)
+	st.keyword = strings.ToUpper(keyword)
+	min, ok := minArgs[st.keyword]
+	if !ok {
+		return nil, fmt.Errorf("unknown statement %s", keyword)
+	}
+	if captures[st.keyword] {
+		if i := strings.LastIndex(rest, "->"); i >= 0 {
+			st.capture = strings.TrimSpace(rest[i+2:])
+			rest = strings.TrimSpace(rest[:i])
+			if !validName(st.capture) {
+				return nil, fmt.Errorf("invalid variable name %q after ->", st.capture)
+			}
+		}
+	}
+	st.args = strings.Fields(rest)
+	if len(st.args) < min {
+		return nil, fmt.Errorf("%s needs at least %d arguments", st.keyword, min)
+	}
+
+	switch st.keyword {
+	case "WRITE", "QUERY":
+		_, st.rest = cutSpace(rest)
+	case "PRINT":
+		st.rest = rest
+	case "SET":
+		if !validName(st.args[0]) {
+			return nil, fmt.Errorf("invalid variable name %q", st.args[0])
+		}
+		_, st.rest = cutSpace(rest)
+	case "WAIT":
+		if strings.ToUpper(st.args[0]) != "SRQ" {
+			return nil, fmt.Errorf("WAIT supports only SRQ, not %s", st.args[0])
+		}
+	case "LOOP":
+		if len(st.args) > 2 || len(st.args) == 2 && !validName(st.args[1]) {
+			return nil, fmt.Errorf("LOOP needs a count and an optional variable name")
+		}
+	case "FOR":
+		if !validName(st.args[0]) || strings.ToUpper(st.args[1]) != "IN" {
+			return nil, fmt.Errorf("FOR needs the form FOR NAME IN VALUE...")
+		}
+	case "EXPECT":
+		if _, ok := operators[strings.ToLower(st.args[1])]; !ok {
+			return nil, fmt.Errorf("unknown operator %s", st.args[1])
+		}
+	}
+	return st, nil
+}
+
+// cutSpace splits s around the first run of white space, as strings.Fields
+// does, and returns the first word and the rest.
+func cutSpace(s string) (word, rest string) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// validName reports whether s can be a variable name.
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// expand replaces $NAME and ${NAME} with the values of variables, and $$ with
+// a dollar sign.
+func expand(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			continue
+		}
+		var name string
+		switch {
+		case i+1 < len(s) && s[i+1] == '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${ in %q", s)
+			}
+			name = s[i+2 : i+end]
+			i += end
+		default:
+			j := i + 1
+			for j < len(s) && validName(s[i+1:j+1]) {
+				j++
+			}
+			name = s[i+1 : j]
+			i = j - 1
+		}
+		v, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("undefined variable %s", strconv.Quote(name))
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
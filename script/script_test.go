@@ -0,0 +1,219 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package script
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/fault"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		line int
+	}{
+		{"FROB 22", 1},
+		{"WRITE 22", 1},
+		{"# setup\nLOOP 3\nWRITE 22 *TRG", 2},
+		{"WRITE 22 *RST\nEND", 2},
+		{"WAIT FOR 22", 1},
+		{"FOR x 1 2", 1},
+		{"EXPECT $x ~ 1", 1},
+		{"QUERY 22 *IDN? -> 9x", 1},
+	} {
+		_, err := Parse(strings.NewReader(tc.in), "test")
+		var se *SyntaxError
+		if !errors.As(err, &se) || se.Line != tc.line {
+			t.Errorf("Parse(%q) = %v; want a syntax error on line %d", tc.in, err, tc.line)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"dmm": "22", "v": "1.5"}
+	for _, tc := range []struct {
+		in, want string
+		ok       bool
+	}{
+		{"plain", "plain", true},
+		{"$dmm", "22", true},
+		{"${dmm}5 $v;$$", "225 1.5;$", true},
+		{"$missing", "", false},
+		{"${dmm", "", false},
+	} {
+		got, err := expand(tc.in, vars)
+		if got != tc.want || (err == nil) != tc.ok {
+			t.Errorf("expand(%q) = %q, %v; want %q, ok=%v", tc.in, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func testRunner(t *testing.T) (*Runner, *sim.SCPI) {
+	be := sim.New()
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	dmm.Respond("MEAS:VOLT?", "+1.25E+00")
+	be.Attach(22, dmm)
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{Board: b}
+	t.Cleanup(func() {
+		r.Close()
+		b.Close()
+	})
+	return r, dmm
+}
+
+func TestRun(t *testing.T) {
+	r, dmm := testRunner(t)
+	var records []Record
+	r.Record = func(rec Record) { records = append(records, rec) }
+	var out strings.Builder
+	r.Out = &out
+
+	s, err := Parse(strings.NewReader(`
+# Take a few readings.
+SET dmm 22
+CLEAR $dmm
+WRITE $dmm *RST
+LOOP 2 i
+  FOR range IN 10 100
+    WRITE $dmm CONF:VOLT:DC ${range}
+    QUERY $dmm MEAS:VOLT? -> volts
+    EXPECT $volts > 1
+    EXPECT $volts < $range
+  END
+END
+query $dmm *IDN?
+EXPECT $RESULT matches ^ACME,
+PRINT done $i
+`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 5 {
+		t.Fatalf("got %d records; want 5", len(records))
+	}
+	want := Record{Script: "test", Line: 9, Address: 22, Statement: "QUERY 22 MEAS:VOLT?", Name: "volts", Value: "+1.25E+00"}
+	got := records[0]
+	got.Time = want.Time
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("first record = %+v; want %+v", got, want)
+	}
+	if got := records[4].Value; got != "ACME,DMM,0,1.0" {
+		t.Errorf("last record = %q; want the identification", got)
+	}
+	if got := out.String(); got != "done 2\n" {
+		t.Errorf("PRINT wrote %q; want %q", got, "done 2\n")
+	}
+	if got := dmm.Received()[1]; got != "CONF:VOLT:DC 10" {
+		t.Errorf("instrument received %q; want CONF:VOLT:DC 10", got)
+	}
+	if dmm.Clears() != 1 {
+		t.Errorf("instrument got %d clears; want 1", dmm.Clears())
+	}
+}
+
+func TestRunTabs(t *testing.T) {
+	r, dmm := testRunner(t)
+	s, err := Parse(strings.NewReader("WRITE\t22\t*RST\nQUERY 22\t*IDN? -> id\nSET\tx\t1"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	if got := dmm.Received()[0]; got != "*RST" {
+		t.Errorf("instrument received %q; want *RST", got)
+	}
+	if got := r.Vars["id"]; got != "ACME,DMM,0,1.0" {
+		t.Errorf("id = %q; want the identification", got)
+	}
+	if got := r.Vars["x"]; got != "1" {
+		t.Errorf("x = %q; want 1", got)
+	}
+}
+
+func TestRunOpenFailure(t *testing.T) {
+	be, err := fault.New(sim.New(), 1, fault.Rule{Kind: fault.BusError, Ops: []string{"Ibdev"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	r := &Runner{Board: b}
+	defer r.Close()
+	s, err := Parse(strings.NewReader("CLEAR 22"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Run(context.Background(), s)
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, linuxgpib.ErrBusError) || !strings.Contains(e.Status, "ERR") {
+		t.Errorf("Run() = %v; want a bus error with its status", err)
+	}
+}
+
+func TestRunWaitSRQ(t *testing.T) {
+	r, dmm := testRunner(t)
+	dmm.RequestService(0x50)
+	s, err := Parse(strings.NewReader("WAIT SRQ 22 -> stb\nEXPECT $stb == 80"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(context.Background(), s); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	r, _ := testRunner(t)
+	for _, tc := range []struct {
+		in     string
+		line   int
+		target error
+		status bool
+	}{
+		{"WRITE 22 *RST\nQUERY 7 *IDN?", 2, linuxgpib.ErrNoListeners, true},
+		{"LOOP 1\nQUERY 22 MEAS:VOLT? -> v\nEXPECT $v > 2\nEND", 3, nil, false},
+		{"PRINT $undefined", 1, nil, false},
+	} {
+		s, err := Parse(strings.NewReader(tc.in), "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Run(context.Background(), s)
+		var e *Error
+		if !errors.As(err, &e) || e.Line != tc.line || (e.Status != "") != tc.status {
+			t.Errorf("Run(%q) = %v; want a failure on line %d with status=%v", tc.in, err, tc.line, tc.status)
+			continue
+		}
+		if tc.target != nil && !errors.Is(err, tc.target) {
+			t.Errorf("Run(%q) = %v; want %v", tc.in, err, tc.target)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package sim
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rqs is the status byte bit set while requesting service.
+const rqs = 0x40
+
+// SCPI is an Instrument which answers queries from a table and records the
+// messages it receives. The responses to the queries in a message are
+// separated by semicolons and terminated by a newline. Unknown queries are not
+// answered, so reading their response times out.
+//
+// It understands the common commands *IDN?, *OPC?, *STB?, *CLS and *TRG.
+type SCPI struct {
+	mu        sync.Mutex
+	responses map[string]string
+	received  []string
+	output    []byte
+	stb       byte
+	triggers  int
+	clears    int
+}
+
+// NewSCPI returns an instrument with the given identification string.
+func NewSCPI(idn string) *SCPI {
+	return &SCPI{
+		responses: map[string]string{"*IDN?": idn},
+	}
+}
+
+// Respond sets the response to a query. Queries are matched without regard to
+// case or surrounding white space.
+func (s *SCPI) Respond(query, response string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[strings.ToUpper(strings.TrimSpace(query))] = response
+}
+
+// RequestService sets the status byte, and requests service if its RQS bit is
+// set.
+func (s *SCPI) RequestService(stb byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stb = stb
+}
+
+// Received returns the messages received so far, without trailing newlines.
+func (s *SCPI) Received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+// Triggers returns the number of triggers received.
+func (s *SCPI) Triggers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.triggers
+}
+
+// Clears returns the number of device clears received.
+func (s *SCPI) Clears() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clears
+}
+
+func (s *SCPI) Receive(msg []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := strings.TrimRight(string(msg), "\r\n")
+	s.received = append(s.received, m)
+	var resp []string
+	for _, unit := range strings.Split(m, ";") {
+		unit = strings.ToUpper(strings.TrimSpace(unit))
+		switch unit {
+		case "*OPC?":
+			resp = append(resp, "1")
+		case "*STB?":
+			resp = append(resp, strconv.Itoa(int(s.stb)))
+		case "*CLS":
+			s.stb = 0
+		case "*TRG":
+			s.triggers++
+		default:
+			if r, ok := s.responses[unit]; ok {
+				resp = append(resp, r)
+			}
+		}
+	}
+	if len(resp) > 0 {
+		s.output = append(s.output, strings.Join(resp, ";")+"\n"...)
+	}
+}
+
+func (s *SCPI) Send() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.output
+	s.output = nil
+	return out
+}
+
+func (s *SCPI) Poll() byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stb := s.stb
+	s.stb &^= rqs
+	return stb
+}
+
+func (s *SCPI) Requesting() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stb&rqs != 0
+}
+
+func (s *SCPI) Trigger() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.triggers++
+}
+
+func (s *SCPI) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.output = nil
+	s.clears++
+}
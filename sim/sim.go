@@ -0,0 +1,542 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package sim simulates a GPIB board with instruments attached, for testing
+// code that uses linuxgpib without hardware.
+//
+// A Backend is passed to linuxgpib.UseBackend in place of the C library:
+//
+//	be := sim.New()
+//	be.Attach(22, sim.NewSCPI("ACME,DMM,0,1.0"))
+//	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+//
+// Operations complete immediately. In particular, reading from an instrument
+// with nothing to send times out without waiting.
+package sim
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// Instrument is a simulated device on the bus.
+type Instrument interface {
+	// Receive handles a message written by the controller, which ended with
+	// EOI.
+	Receive(msg []byte)
+	// Send returns the data the instrument has ready to be read, which is
+	// consumed, or nil if there is none.
+	Send() []byte
+	// Poll returns the status byte for a serial poll, and withdraws any
+	// request for service.
+	Poll() byte
+	// Requesting reports whether the instrument is asserting SRQ.
+	Requesting() bool
+	// Trigger handles a group execute trigger.
+	Trigger()
+	// Clear handles a device clear.
+	Clear()
+}
+
+// Backend is a linuxgpib.Backend which simulates board 0. Other boards do not
+// exist. It is safe for concurrent use.
+type Backend struct {
+	mu          sync.Mutex
+	res         internal.Result
+	instruments map[linuxgpib.Address]Instrument
+	devices     map[int]*device
+	next        int
+	ren         bool
+}
+
+// device is an open device descriptor.
+type device struct {
+	addr    linuxgpib.Address
+	tmo     int
+	eot     int
+	eos     int
+	partIn  []byte // received without EOI
+	partOut []byte // remainder of a message being read
+}
+
+// New returns a simulated board with no instruments.
+func New() *Backend {
+	return &Backend{
+		instruments: map[linuxgpib.Address]Instrument{},
+		devices:     map[int]*device{},
+		next:        internal.GPIB_MAX_NUM_BOARDS,
+	}
+}
+
+// Attach connects an instrument to the bus at the given address.
+func (b *Backend) Attach(addr linuxgpib.Address, in Instrument) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.instruments[addr] = in
+}
+
+// RemoteEnable reports whether the controller is asserting REN.
+func (b *Backend) RemoteEnable() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ren
+}
+
+// OpenDevices returns the number of open device descriptors.
+func (b *Backend) OpenDevices() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.devices)
+}
+
+// board checks that a board descriptor is valid.
+func (b *Backend) board(board int) bool {
+	if board != 0 {
+		b.res.Fail(internal.ENEB)
+		return false
+	}
+	return true
+}
+
+// device returns an open device, or nil if the descriptor is invalid.
+func (b *Backend) device(ud int) *device {
+	d := b.devices[ud]
+	if d == nil {
+		b.res.Fail(internal.EARG)
+	}
+	return d
+}
+
+// instrument returns the instrument for a device, or nil if there is none
+// which is reported as ENOL.
+func (b *Backend) instrument(d *device) Instrument {
+	in := b.instruments[d.addr]
+	if in == nil {
+		b.res.Fail(internal.ENOL)
+	}
+	return in
+}
+
+func (b *Backend) Ibvers() string { return "sim" }
+
+func (b *Backend) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return -1
+	}
+	addr, err := linuxgpib.NewAddress(pad, sad)
+	if err != nil {
+		b.res.Fail(internal.EARG)
+		return -1
+	}
+	ud := b.next
+	b.next++
+	b.devices[ud] = &device{addr: addr, tmo: tmo, eot: eot, eos: eos}
+	b.res.Done(0, 0)
+	return ud
+}
+
+func (b *Backend) Ibfind(name string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.res.FailErrno(int(syscall.ENOENT))
+	return -1
+}
+
+func (b *Backend) Ibonl(ud, v int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ud < internal.GPIB_MAX_NUM_BOARDS {
+		if !b.board(ud) {
+			return b.res.Sta
+		}
+		return b.res.Done(0, 0)
+	}
+	if b.device(ud) == nil {
+		return b.res.Sta
+	}
+	if v == 0 {
+		delete(b.devices, ud)
+	}
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibask(ud, option int) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta, 0
+	}
+	var v int
+	switch option {
+	case internal.IbaPAD:
+		v = d.addr.Primary()
+	case internal.IbaSAD:
+		v = d.addr.Secondary()
+	case internal.IbaTMO:
+		v = d.tmo
+	case internal.IbaEOT:
+		v = d.eot
+	case internal.IbaEOSrd:
+		v = d.eos & internal.REOS
+	case internal.IbaEOSchar:
+		v = d.eos & 0xff
+	case internal.IbaBNA:
+		v = 0
+	default:
+		return b.res.Fail(internal.EARG), 0
+	}
+	return b.res.Done(0, 0), v
+}
+
+func (b *Backend) Ibconfig(ud, option, value int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	switch option {
+	case internal.IbcTMO:
+		d.tmo = value
+	case internal.IbcEOT:
+		d.eot = value
+	case internal.IbcBNA:
+		if !b.board(value) {
+			return b.res.Sta
+		}
+	default:
+		return b.res.Fail(internal.EARG)
+	}
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibbna(ud int, name string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.FailErrno(int(syscall.ENOENT))
+}
+
+func (b *Backend) Ibtmo(ud, v int) int {
+	return b.Ibconfig(ud, internal.IbcTMO, v)
+}
+
+func (b *Backend) Ibeot(ud, v int) int {
+	return b.Ibconfig(ud, internal.IbcEOT, v)
+}
+
+func (b *Backend) Ibeos(ud, v int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	d.eos = v
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibrd(ud int, buf []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	if d.partOut == nil {
+		if in := b.instruments[d.addr]; in != nil {
+			d.partOut = in.Send()
+		}
+		if d.partOut == nil {
+			return b.res.Timeout(0)
+		}
+	}
+	// Stop after the EOS character if enabled, or when the buffer is full.
+	out := d.partOut
+	reos := d.eos&internal.REOS != 0
+	n := len(out)
+	if reos {
+		if i := bytes.IndexByte(out, byte(d.eos)); i >= 0 {
+			n = i + 1
+		}
+	}
+	if n > len(buf) {
+		n = len(buf)
+	}
+	copy(buf, out[:n])
+	end := n == len(out) || reos && n > 0 && out[n-1] == byte(d.eos)
+	d.partOut = out[n:]
+	if len(d.partOut) == 0 {
+		d.partOut = nil
+	}
+	if !end {
+		return b.res.Done(0, n)
+	}
+	return b.res.Done(internal.END, n)
+}
+
+func (b *Backend) Ibwrt(ud int, buf []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	in := b.instrument(d)
+	if in == nil {
+		return b.res.Sta
+	}
+	d.partIn = append(d.partIn, buf...)
+	if d.eot != 0 {
+		msg := d.partIn
+		d.partIn = nil
+		in.Receive(msg)
+	}
+	return b.res.Done(0, len(buf))
+}
+
+func (b *Backend) Ibrdf(ud int, path string) int {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return b.fileErr(err)
+	}
+	defer f.Close()
+	buf := make([]byte, 4096)
+	total := 0
+	for {
+		ibsta := b.Ibrd(ud, buf)
+		b.mu.Lock()
+		n := b.res.Cnt
+		b.mu.Unlock()
+		if ibsta&internal.ERR != 0 {
+			return ibsta
+		}
+		if _, err := f.Write(buf[:n]); err != nil {
+			return b.fileErr(err)
+		}
+		total += n
+		if ibsta&internal.END != 0 {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			return b.res.Done(internal.END, total)
+		}
+	}
+}
+
+func (b *Backend) Ibwrtf(ud int, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b.fileErr(err)
+	}
+	return b.Ibwrt(ud, data)
+}
+
+// fileErr records a file system error, and returns ibsta.
+func (b *Backend) fileErr(err error) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	errno := syscall.EIO
+	if e, ok := err.(*os.PathError); ok {
+		if n, ok := e.Err.(syscall.Errno); ok {
+			errno = n
+		}
+	}
+	b.res.Sta, b.res.Err, b.res.Cnt = internal.ERR|internal.CMPL, internal.EFSO, int(errno)
+	return b.res.Sta
+}
+
+func (b *Backend) Ibclr(ud int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	in := b.instrument(d)
+	if in == nil {
+		return b.res.Sta
+	}
+	d.partIn, d.partOut = nil, nil
+	in.Clear()
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibtrg(ud int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	in := b.instrument(d)
+	if in == nil {
+		return b.res.Sta
+	}
+	in.Trigger()
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibrsp(ud int) (int, byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta, 0
+	}
+	in := b.instrument(d)
+	if in == nil {
+		return b.res.Sta, 0
+	}
+	return b.res.Done(0, 0), in.Poll()
+}
+
+func (b *Backend) Ibloc(ud int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.device(ud) == nil {
+		return b.res.Sta
+	}
+	return b.res.Done(0, 0)
+}
+
+// Ibwait returns the current status without waiting. If the mask includes a
+// condition which is not met, the status includes TIMO.
+func (b *Backend) Ibwait(ud, mask int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	d := b.device(ud)
+	if d == nil {
+		return b.res.Sta
+	}
+	ibsta := 0
+	if in := b.instruments[d.addr]; in != nil && in.Requesting() {
+		ibsta |= internal.RQS
+	}
+	if mask&^internal.TIMO != 0 && ibsta&mask == 0 {
+		ibsta |= internal.TIMO
+	}
+	return b.res.Done(ibsta, 0)
+}
+
+func (b *Backend) Ibcmd(board int, cmd []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta
+	}
+	for _, c := range cmd {
+		if linuxgpib.Command(c) == linuxgpib.DCL {
+			for _, in := range b.instruments {
+				in.Clear()
+			}
+		}
+	}
+	return b.res.Done(0, len(cmd))
+}
+
+func (b *Backend) Ibsic(board int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta
+	}
+	return b.res.Done(0, 0)
+}
+
+func (b *Backend) Ibsre(board, v int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta
+	}
+	b.ren = v != 0
+	return b.res.Done(0, 0)
+}
+
+// Iblines reports all lines as valid. NRFD is never asserted, and SRQ is
+// asserted while any instrument requests service.
+func (b *Backend) Iblines(board int) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta, 0
+	}
+	lines := internal.ValidALL
+	for _, in := range b.instruments {
+		if in.Requesting() {
+			lines |= internal.BusSRQ
+		}
+	}
+	if b.ren {
+		lines |= internal.BusREN
+	}
+	return b.res.Done(0, 0), lines
+}
+
+func (b *Backend) Ibln(board, pad, sad int) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta, 0
+	}
+	addr, err := linuxgpib.NewAddress(pad, sad)
+	if err != nil {
+		return b.res.Fail(internal.EARG), 0
+	}
+	found := 0
+	if b.instruments[addr] != nil {
+		found = 1
+	}
+	return b.res.Done(0, 0), found
+}
+
+func (b *Backend) SendList(board int, addrs []linuxgpib.Address, buf []byte, eotmode int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.board(board) {
+		return b.res.Sta
+	}
+	for _, a := range addrs {
+		in := b.instruments[a]
+		if in == nil {
+			return b.res.Fail(internal.ENOL)
+		}
+		in.Receive(buf)
+	}
+	return b.res.Done(0, len(buf))
+}
+
+func (b *Backend) Ibsta() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.Sta
+}
+
+func (b *Backend) Iberr() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.Err
+}
+
+func (b *Backend) Ibcnt() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.res.Cnt
+}
+
+var _ linuxgpib.Backend = (*Backend)(nil)
@@ -0,0 +1,259 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// chunkSize is the number of bytes moved by each GPIB operation in ReadTo and
+// WriteFrom.
+const chunkSize = 64 * 1024
+
+// logHead accumulates the first few bytes of a stream so that long transfers
+// can be logged in the same shortened form as single reads and writes.
+type logHead []byte
+
+func (h *logHead) add(b []byte) {
+	if r := maxLogData + 1 - len(*h); r > 0 {
+		if len(b) > r {
+			b = b[:r]
+		}
+		*h = append(*h, b...)
+	}
+}
+
+// ReadTo reads from the GPIB device into w until the device asserts EOI, or
+// the end of string character configured by ReadEOS is received. It returns
+// the number of bytes written to w.
+//
+// Data is transferred in chunks, so the total size of the response need not be
+// known in advance. A callback registered with Progress is informed after each
+// chunk.
+func (d *Device) ReadTo(w io.Writer) (n int64, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.isClosed {
+		return 0, errors.New("already closed")
+	}
+
+	op, err := d.begin("read", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { op.end(n, nil, err) }()
+
+	var head logHead
+	buf := make([]byte, chunkSize)
+	started := time.Now()
+	for {
+		ibsta := d.board.be.Ibrd(d.ud, buf)
+		rerr := d.err(ibsta)
+		// Data which arrived before a failure, such as a timeout, is stored
+		// too.
+		c := min(int(d.count(rerr)), len(buf))
+		if c > 0 {
+			head.add(buf[:c])
+			if _, err := w.Write(buf[:c]); err != nil {
+				d.logErr("read", err, "Failed to store data from address %v after %d bytes: %v", d.addr, n, err)
+				return n, err
+			}
+			n += int64(c)
+			if d.options.progress != nil {
+				d.options.progress(n)
+			}
+		}
+		if rerr != nil {
+			d.logErr("read", rerr, "Failed to read from address %v device %d after %d bytes: %v", d.addr, d.ud, n, rerr)
+			return n, rerr
+		}
+		if ibsta&internal.END != 0 {
+			break
+		}
+	}
+
+	took := time.Since(started)
+	d.logf(slog.LevelDebug, "read", transferAttrs(n, took), "Read %s in %v from address %v", formatLogTotal(head, n), took.Truncate(time.Millisecond), d.addr)
+	return n, nil
+}
+
+// WriteFrom sends data from r to the GPIB device until r returns io.EOF. EOI
+// is asserted with the last byte only. It returns the number of bytes sent.
+//
+// Data is transferred in chunks, so the total size of the message need not be
+// known in advance. A callback registered with Progress is informed after each
+// chunk.
+func (d *Device) WriteFrom(r io.Reader) (n int64, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.isClosed {
+		return 0, errors.New("already closed")
+	}
+
+	op, err := d.begin("write", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { op.end(n, nil, err) }()
+
+	// Suppress EOI on all but the final chunk, and restore the default when
+	// done so that later calls to Write behave normally.
+	eot := 1
+	defer func() {
+		if eot != 1 {
+			if err := d.err(d.board.be.Ibeot(d.ud, 1)); err != nil {
+				d.logErr("write", err, "Failed to restore EOI mode on address %v device %d: %v", d.addr, d.ud, err)
+			}
+		}
+	}()
+
+	var head logHead
+	br := bufio.NewReaderSize(r, chunkSize)
+	buf := make([]byte, chunkSize)
+	started := time.Now()
+	for {
+		c, err := io.ReadFull(br, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			d.logErr("write", err, "Failed to load data for address %v after %d bytes: %v", d.addr, n, err)
+			return n, err
+		}
+		last := err == io.ErrUnexpectedEOF
+		if !last {
+			_, err := br.Peek(1)
+			if err != nil && err != io.EOF {
+				d.logErr("write", err, "Failed to load data for address %v after %d bytes: %v", d.addr, n, err)
+				return n, err
+			}
+			last = err == io.EOF
+		}
+
+		want := 0
+		if last {
+			want = 1
+		}
+		if eot != want {
+			if err := d.err(d.board.be.Ibeot(d.ud, want)); err != nil {
+				d.logErr("write", err, "Failed to set EOI mode on address %v device %d: %v", d.addr, d.ud, err)
+				return n, err
+			}
+			eot = want
+		}
+
+		ibsta := d.board.be.Ibwrt(d.ud, buf[:c])
+		err = d.err(ibsta)
+		c = min(int(d.count(err)), c)
+		n += int64(c)
+		head.add(buf[:c])
+		if err != nil {
+			d.logErr("write", err, "Failed to write to address %v device %d after %d bytes: %v", d.addr, d.ud, n, err)
+			return n, err
+		}
+		if d.options.progress != nil {
+			d.options.progress(n)
+		}
+		if last {
+			break
+		}
+	}
+
+	took := time.Since(started)
+	d.logf(slog.LevelDebug, "write", transferAttrs(n, took), "Wrote %s in %v to address %v", formatLogTotal(head, n), took.Truncate(time.Millisecond), d.addr)
+	return n, nil
+}
+
+// ReadToFile reads from the GPIB device until EOI, appending the data to the
+// named file. The transfer is performed by the C library using ibrdf, which
+// avoids copying the data through Go.
+func (d *Device) ReadToFile(path string) (n int64, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.isClosed {
+		return 0, errors.New("already closed")
+	}
+
+	op, err := d.begin("read", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { op.end(n, nil, err) }()
+
+	started := time.Now()
+	ibsta := d.board.be.Ibrdf(d.ud, path)
+	took := time.Since(started)
+	err = d.err(ibsta)
+	n = d.count(err)
+
+	if err != nil {
+		d.logErr("read", err, "Failed to read from address %v device %d into %s after %d bytes: %v", d.addr, d.ud, path, n, err)
+		return n, err
+	}
+	if d.options.progress != nil {
+		d.options.progress(n)
+	}
+
+	d.logf(slog.LevelDebug, "read", append(transferAttrs(n, took), slog.String("path", path)), "Read %d bytes in %v from address %v into %s", n, took.Truncate(time.Millisecond), d.addr, path)
+	return n, nil
+}
+
+// WriteFromFile sends the contents of the named file to the GPIB device. The
+// transfer is performed by the C library using ibwrtf, which avoids copying
+// the data through Go.
+func (d *Device) WriteFromFile(path string) (n int64, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d.isClosed {
+		return 0, errors.New("already closed")
+	}
+
+	op, err := d.begin("write", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { op.end(n, nil, err) }()
+
+	started := time.Now()
+	ibsta := d.board.be.Ibwrtf(d.ud, path)
+	took := time.Since(started)
+	err = d.err(ibsta)
+	n = d.count(err)
+
+	if err != nil {
+		d.logErr("write", err, "Failed to write %s to address %v device %d after %d bytes: %v", path, d.addr, d.ud, n, err)
+		return n, err
+	}
+	if d.options.progress != nil {
+		d.options.progress(n)
+	}
+
+	d.logf(slog.LevelDebug, "write", append(transferAttrs(n, took), slog.String("path", path)), "Wrote %d bytes in %v from %s to address %v", n, took.Truncate(time.Millisecond), path, d.addr)
+	return n, nil
+}
+
+// count returns the number of bytes transferred by the last operation, given
+// the error it returned. After EDVR or EFSO, ibcnt holds errno instead.
+func (d *Device) count(err error) int64 {
+	if err != nil {
+		if e := d.board.be.Iberr(); e == internal.EDVR || e == internal.EFSO {
+			return 0
+		}
+	}
+	return int64(d.board.be.Ibcnt())
+}
@@ -0,0 +1,240 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package linuxgpib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/iotest"
+)
+
+// openStream returns a device on the backend.
+func openStream(t *testing.T, be *fakeBackend) *Device {
+	t.Helper()
+	d, err := NewDevice(0, 22, UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// errWriter fails once more than limit bytes have been written.
+type errWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+var errFull = errors.New("full")
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > w.limit {
+		return 0, errFull
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestReadTo(t *testing.T) {
+	big := strings.Repeat("x", 2*chunkSize+100) + "\n"
+	for _, tc := range []struct {
+		name    string
+		be      fakeBackend
+		w       io.Writer
+		wantN   int64
+		wantErr func(error) bool
+	}{
+		{
+			name:    "complete",
+			w:       &bytes.Buffer{},
+			wantN:   int64(len(big)),
+			wantErr: func(err error) bool { return err == nil },
+		},
+		{
+			name:    "short reads",
+			be:      fakeBackend{maxRead: 1000},
+			w:       &bytes.Buffer{},
+			wantN:   int64(len(big)),
+			wantErr: func(err error) bool { return err == nil },
+		},
+		{
+			// The bytes which arrived before the timeout are delivered.
+			name:    "timeout part-way",
+			be:      fakeBackend{limit: chunkSize + 10},
+			w:       &bytes.Buffer{},
+			wantN:   chunkSize + 10,
+			wantErr: os.IsTimeout,
+		},
+		{
+			name:    "timeout in a short read",
+			be:      fakeBackend{maxRead: 1000, limit: 2500},
+			w:       &bytes.Buffer{},
+			wantN:   2500,
+			wantErr: os.IsTimeout,
+		},
+		{
+			name:    "writer fails",
+			w:       &errWriter{limit: chunkSize + 1},
+			wantN:   chunkSize,
+			wantErr: func(err error) bool { return err == errFull },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			be := &tc.be
+			d := openStream(t, be)
+			be.resp = []byte(big)
+			n, err := d.ReadTo(tc.w)
+			if n != tc.wantN || !tc.wantErr(err) {
+				t.Errorf("ReadTo got %d, %v; want %d bytes", n, err, tc.wantN)
+			}
+			if buf, ok := tc.w.(*bytes.Buffer); ok && buf.String() != big[:tc.wantN] {
+				t.Errorf("ReadTo stored %d bytes of data; want %d", buf.Len(), tc.wantN)
+			}
+			if err == nil {
+				// Reading stopped at END, leaving nothing more to read.
+				if _, err := d.Read(make([]byte, 10)); !os.IsTimeout(err) {
+					t.Errorf("Read after ReadTo got error %v; want a timeout", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFrom(t *testing.T) {
+	big := strings.Repeat("y", 2*chunkSize+100) + "\n"
+	be := &fakeBackend{}
+	d := openStream(t, be)
+
+	// EOI is only asserted with the last chunkSize, so the device receives one
+	// message.
+	n, err := d.WriteFrom(strings.NewReader(big))
+	if n != int64(len(big)) || err != nil {
+		t.Errorf("WriteFrom got %d, %v", n, err)
+	}
+	if got := be.received; len(got) != 1 || got[0] != big {
+		t.Errorf("device received %d messages; want one of %d bytes", len(got), len(big))
+	}
+
+	// A reader failing part-way stops the transfer without asserting EOI.
+	errRead := errors.New("read failed")
+	n, err = d.WriteFrom(io.MultiReader(strings.NewReader(big), iotest.ErrReader(errRead)))
+	if n != 2*chunkSize || !errors.Is(err, errRead) {
+		t.Errorf("WriteFrom with failing reader got %d, %v; want %d bytes", n, err, 2*chunkSize)
+	}
+	if got := be.received; len(got) != 1 {
+		t.Errorf("device received %d messages after failed write; want 1", len(got))
+	}
+}
+
+func TestWriteFromTimeout(t *testing.T) {
+	d := openStream(t, &fakeBackend{limit: chunkSize + 7})
+	n, err := d.WriteFrom(strings.NewReader(strings.Repeat("z", 3*chunkSize)))
+	if n != chunkSize+7 || !os.IsTimeout(err) {
+		t.Errorf("WriteFrom got %d, %v; want a timeout after %d bytes", n, err, chunkSize+7)
+	}
+}
+
+func TestWriteFromSystemError(t *testing.T) {
+	// ibcnt holds errno rather than a count after a system error.
+	d := openStream(t, &fakeBackend{fail: "Ibwrt", failErrno: syscall.EIO})
+	n, err := d.WriteFrom(strings.NewReader("*RST\n"))
+	if n != 0 || !errors.Is(err, syscall.EIO) {
+		t.Errorf("WriteFrom got %d, %v; want EIO after 0 bytes", n, err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	be := &fakeBackend{}
+	d := openStream(t, be)
+
+	// ReadToFile appends to an existing file.
+	out := filepath.Join(dir, "out")
+	if err := os.WriteFile(out, []byte("old\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	be.resp = []byte("ACME,DMM,0,1.0\n")
+	n, err := d.ReadToFile(out)
+	if n != 15 || err != nil {
+		t.Errorf("ReadToFile got %d, %v; want 15 bytes", n, err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "old\nACME,DMM,0,1.0\n" {
+		t.Errorf("file contains %q", got)
+	}
+	if _, err := d.ReadToFile(out); !os.IsTimeout(err) {
+		t.Errorf("ReadToFile with no data got error %v; want a timeout", err)
+	}
+
+	in := filepath.Join(dir, "in")
+	if err := os.WriteFile(in, []byte("CONF:VOLT\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	n, err = d.WriteFromFile(in)
+	if n != 10 || err != nil {
+		t.Errorf("WriteFromFile got %d, %v; want 10 bytes", n, err)
+	}
+	if got := be.received; len(got) != 1 || got[0] != "CONF:VOLT\n" {
+		t.Errorf("device received %q", got)
+	}
+	if _, err := d.WriteFromFile(filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("WriteFromFile of missing file got error %v; want ErrNotExist", err)
+	}
+}
+
+func TestFilesPartial(t *testing.T) {
+	dir := t.TempDir()
+	be := &fakeBackend{resp: []byte("ACME,DMM,0,1.0\n"), limit: 5}
+	var ops []string
+	record := InterceptorFuncs{AfterFunc: func(op *Operation) {
+		ops = append(ops, fmt.Sprintf("%s %d", op.Kind, op.Bytes))
+	}}
+	d, err := NewDevice(0, 22, UseBackend(be), Intercept(record))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	ops = nil
+
+	// A transfer which times out part-way reports the bytes it moved.
+	out := filepath.Join(dir, "out")
+	if n, err := d.ReadToFile(out); n != 5 || !os.IsTimeout(err) {
+		t.Errorf("ReadToFile got %d, %v; want a timeout after 5 bytes", n, err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "ACME," {
+		t.Errorf("file contains %q", got)
+	}
+	be.limit, be.moved = 3, 0
+	in := filepath.Join(dir, "in")
+	if err := os.WriteFile(in, []byte("CONF:VOLT\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.WriteFromFile(in); n != 3 || !os.IsTimeout(err) {
+		t.Errorf("WriteFromFile got %d, %v; want a timeout after 3 bytes", n, err)
+	}
+
+	// A file error moves nothing, and errno is not taken for a count.
+	if n, err := d.WriteFromFile(filepath.Join(dir, "missing")); n != 0 || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("WriteFromFile of missing file got %d, %v; want ErrNotExist", n, err)
+	}
+
+	want := []string{"read 5", "write 3", "write 0"}
+	if !slices.Equal(ops, want) {
+		t.Errorf("interceptor saw %q; want %q", ops, want)
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// polls lists the operations which programs repeat while waiting for
+// something to happen. Their number depends on timing, so a Player repeats
+// the last result for as long as the program keeps asking.
+var polls = map[string]bool{"Ibwait": true, "Iblines": true}
+
+// Player is a linuxgpib.Backend which serves recorded operations. Each
+// operation must match the next entry, with the same descriptor, arguments and
+// data written, and then returns its recorded results immediately. An
+// operation which does not match fails with EDVR and errno EPROTO, and is
+// reported by Err. It is safe for concurrent use.
+type Player struct {
+	mu      sync.Mutex
+	res     internal.Result
+	entries []Entry
+	next    int
+	last    *Entry // the last entry played, which may be repeated if it polls
+	err     error
+}
+
+// NewPlayer returns a Player which serves the entries read from r, as written
+// by a Recorder.
+func NewPlayer(r io.Reader) (*Player, error) {
+	p := &Player{}
+	dec := json.NewDecoder(r)
+	for {
+		var e Entry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return p, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %v", len(p.entries)+1, err)
+		}
+		p.entries = append(p.entries, e)
+	}
+}
+
+// Err returns an error describing the first operation which did not match the
+// recording, or nil if all have matched so far.
+func (p *Player) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Remaining returns the number of entries which have not been played.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries) - p.next
+}
+
+// String describes the operation of an entry, without its results.
+func (e *Entry) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s(%d", e.Op, e.UD)
+	for _, a := range e.Args {
+		fmt.Fprintf(&b, ", %d", a)
+	}
+	if e.Name != "" && e.Op != "Ibvers" {
+		fmt.Fprintf(&b, ", %q", e.Name)
+	}
+	if len(e.Data) > 0 && e.Op != "Ibrd" && e.Op != "Ibrdf" {
+		fmt.Fprintf(&b, ", %q", e.Data)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// matches reports whether entry e was recorded for the operation want.
+func matches(e, want *Entry) bool {
+	if e.Op != want.Op || e.UD != want.UD {
+		return false
+	}
+	switch e.Op {
+	case "Ibrd":
+		// Only the size of the buffer is known, and it must be large enough.
+		return want.Args[0] >= len(e.Data)
+	case "Ibrdf":
+		// The data is read, and the file may be elsewhere.
+		return true
+	}
+	if !bytes.Equal(e.Data, want.Data) {
+		return false
+	}
+	if e.Op == "Ibfind" || e.Op == "Ibbna" {
+		return e.Name == want.Name
+	}
+	return slices.Equal(e.Args, want.Args)
+}
+
+// play finds the entry for an operation and sets the results from it. It
+// returns nil if the operation does not match, after recording the error.
+func (p *Player) play(want *Entry) *Entry {
+	var e *Entry
+	switch {
+	case p.next < len(p.entries) && matches(&p.entries[p.next], want):
+		e = &p.entries[p.next]
+		p.next++
+	case p.last != nil && polls[want.Op] && matches(p.last, want):
+		e = p.last
+	default:
+		if p.err == nil {
+			if p.next < len(p.entries) {
+				p.err = fmt.Errorf("entry %d: got %v, want %v", p.next+1, want, &p.entries[p.next])
+			} else {
+				p.err = fmt.Errorf("got %v after the end of the recording", want)
+			}
+		}
+		p.res.FailErrno(int(syscall.EPROTO))
+		return nil
+	}
+	p.last = e
+	p.res = internal.Result{Sta: e.Ibsta, Err: e.Iberr, Cnt: e.Ibcnt}
+	return e
+}
+
+func (p *Player) Ibvers() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e := p.play(&Entry{Op: "Ibvers"}); e != nil {
+		return e.Name
+	}
+	return ""
+}
+
+func (p *Player) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e := p.play(&Entry{Op: "Ibdev", UD: board, Args: []int{pad, sad, tmo, eot, eos}}); e != nil {
+		return e.Value
+	}
+	return -1
+}
+
+func (p *Player) Ibfind(name string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e := p.play(&Entry{Op: "Ibfind", Name: name}); e != nil {
+		return e.Value
+	}
+	return -1
+}
+
+// simple plays an operation which returns only ibsta.
+func (p *Player) simple(want *Entry) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.play(want)
+	return p.res.Sta
+}
+
+// value plays an operation which returns ibsta and a value.
+func (p *Player) value(want *Entry) (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e := p.play(want); e != nil {
+		return p.res.Sta, e.Value
+	}
+	return p.res.Sta, 0
+}
+
+func (p *Player) Ibonl(ud, v int) int {
+	return p.simple(&Entry{Op: "Ibonl", UD: ud, Args: []int{v}})
+}
+
+func (p *Player) Ibask(ud, option int) (int, int) {
+	return p.value(&Entry{Op: "Ibask", UD: ud, Args: []int{option}})
+}
+
+func (p *Player) Ibconfig(ud, option, value int) int {
+	return p.simple(&Entry{Op: "Ibconfig", UD: ud, Args: []int{option, value}})
+}
+
+func (p *Player) Ibbna(ud int, name string) int {
+	return p.simple(&Entry{Op: "Ibbna", UD: ud, Name: name})
+}
+
+func (p *Player) Ibtmo(ud, v int) int { return p.simple(&Entry{Op: "Ibtmo", UD: ud, Args: []int{v}}) }
+func (p *Player) Ibeot(ud, v int) int { return p.simple(&Entry{Op: "Ibeot", UD: ud, Args: []int{v}}) }
+func (p *Player) Ibeos(ud, v int) int { return p.simple(&Entry{Op: "Ibeos", UD: ud, Args: []int{v}}) }
+
+func (p *Player) Ibrd(ud int, buf []byte) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e := p.play(&Entry{Op: "Ibrd", UD: ud, Args: []int{len(buf)}}); e != nil {
+		copy(buf, e.Data)
+	}
+	return p.res.Sta
+}
+
+func (p *Player) Ibwrt(ud int, buf []byte) int {
+	return p.simple(&Entry{Op: "Ibwrt", UD: ud, Data: buf})
+}
+
+func (p *Player) Ibrdf(ud int, path string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e := p.play(&Entry{Op: "Ibrdf", UD: ud}); e != nil && e.Ibsta&internal.ERR == 0 {
+		if err := appendFile(path, e.Data); err != nil {
+			p.res.Fail(internal.EFSO)
+		}
+	}
+	return p.res.Sta
+}
+
+func (p *Player) Ibwrtf(ud int, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.res.Fail(internal.EFSO)
+	}
+	return p.simple(&Entry{Op: "Ibwrtf", UD: ud, Data: data})
+}
+
+func (p *Player) Ibclr(ud int) int { return p.simple(&Entry{Op: "Ibclr", UD: ud}) }
+func (p *Player) Ibtrg(ud int) int { return p.simple(&Entry{Op: "Ibtrg", UD: ud}) }
+func (p *Player) Ibloc(ud int) int { return p.simple(&Entry{Op: "Ibloc", UD: ud}) }
+
+func (p *Player) Ibrsp(ud int) (int, byte) {
+	ibsta, spr := p.value(&Entry{Op: "Ibrsp", UD: ud})
+	return ibsta, byte(spr)
+}
+
+func (p *Player) Ibwait(ud, mask int) int {
+	return p.simple(&Entry{Op: "Ibwait", UD: ud, Args: []int{mask}})
+}
+
+func (p *Player) Ibcmd(board int, cmd []byte) int {
+	return p.simple(&Entry{Op: "Ibcmd", UD: board, Data: cmd})
+}
+
+func (p *Player) Ibsic(board int) int { return p.simple(&Entry{Op: "Ibsic", UD: board}) }
+func (p *Player) Ibsre(board, v int) int {
+	return p.simple(&Entry{Op: "Ibsre", UD: board, Args: []int{v}})
+}
+func (p *Player) Iblines(board int) (int, int) {
+	return p.value(&Entry{Op: "Iblines", UD: board})
+}
+
+func (p *Player) Ibln(board, pad, sad int) (int, int) {
+	return p.value(&Entry{Op: "Ibln", UD: board, Args: []int{pad, sad}})
+}
+
+func (p *Player) SendList(board int, addrs []linuxgpib.Address, buf []byte, eotmode int) int {
+	return p.simple(&Entry{Op: "SendList", UD: board, Args: sendListArgs(addrs, eotmode), Data: buf})
+}
+
+func (p *Player) Ibsta() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.res.Sta
+}
+
+func (p *Player) Iberr() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.res.Err
+}
+
+func (p *Player) Ibcnt() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.res.Cnt
+}
+
+// appendFile appends data to the named file, creating it if necessary, as
+// ibrdf does.
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
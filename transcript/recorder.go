@@ -0,0 +1,236 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package transcript
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+)
+
+// Recorder is a linuxgpib.Backend which passes operations to another backend,
+// and writes each one as an Entry. It is safe for concurrent use if the
+// backend it wraps is.
+type Recorder struct {
+	be linuxgpib.Backend
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder which passes operations to be and writes
+// them to w.
+func NewRecorder(be linuxgpib.Backend, w io.Writer) *Recorder {
+	return &Recorder{be: be, enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error writing an entry. Operations continue to be
+// passed to the backend after an error.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// do performs an operation, fills in the results of e, and writes it. The
+// operation returns ibsta, which is also returned by do.
+func (r *Recorder) do(e *Entry, op func() int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.Time = time.Now()
+	e.Ibsta = op()
+	e.Duration = time.Since(e.Time)
+	e.Iberr, e.Ibcnt = r.be.Iberr(), r.be.Ibcnt()
+	if r.err == nil {
+		r.err = r.enc.Encode(e)
+	}
+	return e.Ibsta
+}
+
+func (r *Recorder) Ibvers() string {
+	e := &Entry{Op: "Ibvers"}
+	r.do(e, func() int {
+		e.Name = r.be.Ibvers()
+		return r.be.Ibsta()
+	})
+	return e.Name
+}
+
+func (r *Recorder) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	e := &Entry{Op: "Ibdev", UD: board, Args: []int{pad, sad, tmo, eot, eos}}
+	r.do(e, func() int {
+		e.Value = r.be.Ibdev(board, pad, sad, tmo, eot, eos)
+		return r.be.Ibsta()
+	})
+	return e.Value
+}
+
+func (r *Recorder) Ibfind(name string) int {
+	e := &Entry{Op: "Ibfind", Name: name}
+	r.do(e, func() int {
+		e.Value = r.be.Ibfind(name)
+		return r.be.Ibsta()
+	})
+	return e.Value
+}
+
+func (r *Recorder) Ibonl(ud, v int) int {
+	return r.do(&Entry{Op: "Ibonl", UD: ud, Args: []int{v}}, func() int { return r.be.Ibonl(ud, v) })
+}
+
+func (r *Recorder) Ibask(ud, option int) (int, int) {
+	e := &Entry{Op: "Ibask", UD: ud, Args: []int{option}}
+	ibsta := r.do(e, func() (ibsta int) {
+		ibsta, e.Value = r.be.Ibask(ud, option)
+		return ibsta
+	})
+	return ibsta, e.Value
+}
+
+func (r *Recorder) Ibconfig(ud, option, value int) int {
+	return r.do(&Entry{Op: "Ibconfig", UD: ud, Args: []int{option, value}}, func() int { return r.be.Ibconfig(ud, option, value) })
+}
+
+func (r *Recorder) Ibbna(ud int, name string) int {
+	return r.do(&Entry{Op: "Ibbna", UD: ud, Name: name}, func() int { return r.be.Ibbna(ud, name) })
+}
+
+func (r *Recorder) Ibtmo(ud, v int) int {
+	return r.do(&Entry{Op: "Ibtmo", UD: ud, Args: []int{v}}, func() int { return r.be.Ibtmo(ud, v) })
+}
+
+func (r *Recorder) Ibeot(ud, v int) int {
+	return r.do(&Entry{Op: "Ibeot", UD: ud, Args: []int{v}}, func() int { return r.be.Ibeot(ud, v) })
+}
+
+func (r *Recorder) Ibeos(ud, v int) int {
+	return r.do(&Entry{Op: "Ibeos", UD: ud, Args: []int{v}}, func() int { return r.be.Ibeos(ud, v) })
+}
+
+func (r *Recorder) Ibrd(ud int, buf []byte) int {
+	e := &Entry{Op: "Ibrd", UD: ud, Args: []int{len(buf)}}
+	return r.do(e, func() int {
+		ibsta := r.be.Ibrd(ud, buf)
+		if cnt := r.be.Ibcnt(); countValid(ibsta, r.be.Iberr()) && cnt <= len(buf) {
+			e.Data = append(Data(nil), buf[:cnt]...)
+		}
+		return ibsta
+	})
+}
+
+func (r *Recorder) Ibwrt(ud int, buf []byte) int {
+	e := &Entry{Op: "Ibwrt", UD: ud, Data: append(Data(nil), buf...)}
+	return r.do(e, func() int { return r.be.Ibwrt(ud, buf) })
+}
+
+func (r *Recorder) Ibrdf(ud int, path string) int {
+	e := &Entry{Op: "Ibrdf", UD: ud, Name: path}
+	return r.do(e, func() int {
+		// The data is appended, so only record what follows the existing
+		// contents.
+		var size int64
+		if fi, err := os.Stat(path); err == nil {
+			size = fi.Size()
+		}
+		ibsta := r.be.Ibrdf(ud, path)
+		if data, err := os.ReadFile(path); err == nil && int64(len(data)) >= size {
+			e.Data = data[size:]
+		}
+		return ibsta
+	})
+}
+
+func (r *Recorder) Ibwrtf(ud int, path string) int {
+	e := &Entry{Op: "Ibwrtf", UD: ud, Name: path}
+	e.Data, _ = os.ReadFile(path)
+	return r.do(e, func() int { return r.be.Ibwrtf(ud, path) })
+}
+
+func (r *Recorder) Ibclr(ud int) int {
+	return r.do(&Entry{Op: "Ibclr", UD: ud}, func() int { return r.be.Ibclr(ud) })
+}
+
+func (r *Recorder) Ibtrg(ud int) int {
+	return r.do(&Entry{Op: "Ibtrg", UD: ud}, func() int { return r.be.Ibtrg(ud) })
+}
+
+func (r *Recorder) Ibrsp(ud int) (int, byte) {
+	e := &Entry{Op: "Ibrsp", UD: ud}
+	ibsta := r.do(e, func() int {
+		ibsta, spr := r.be.Ibrsp(ud)
+		e.Value = int(spr)
+		return ibsta
+	})
+	return ibsta, byte(e.Value)
+}
+
+func (r *Recorder) Ibloc(ud int) int {
+	return r.do(&Entry{Op: "Ibloc", UD: ud}, func() int { return r.be.Ibloc(ud) })
+}
+
+func (r *Recorder) Ibwait(ud, mask int) int {
+	return r.do(&Entry{Op: "Ibwait", UD: ud, Args: []int{mask}}, func() int { return r.be.Ibwait(ud, mask) })
+}
+
+func (r *Recorder) Ibcmd(board int, cmd []byte) int {
+	e := &Entry{Op: "Ibcmd", UD: board, Data: append(Data(nil), cmd...)}
+	return r.do(e, func() int { return r.be.Ibcmd(board, cmd) })
+}
+
+func (r *Recorder) Ibsic(board int) int {
+	return r.do(&Entry{Op: "Ibsic", UD: board}, func() int { return r.be.Ibsic(board) })
+}
+
+func (r *Recorder) Ibsre(board, v int) int {
+	return r.do(&Entry{Op: "Ibsre", UD: board, Args: []int{v}}, func() int { return r.be.Ibsre(board, v) })
+}
+
+func (r *Recorder) Iblines(board int) (int, int) {
+	e := &Entry{Op: "Iblines", UD: board}
+	ibsta := r.do(e, func() (ibsta int) {
+		ibsta, e.Value = r.be.Iblines(board)
+		return ibsta
+	})
+	return ibsta, e.Value
+}
+
+func (r *Recorder) Ibln(board, pad, sad int) (int, int) {
+	e := &Entry{Op: "Ibln", UD: board, Args: []int{pad, sad}}
+	ibsta := r.do(e, func() (ibsta int) {
+		ibsta, e.Value = r.be.Ibln(board, pad, sad)
+		return ibsta
+	})
+	return ibsta, e.Value
+}
+
+func (r *Recorder) SendList(board int, addrs []linuxgpib.Address, buf []byte, eotmode int) int {
+	e := &Entry{Op: "SendList", UD: board, Args: sendListArgs(addrs, eotmode), Data: append(Data(nil), buf...)}
+	return r.do(e, func() int { return r.be.SendList(board, addrs, buf, eotmode) })
+}
+
+// sendListArgs returns the Args of a SendList entry.
+func sendListArgs(addrs []linuxgpib.Address, eotmode int) []int {
+	args := []int{eotmode}
+	for _, a := range addrs {
+		args = append(args, int(a))
+	}
+	return args
+}
+
+func (r *Recorder) Ibsta() int { return r.be.Ibsta() }
+func (r *Recorder) Iberr() int { return r.be.Iberr() }
+func (r *Recorder) Ibcnt() int { return r.be.Ibcnt() }
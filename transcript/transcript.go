@@ -0,0 +1,102 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package transcript records the GPIB operations of a program, and plays them
+// back without hardware.
+//
+// A Recorder wraps the backend of a board and writes each operation to a file,
+// one JSON object per line:
+//
+//	f, err := os.Create("session.jsonl")
+//	rec := transcript.NewRecorder(linuxgpib.DefaultBackend(), f)
+//	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(rec))
+//
+// A Player reads such a file and serves the recorded results, so that a
+// customer's session can be reproduced in a unit test:
+//
+//	p, err := transcript.NewPlayer(f)
+//	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(p))
+//	// ... run the code under test ...
+//	if err := p.Err(); err != nil {
+//		t.Error(err)
+//	}
+package transcript
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// Entry is a recorded operation.
+type Entry struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"` // in nanoseconds
+	// Op is the name of the Backend method, such as "Ibwrt".
+	Op string `json:"op"`
+	// UD is the device or board descriptor, or the board index for Ibdev.
+	UD int `json:"ud"`
+	// Args are the other integer arguments, in order. For Ibrd it is the size
+	// of the buffer, and for SendList the eotmode followed by the addresses.
+	Args []int `json:"args,omitempty"`
+	// Name is the name passed to Ibfind or Ibbna, the path passed to Ibrdf or
+	// Ibwrtf, or the version returned by Ibvers.
+	Name string `json:"name,omitempty"`
+	// Data is the data written or read, including the contents of the file
+	// for Ibrdf and Ibwrtf.
+	Data Data `json:"data,omitempty"`
+	// Value is the second result of the operation, such as the descriptor
+	// returned by Ibdev or the status byte returned by Ibrsp.
+	Value int `json:"value,omitempty"`
+
+	Ibsta int `json:"ibsta"`
+	Iberr int `json:"iberr,omitempty"`
+	Ibcnt int `json:"ibcnt,omitempty"`
+}
+
+// Data is encoded in JSON as a string if it is valid UTF-8, which is usually
+// the case for instrument messages, and otherwise as an object with the
+// base64 encoding in its "base64" field.
+type Data []byte
+
+func (d Data) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(d) {
+		return json.Marshal(string(d))
+	}
+	return json.Marshal(struct {
+		Base64 []byte `json:"base64"`
+	}{d})
+}
+
+func (d *Data) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*d = Data(s)
+		return nil
+	}
+	var v struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	b, err := base64.StdEncoding.DecodeString(v.Base64)
+	*d = b
+	return err
+}
+
+// countValid reports whether ibcnt is a byte count, rather than an errno.
+func countValid(ibsta, iberr int) bool {
+	return ibsta&internal.ERR == 0 || iberr != internal.EDVR && iberr != internal.EFSO
+}
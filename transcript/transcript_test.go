@@ -0,0 +1,149 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+func TestData(t *testing.T) {
+	for _, tc := range []struct {
+		data Data
+		json string
+	}{
+		{Data("*IDN?\n"), `"*IDN?\n"`},
+		{Data{0x23, 0xff, 0x00}, `{"base64":"I/8A"}`},
+	} {
+		b, err := json.Marshal(tc.data)
+		if err != nil || string(b) != tc.json {
+			t.Errorf("Marshal(%q) = %s, %v; want %s", tc.data, b, err, tc.json)
+		}
+		var got Data
+		if err := json.Unmarshal(b, &got); err != nil || !bytes.Equal(got, tc.data) {
+			t.Errorf("Unmarshal(%s) = %q, %v; want %q", b, got, err, tc.data)
+		}
+	}
+}
+
+// session runs a customer's program, returning what it observed.
+func session(be linuxgpib.Backend, cmd string) string {
+	var out strings.Builder
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		return err.Error()
+	}
+	defer b.Close()
+	d, err := b.NewDevice(22)
+	if err != nil {
+		return err.Error()
+	}
+	defer d.Close()
+	resp, err := d.Query(cmd)
+	fmt.Fprintf(&out, "query %q %v\n", resp, err)
+	stb, err := d.WaitSRQ()
+	fmt.Fprintf(&out, "srq %02x %v\n", stb, err)
+	fmt.Fprintf(&out, "trigger %v\n", d.Trigger())
+	fmt.Fprintf(&out, "clear %v\n", d.Clear())
+	_, err = d.Read(make([]byte, 100))
+	fmt.Fprintf(&out, "read %v\n", err)
+	return out.String()
+}
+
+func TestRecordAndPlay(t *testing.T) {
+	be := sim.New()
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	dmm.RequestService(0x41)
+	be.Attach(22, dmm)
+	var file bytes.Buffer
+	rec := NewRecorder(be, &file)
+	want := session(rec, "*IDN?")
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(want, "ACME") || !strings.Contains(want, "srq 41") || !strings.Contains(want, "read timed out") {
+		t.Fatalf("session against the simulator observed:\n%s", want)
+	}
+	if !strings.Contains(file.String(), `"op":"Ibrsp","ud":16,"value":65`) {
+		t.Errorf("recording lacks the serial poll:\n%s", file.String())
+	}
+
+	p, err := NewPlayer(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := session(p, "*IDN?"); got != want {
+		t.Errorf("replayed session observed:\n%s\nwant:\n%s", got, want)
+	}
+	if err := p.Err(); err != nil {
+		t.Error(err)
+	}
+	if n := p.Remaining(); n != 0 {
+		t.Errorf("%d entries remain after replay", n)
+	}
+
+	// A program which deviates from the recording is caught.
+	p, err = NewPlayer(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session(p, "*OPC?")
+	if err := p.Err(); err == nil || !strings.Contains(err.Error(), `"*OPC?\n"`) {
+		t.Errorf("Err after a different query = %v; want a mismatch", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	be := sim.New()
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	dmm.Respond("DATA?", "1,2,3")
+	be.Attach(22, dmm)
+	dir := t.TempDir()
+	in := dir + "/in"
+	if err := os.WriteFile(in, []byte("DATA?\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	run := func(be linuxgpib.Backend, out string) {
+		d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(be))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		if _, err := d.WriteFromFile(in); err != nil {
+			t.Error(err)
+		}
+		if _, err := d.ReadToFile(out); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var file bytes.Buffer
+	run(NewRecorder(be, &file), dir+"/recorded")
+	p, err := NewPlayer(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(p, dir+"/played")
+	if err := p.Err(); err != nil {
+		t.Error(err)
+	}
+	if got, _ := os.ReadFile(dir + "/played"); string(got) != "1,2,3\n" {
+		t.Errorf("replayed file = %q; want the recorded response", got)
+	}
+}
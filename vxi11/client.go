@@ -0,0 +1,902 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package vxi11
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+)
+
+// Commands for device_docmd on a gateway's interface link, from VXI-11.2.
+const (
+	docmdSendCommand = 0x020000
+	docmdBusStatus   = 0x020001
+	docmdRENControl  = 0x020003
+	docmdIFCControl  = 0x020010
+
+	busStatusSRQ = 2
+)
+
+const (
+	// slack is added to the I/O timeout of a call when waiting for its reply.
+	slack = 5 * time.Second
+	// clientChunk is the size of each transfer in Ibrdf.
+	clientChunk = 64 * 1024
+)
+
+// Client is a linuxgpib.Backend for the devices behind a VXI-11 LAN/GPIB
+// gateway. Device descriptors are links to names such as "gpib0,22", and
+// board descriptors are the gateway's GPIB interfaces. It is safe for
+// concurrent use.
+//
+// Interface operations such as Ibsic and Ibcmd use the gateway's interface
+// link, if it has one. Otherwise Ibcmd is emulated for the commands which
+// have an equivalent link operation: addressing a device as a listener puts it
+// in remote, GTL, SDC and GET address its link, and DCL clears all open links
+// on the board. REN is controlled by the gateway if it cannot be controlled
+// through the interface link. Service requests are detected by polling the
+// status byte.
+//
+// If a reply does not arrive in full within the I/O timeout plus some slack,
+// the connection to the gateway is closed, and every later operation fails
+// with EDVR and errno ENOTCONN. A new Client is needed to continue.
+type Client struct {
+	mu   sync.Mutex
+	res  internal.Result
+	host string
+	conn net.Conn
+	core *rpcClient
+	intf map[int]int32 // interface links by board, or -1 if unavailable
+
+	devMu     sync.Mutex // guards the fields below, so Abort need not wait for mu
+	devices   map[int]*clientDevice
+	next      int
+	abort     *rpcClient
+	abortPort int
+}
+
+// clientDevice is an open device descriptor.
+type clientDevice struct {
+	board   int
+	addr    linuxgpib.Address
+	lid     int32
+	maxRecv uint32
+	tmo     int
+	eot     int
+	eos     int
+	stb     int // status byte obtained by Ibwait, or -1
+}
+
+// Dial connects to the core channel of a gateway. If addr includes a port it
+// is used directly, otherwise the port is obtained from the gateway's
+// portmapper.
+func Dial(addr string) (*Client, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+		p, err := lookupPort(net.JoinHostPort(host, strconv.Itoa(PortmapPort)))
+		if err != nil {
+			return nil, err
+		}
+		port = strconv.Itoa(p)
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		host:    host,
+		conn:    conn,
+		core:    newRPCClient(conn, coreProg, coreVers),
+		intf:    map[int]int32{},
+		devices: map[int]*clientDevice{},
+		next:    internal.GPIB_MAX_NUM_BOARDS,
+	}, nil
+}
+
+// lookupPort asks the portmapper at addr for the TCP port of the core
+// channel.
+func lookupPort(addr string) (int, error) {
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(slack))
+	pm := newRPCClient(conn, portmapProg, portmapVers)
+	w := &xdrWriter{}
+	w.uint(coreProg)
+	w.uint(coreVers)
+	w.uint(protoTCP)
+	w.uint(0)
+	r, err := pm.call(procPortmapGet, w.b)
+	if err != nil {
+		return 0, fmt.Errorf("vxi11: portmapper: %v", err)
+	}
+	port := r.uint()
+	if r.err != nil || port == 0 || port > math.MaxUint16 {
+		return 0, errors.New("vxi11: core channel is not registered with the portmapper")
+	}
+	return int(port), nil
+}
+
+// Close closes the connections to the gateway, which destroys the open links.
+func (c *Client) Close() error {
+	c.devMu.Lock()
+	if c.abort != nil {
+		c.abort.Close()
+	}
+	c.devMu.Unlock()
+	return c.core.Close()
+}
+
+// call invokes a core channel procedure, waiting for up to the I/O timeout
+// plus some slack for the reply.
+func (c *Client) call(proc uint32, ioTimeout uint32, args *xdrWriter) (*xdrReader, error) {
+	wait := time.Duration(ioTimeout)*time.Millisecond + slack
+	if ioTimeout == math.MaxUint32 {
+		c.conn.SetDeadline(time.Time{})
+	} else {
+		c.conn.SetDeadline(time.Now().Add(wait))
+	}
+	return c.core.call(proc, args.b)
+}
+
+// fail records an error, and returns ibsta.
+func (c *Client) fail(err error) int {
+	var e Error
+	if errors.As(err, &e) {
+		switch e {
+		case ErrTimeout:
+			return c.res.Timeout(0)
+		case ErrAbort:
+			return c.res.Fail(internal.EABO)
+		case ErrNotSupported, ErrNoChannel:
+			return c.res.Fail(internal.ECAP)
+		case ErrSyntax, ErrParameter, ErrInvalidLink, ErrInvalidAddress:
+			return c.res.Fail(internal.EARG)
+		case ErrNotAccessible:
+			return c.res.FailErrno(int(syscall.ENODEV))
+		case ErrNoResources:
+			return c.res.FailErrno(int(syscall.ENOMEM))
+		case ErrLocked, ErrNoLock:
+			return c.res.FailErrno(int(syscall.EBUSY))
+		default:
+			return c.res.FailErrno(int(syscall.EIO))
+		}
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return c.res.FailErrno(int(syscall.ETIMEDOUT))
+	}
+	return c.res.FailErrno(int(syscall.ENOTCONN))
+}
+
+// ioTimeout returns a timeout constant in milliseconds, for which TNONE is
+// unlimited.
+func ioTimeout(tmo int) uint32 {
+	if tmo == internal.TNONE {
+		return math.MaxUint32
+	}
+	return uint32(internal.Duration(tmo).Milliseconds())
+}
+
+// deviceName returns the name of a device on a board, in the form accepted
+// by ParseDevice.
+func deviceName(board int, addr linuxgpib.Address) string {
+	s := fmt.Sprintf("gpib%d,%d", board, addr.Primary())
+	if sad := addr.Secondary(); sad != 0 {
+		s += fmt.Sprintf(",%d", sad-0x60)
+	}
+	return s
+}
+
+// createLink opens a link to a device or interface.
+func (c *Client) createLink(name string) (lid int32, maxRecv, abortPort uint32, err error) {
+	w := &xdrWriter{}
+	w.int(int32(os.Getpid()))
+	w.bool(false)
+	w.uint(0)
+	w.string(name)
+	r, err := c.call(procCreateLink, 0, w)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	e, lid, abortPort, maxRecv := Error(r.uint()), r.int(), r.uint(), r.uint()
+	if r.err != nil {
+		return 0, 0, 0, r.err
+	}
+	if e != 0 {
+		return 0, 0, 0, e
+	}
+	return lid, maxRecv, abortPort, nil
+}
+
+// destroyLink closes a link.
+func (c *Client) destroyLink(lid int32) error {
+	w := &xdrWriter{}
+	w.int(lid)
+	r, err := c.call(procDestroyLink, 0, w)
+	if err != nil {
+		return err
+	}
+	if e := Error(r.uint()); e != 0 {
+		return e
+	}
+	return r.err
+}
+
+// generic invokes a procedure which takes Device_GenericParms, and returns
+// the status byte for device_readstb.
+func (c *Client) generic(proc uint32, d *clientDevice) (byte, error) {
+	tmo := ioTimeout(d.tmo)
+	w := &xdrWriter{}
+	w.int(d.lid)
+	w.uint(0)
+	w.uint(0)
+	w.uint(tmo)
+	r, err := c.call(proc, tmo, w)
+	if err != nil {
+		return 0, err
+	}
+	if e := Error(r.uint()); e != 0 {
+		return 0, e
+	}
+	var stb uint32
+	if proc == procReadStb {
+		stb = r.uint()
+	}
+	return byte(stb), r.err
+}
+
+// interfaceLink returns the link to a board, opening it if needed.
+func (c *Client) interfaceLink(board int) (int32, error) {
+	lid, ok := c.intf[board]
+	if !ok {
+		var err error
+		lid, _, _, err = c.createLink(fmt.Sprintf("gpib%d", board))
+		if err != nil {
+			var e Error
+			if !errors.As(err, &e) {
+				return 0, err
+			}
+			lid = -1
+		}
+		c.intf[board] = lid
+	}
+	if lid < 0 {
+		return 0, ErrNotSupported
+	}
+	return lid, nil
+}
+
+// docmd performs a command on a board's interface link.
+func (c *Client) docmd(board int, cmd int32, data []byte) ([]byte, error) {
+	lid, err := c.interfaceLink(board)
+	if err != nil {
+		return nil, err
+	}
+	const tmo = 10000
+	w := &xdrWriter{}
+	w.int(lid)
+	w.uint(0)
+	w.uint(tmo)
+	w.uint(0)
+	w.int(cmd)
+	w.bool(true)
+	w.int(int32(len(data)))
+	w.opaque(data)
+	r, err := c.call(procDocmd, tmo, w)
+	if err != nil {
+		return nil, err
+	}
+	e, out := Error(r.uint()), r.opaque(maxRecord)
+	if e != 0 {
+		return nil, e
+	}
+	return out, r.err
+}
+
+// short encodes a value for docmd in network order.
+func short(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+// device returns an open device, or nil if the descriptor is invalid.
+func (c *Client) device(ud int) *clientDevice {
+	c.devMu.Lock()
+	defer c.devMu.Unlock()
+	d := c.devices[ud]
+	if d == nil {
+		c.res.Fail(internal.EARG)
+	}
+	return d
+}
+
+// Abort aborts an operation in progress on a device, through the gateway's
+// abort channel. Unlike the other methods, it does not wait for the
+// operation, which fails with EABO. It implements linuxgpib.Aborter, so that
+// Device.Abort can be used.
+func (c *Client) Abort(ud int) error {
+	c.devMu.Lock()
+	d := c.devices[ud]
+	if d == nil {
+		c.devMu.Unlock()
+		return fmt.Errorf("vxi11: invalid device descriptor %d", ud)
+	}
+	lid := d.lid
+	if c.abort == nil {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(c.host, strconv.Itoa(c.abortPort)), 10*time.Second)
+		if err != nil {
+			c.devMu.Unlock()
+			return err
+		}
+		c.abort = newRPCClient(conn, abortProg, abortVers)
+	}
+	abort := c.abort
+	c.devMu.Unlock()
+
+	w := &xdrWriter{}
+	w.int(lid)
+	r, err := abort.call(procDeviceAbort, w.b)
+	if err != nil {
+		return err
+	}
+	if e := Error(r.uint()); e != 0 {
+		return e
+	}
+	return r.err
+}
+
+func (c *Client) Ibvers() string {
+	return "vxi11"
+}
+
+func (c *Client) Ibdev(board, pad, sad, tmo, eot, eos int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	addr, err := linuxgpib.NewAddress(pad, sad)
+	if err != nil {
+		c.res.Fail(internal.EARG)
+		return -1
+	}
+	lid, maxRecv, abortPort, err := c.createLink(deviceName(board, addr))
+	if err != nil {
+		c.fail(err)
+		return -1
+	}
+	c.devMu.Lock()
+	defer c.devMu.Unlock()
+	c.abortPort = int(abortPort)
+	ud := c.next
+	c.next++
+	c.devices[ud] = &clientDevice{
+		board:   board,
+		addr:    addr,
+		lid:     lid,
+		maxRecv: maxRecv,
+		tmo:     tmo,
+		eot:     eot,
+		eos:     eos,
+		stb:     -1,
+	}
+	c.res.Done(0, 0)
+	return ud
+}
+
+func (c *Client) Ibfind(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.res.FailErrno(int(syscall.ENOENT))
+	return -1
+}
+
+func (c *Client) Ibonl(ud, v int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ud < internal.GPIB_MAX_NUM_BOARDS {
+		return c.res.Done(0, 0)
+	}
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta
+	}
+	if v == 0 {
+		c.devMu.Lock()
+		delete(c.devices, ud)
+		c.devMu.Unlock()
+		if err := c.destroyLink(d.lid); err != nil {
+			return c.fail(err)
+		}
+	}
+	return c.res.Done(0, 0)
+}
+
+func (c *Client) Ibask(ud, option int) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta, 0
+	}
+	var v int
+	switch option {
+	case internal.IbaPAD:
+		v = d.addr.Primary()
+	case internal.IbaSAD:
+		v = d.addr.Secondary()
+	case internal.IbaTMO:
+		v = d.tmo
+	case internal.IbaEOT:
+		v = d.eot
+	case internal.IbaEOSrd:
+		v = d.eos & internal.REOS
+	case internal.IbaEOSchar:
+		v = d.eos & 0xff
+	case internal.IbaBNA:
+		v = d.board
+	default:
+		return c.res.Fail(internal.ECAP), 0
+	}
+	return c.res.Done(0, 0), v
+}
+
+func (c *Client) Ibconfig(ud, option, value int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta
+	}
+	switch option {
+	case internal.IbcTMO:
+		d.tmo = value
+	case internal.IbcEOT:
+		d.eot = value
+	default:
+		return c.res.Fail(internal.ECAP)
+	}
+	return c.res.Done(0, 0)
+}
+
+func (c *Client) Ibbna(ud int, name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.res.FailErrno(int(syscall.ENOENT))
+}
+
+func (c *Client) Ibtmo(ud, v int) int {
+	return c.Ibconfig(ud, internal.IbcTMO, v)
+}
+
+func (c *Client) Ibeot(ud, v int) int {
+	return c.Ibconfig(ud, internal.IbcEOT, v)
+}
+
+func (c *Client) Ibeos(ud, v int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta
+	}
+	d.eos = v
+	return c.res.Done(0, 0)
+}
+
+// Ibrd reads once from the device. The read ends with END if the device
+// asserted EOI or sent the EOS character.
+func (c *Client) Ibrd(ud int, buf []byte) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta
+	}
+	tmo := ioTimeout(d.tmo)
+	var flags uint32
+	if d.eos&internal.REOS != 0 {
+		flags |= flagTermChrSet
+	}
+	w := &xdrWriter{}
+	w.int(d.lid)
+	w.uint(uint32(min(len(buf), math.MaxInt32)))
+	w.uint(tmo)
+	w.uint(0)
+	w.uint(flags)
+	w.uint(uint32(d.eos & 0xff))
+	r, err := c.call(procDeviceRead, tmo, w)
+	if err != nil {
+		return c.fail(err)
+	}
+	e, reason, data := Error(r.uint()), r.uint(), r.opaque(len(buf))
+	if r.err != nil {
+		return c.fail(r.err)
+	}
+	if e != 0 {
+		return c.fail(e)
+	}
+	n := copy(buf, data)
+	if reason&(reasonEnd|reasonChr) != 0 {
+		return c.res.Done(internal.END, n)
+	}
+	return c.res.Done(0, n)
+}
+
+// Ibwrt writes to the device in pieces no larger than the gateway accepts,
+// with the END flag on the last if the device's EOT mode is set.
+func (c *Client) Ibwrt(ud int, buf []byte) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta
+	}
+	n, err := c.write(d, buf)
+	if err != nil {
+		c.fail(err)
+		c.res.Cnt = n
+		return c.res.Sta
+	}
+	return c.res.Done(0, n)
+}
+
+// write sends data to a device, and returns the number of bytes accepted.
+func (c *Client) write(d *clientDevice, buf []byte) (int, error) {
+	tmo := ioTimeout(d.tmo)
+	chunk := int(d.maxRecv)
+	if chunk <= 0 || chunk > maxRecord/2 {
+		chunk = maxRecord / 2
+	}
+	n := 0
+	for {
+		p := buf[n:min(n+chunk, len(buf))]
+		var flags uint32
+		if n+len(p) == len(buf) && d.eot != 0 {
+			flags |= flagEnd
+		}
+		w := &xdrWriter{}
+		w.int(d.lid)
+		w.uint(tmo)
+		w.uint(0)
+		w.uint(flags)
+		w.opaque(p)
+		r, err := c.call(procDeviceWrite, tmo, w)
+		if err != nil {
+			return n, err
+		}
+		e, size := Error(r.uint()), r.uint()
+		if r.err != nil {
+			return n, r.err
+		}
+		if size > uint32(len(p)) {
+			return n, errors.New("vxi11: gateway wrote more than it was sent")
+		}
+		n += int(size)
+		if e != 0 {
+			return n, e
+		}
+		if n == len(buf) {
+			return n, nil
+		}
+	}
+}
+
+func (c *Client) Ibrdf(ud int, path string) int {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return c.fileErr(err)
+	}
+	defer f.Close()
+	buf := make([]byte, clientChunk)
+	total := 0
+	for {
+		ibsta := c.Ibrd(ud, buf)
+		n := c.Ibcnt()
+		if ibsta&internal.ERR != 0 {
+			return ibsta
+		}
+		if _, err := f.Write(buf[:n]); err != nil {
+			return c.fileErr(err)
+		}
+		total += n
+		if ibsta&internal.END != 0 {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.res.Done(internal.END, total)
+		}
+	}
+}
+
+func (c *Client) Ibwrtf(ud int, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c.fileErr(err)
+	}
+	return c.Ibwrt(ud, data)
+}
+
+// fileErr records a file system error, and returns ibsta.
+func (c *Client) fileErr(err error) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	errno := syscall.EIO
+	errors.As(err, &errno)
+	c.res.Sta, c.res.Err, c.res.Cnt = internal.ERR|internal.CMPL, internal.EFSO, int(errno)
+	return c.res.Sta
+}
+
+// op performs a generic operation on a device, and returns ibsta.
+func (c *Client) op(ud int, proc uint32) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta
+	}
+	if proc == procClear {
+		d.stb = -1
+	}
+	if _, err := c.generic(proc, d); err != nil {
+		return c.fail(err)
+	}
+	return c.res.Done(0, 0)
+}
+
+func (c *Client) Ibclr(ud int) int {
+	return c.op(ud, procClear)
+}
+
+func (c *Client) Ibtrg(ud int) int {
+	return c.op(ud, procTrigger)
+}
+
+func (c *Client) Ibloc(ud int) int {
+	return c.op(ud, procLocal)
+}
+
+func (c *Client) Ibrsp(ud int) (int, byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta, 0
+	}
+	if d.stb >= 0 {
+		stb := byte(d.stb)
+		d.stb = -1
+		return c.res.Done(0, 0), stb
+	}
+	stb, err := c.generic(procReadStb, d)
+	if err != nil {
+		return c.fail(err), 0
+	}
+	return c.res.Done(0, 0), stb
+}
+
+// Ibwait returns the current status without waiting. RQS is detected by
+// reading the status byte, which is kept for the next Ibrsp. If the mask
+// includes a condition which is not met, the status includes TIMO.
+func (c *Client) Ibwait(ud, mask int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.device(ud)
+	if d == nil {
+		return c.res.Sta
+	}
+	if d.stb < 0 {
+		stb, err := c.generic(procReadStb, d)
+		if err != nil {
+			return c.fail(err)
+		}
+		if stb&0x40 != 0 {
+			d.stb = int(stb)
+		}
+	}
+	ibsta := 0
+	if d.stb >= 0 {
+		ibsta |= internal.RQS
+	}
+	if mask&^internal.TIMO != 0 && ibsta&mask == 0 {
+		ibsta |= internal.TIMO
+	}
+	return c.res.Done(ibsta, 0)
+}
+
+// Ibcmd sends commands through the interface link, or emulates them with link
+// operations if the gateway has none.
+func (c *Client) Ibcmd(board int, cmd []byte) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.docmd(board, docmdSendCommand, cmd)
+	if errors.Is(err, ErrNotSupported) {
+		err = c.emulateCommands(board, cmd)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	return c.res.Done(0, len(cmd))
+}
+
+// emulateCommands performs the link operations equivalent to commands.
+func (c *Client) emulateCommands(board int, cmd []byte) error {
+	c.devMu.Lock()
+	links := map[linuxgpib.Address]*clientDevice{}
+	for _, d := range c.devices {
+		if d.board == board {
+			links[d.addr] = d
+		}
+	}
+	c.devMu.Unlock()
+
+	var listeners []linuxgpib.Address
+	addressed := false // whether listeners have been given a command
+	apply := func(proc uint32) error {
+		addressed = true
+		for _, a := range listeners {
+			d := links[a]
+			if d == nil {
+				return fmt.Errorf("vxi11: no link to address %v", a)
+			}
+			if _, err := c.generic(proc, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// Addressing a device to listen while REN is asserted puts it in remote.
+	unlisten := func() error {
+		if !addressed {
+			if err := apply(procRemote); err != nil {
+				return err
+			}
+		}
+		listeners, addressed = nil, false
+		return nil
+	}
+	for _, b := range cmd {
+		var err error
+		switch cm := linuxgpib.Command(b); {
+		case cm == linuxgpib.UNL:
+			err = unlisten()
+		case cm == linuxgpib.UNT, b&0x60 == internal.TAD:
+		case b&0x60 == internal.LAD:
+			listeners = append(listeners, linuxgpib.Address(b&0x1f))
+			addressed = false
+		case b&0x60 == internal.SAD && len(listeners) > 0:
+			last := &listeners[len(listeners)-1]
+			*last, err = linuxgpib.NewAddress(last.Primary(), int(b))
+		case cm == linuxgpib.GTL:
+			err = apply(procLocal)
+		case cm == linuxgpib.SDC:
+			err = apply(procClear)
+		case cm == linuxgpib.GET:
+			err = apply(procTrigger)
+		case cm == linuxgpib.DCL:
+			for _, d := range links {
+				if _, err = c.generic(procClear, d); err != nil {
+					break
+				}
+			}
+		default:
+			err = ErrNotSupported
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return unlisten()
+}
+
+func (c *Client) Ibsic(board int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.docmd(board, docmdIFCControl, nil); err != nil {
+		return c.fail(err)
+	}
+	return c.res.Done(0, 0)
+}
+
+// Ibsre controls REN through the interface link, and otherwise succeeds
+// without effect, leaving REN to the gateway.
+func (c *Client) Ibsre(board, v int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.docmd(board, docmdRENControl, short(uint16(min(v, 1))))
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		return c.fail(err)
+	}
+	return c.res.Done(0, 0)
+}
+
+// Iblines reports only the SRQ line, obtained through the interface link. If
+// the gateway has no interface link, no lines are reported as valid.
+func (c *Client) Iblines(board int) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out, err := c.docmd(board, docmdBusStatus, short(busStatusSRQ))
+	if errors.Is(err, ErrNotSupported) {
+		return c.res.Done(0, 0), 0
+	}
+	if err != nil {
+		return c.fail(err), 0
+	}
+	lines := internal.ValidSRQ
+	if len(out) >= 2 && binary.BigEndian.Uint16(out) != 0 {
+		lines |= internal.BusSRQ
+	}
+	return c.res.Done(0, 0), lines
+}
+
+func (c *Client) Ibln(board, pad, sad int) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.res.Fail(internal.ECAP), 0
+}
+
+// SendList opens a link to each address in turn, and writes to it.
+func (c *Client) SendList(board int, addrs []linuxgpib.Address, buf []byte, eotmode int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if eotmode == internal.NLend {
+		buf = append(buf[:len(buf):len(buf)], '\n')
+	}
+	d := &clientDevice{board: board, tmo: internal.T10s}
+	if eotmode != internal.NULLend {
+		d.eot = 1
+	}
+	for _, a := range addrs {
+		lid, maxRecv, _, err := c.createLink(deviceName(board, a))
+		if err != nil {
+			return c.fail(err)
+		}
+		d.lid, d.maxRecv = lid, maxRecv
+		_, err = c.write(d, buf)
+		if derr := c.destroyLink(lid); err == nil {
+			err = derr
+		}
+		if err != nil {
+			return c.fail(err)
+		}
+	}
+	return c.res.Done(0, len(buf))
+}
+
+func (c *Client) Ibsta() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.res.Sta
+}
+
+func (c *Client) Iberr() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.res.Err
+}
+
+func (c *Client) Ibcnt() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.res.Cnt
+}
+
+var _ linuxgpib.Backend = (*Client)(nil)
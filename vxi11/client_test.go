@@ -0,0 +1,332 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package vxi11
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/internal"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+// clientErr returns the error of the last call to c.
+func clientErr(c *Client) error {
+	return internal.ErrFrom(c.Ibsta(), c.Iberr, c.Ibcnt)
+}
+
+func TestClient(t *testing.T) {
+	portmap, core, dmm := testServer(t)
+	port, err := lookupPort(portmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, want, _ := net.SplitHostPort(core); fmt.Sprint(port) != want {
+		t.Errorf("lookupPort = %d; want %s", port, want)
+	}
+
+	// The client is used directly rather than through a Device, so that a call
+	// to it does not wait for the server's call.
+	c, err := Dial(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if ud := c.Ibdev(1, 5, 0, internal.T1s, 1, 0); ud >= 0 {
+		t.Error("Ibdev succeeded for a board the server does not have")
+	}
+	ud := c.Ibdev(0, 22, 0, internal.T1s, 1, 0)
+	if ud < 0 {
+		t.Fatalf("Ibdev failed: %v", clientErr(c))
+	}
+
+	if ibsta := c.Ibwrt(ud, []byte("*IDN?\n")); ibsta&internal.ERR != 0 {
+		t.Fatalf("Ibwrt failed: %v", clientErr(c))
+	}
+	buf := make([]byte, 8)
+	var got []byte
+	for {
+		ibsta := c.Ibrd(ud, buf)
+		if ibsta&internal.ERR != 0 {
+			t.Fatalf("Ibrd failed: %v", clientErr(c))
+		}
+		got = append(got, buf[:c.Ibcnt()]...)
+		if ibsta&internal.END != 0 {
+			break
+		}
+	}
+	if string(got) != "ACME,DMM,0,1.0\n" {
+		t.Errorf("read %q", got)
+	}
+
+	// A query with no response times out.
+	c.Ibwrt(ud, []byte("BOGUS?\n"))
+	if ibsta := c.Ibrd(ud, buf); ibsta&internal.TIMO == 0 {
+		t.Errorf("Ibrd = %s, want TIMO", internal.FormatIbsta(ibsta))
+	}
+
+	if ibsta := c.Ibtrg(ud); ibsta&internal.ERR != 0 {
+		t.Errorf("Ibtrg failed: %v", clientErr(c))
+	}
+	if ibsta := c.Ibclr(ud); ibsta&internal.ERR != 0 {
+		t.Errorf("Ibclr failed: %v", clientErr(c))
+	}
+	if ibsta := c.Ibloc(ud); ibsta&internal.ERR != 0 {
+		t.Errorf("Ibloc failed: %v", clientErr(c))
+	}
+	if n := dmm.Triggers(); n != 1 {
+		t.Errorf("triggers = %d, want 1", n)
+	}
+	if n := dmm.Clears(); n != 1 {
+		t.Errorf("clears = %d, want 1", n)
+	}
+
+	dmm.RequestService(0x41)
+	if ibsta := c.Ibwait(ud, internal.RQS); ibsta&internal.RQS == 0 {
+		t.Errorf("Ibwait = %s, want RQS", internal.FormatIbsta(ibsta))
+	}
+	if ibsta, stb := c.Ibrsp(ud); ibsta&internal.ERR != 0 || stb != 0x41 {
+		t.Errorf("Ibrsp = %#x, %v", stb, clientErr(c))
+	}
+
+	// The server has no interface link, so REN is left alone and commands
+	// are emulated with link operations.
+	if ibsta := c.Ibsre(0, 1); ibsta&internal.ERR != 0 {
+		t.Errorf("Ibsre failed: %v", clientErr(c))
+	}
+	lad, err := linuxgpib.Listen(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds := linuxgpib.Commands{linuxgpib.UNL, lad, linuxgpib.GET, linuxgpib.UNL, lad, linuxgpib.UNL}
+	if ibsta := c.Ibcmd(0, cmds.Bytes()); ibsta&internal.ERR != 0 {
+		t.Errorf("Ibcmd(%v) failed: %v", cmds, clientErr(c))
+	}
+	if n := dmm.Triggers(); n != 2 {
+		t.Errorf("triggers = %d, want 2", n)
+	}
+	if ibsta := c.Ibcmd(0, []byte{byte(linuxgpib.LLO)}); ibsta&internal.ERR == 0 || c.Iberr() != internal.ECAP {
+		t.Errorf("Ibcmd(LLO) = %s, want ECAP", internal.FormatIbsta(ibsta))
+	}
+
+	if ibsta := c.SendList(0, []linuxgpib.Address{22}, []byte("*CLS"), internal.NLend); ibsta&internal.ERR != 0 {
+		t.Errorf("SendList failed: %v", clientErr(c))
+	}
+	if r := dmm.Received(); r[len(r)-1] != "*CLS" {
+		t.Errorf("received %q, want *CLS last", r)
+	}
+
+	if ibsta := c.Ibonl(ud, 0); ibsta&internal.ERR != 0 {
+		t.Errorf("Ibonl failed: %v", clientErr(c))
+	}
+	if ibsta := c.Ibrd(ud, buf); ibsta&internal.ERR == 0 || c.Iberr() != internal.EARG {
+		t.Errorf("Ibrd after Ibonl = %s, want EARG", internal.FormatIbsta(ibsta))
+	}
+}
+
+func TestRPCClientPartialReply(t *testing.T) {
+	cc, sc := net.Pipe()
+	defer sc.Close()
+	c := newRPCClient(cc, coreProg, coreVers)
+	defer c.Close()
+
+	// The server starts a reply of 100 bytes, but stops part-way.
+	go func() {
+		if _, err := readRecord(sc); err != nil {
+			return
+		}
+		h := binary.BigEndian.AppendUint32(nil, 100|lastFragment)
+		sc.Write(append(h, make([]byte, 10)...))
+	}()
+	cc.SetDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := c.call(procDeviceRead, nil); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("call got error %v; want a deadline error", err)
+	}
+
+	// The rest of the stream cannot be followed, so later calls fail without
+	// reading it.
+	cc.SetDeadline(time.Time{})
+	if _, err := c.call(procDeviceRead, nil); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("call after partial reply got error %v; want a lost connection", err)
+	}
+}
+
+// fakeGateway serves the core and abort channels for one instrument at every
+// address, without an interface link. Unlike Server, it does not use the
+// linuxgpib package, so that a Client can be used through a Device in the same
+// process. An abort makes a read in progress fail, without waiting for the
+// instrument.
+func fakeGateway(t *testing.T, in sim.Instrument) string {
+	listen := func() net.Listener {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		return l
+	}
+	lc, la := listen(), listen()
+
+	var mu sync.Mutex
+	aborted := make(chan struct{}) // closed by an abort, then replaced
+	handleAbort := func(proc uint32, args *xdrReader, res *xdrWriter) uint32 {
+		mu.Lock()
+		close(aborted)
+		aborted = make(chan struct{})
+		mu.Unlock()
+		res.uint(0)
+		return acceptSuccess
+	}
+	handle := func(proc uint32, args *xdrReader, res *xdrWriter) uint32 {
+		switch proc {
+		case procCreateLink:
+			args.int()
+			args.bool()
+			args.uint()
+			if _, _, err := ParseDevice(args.string(maxDeviceName)); err != nil {
+				res.uint(uint32(ErrInvalidAddress))
+			} else {
+				res.uint(0)
+			}
+			res.int(1)
+			res.uint(uint32(la.Addr().(*net.TCPAddr).Port))
+			res.uint(defaultRecvSize)
+		case procDeviceWrite:
+			args.int()
+			args.uint()
+			args.uint()
+			flags := args.uint()
+			data := args.opaque(maxRecord)
+			if flags&flagEnd != 0 {
+				in.Receive(data)
+			}
+			res.uint(0)
+			res.uint(uint32(len(data)))
+		case procDeviceRead:
+			mu.Lock()
+			abort := aborted
+			mu.Unlock()
+			sent := make(chan []byte, 1)
+			go func() { sent <- in.Send() }()
+			var data []byte
+			select {
+			case data = <-sent:
+			case <-abort:
+				res.uint(uint32(ErrAbort))
+				res.uint(0)
+				res.opaque(nil)
+				return acceptSuccess
+			}
+			if data == nil {
+				res.uint(uint32(ErrTimeout))
+			} else {
+				res.uint(0)
+			}
+			res.uint(reasonEnd)
+			res.opaque(data)
+		case procClear:
+			in.Clear()
+			res.uint(0)
+		case procDestroyLink:
+			res.uint(0)
+		default:
+			res.uint(uint32(ErrNotSupported))
+		}
+		return acceptSuccess
+	}
+	go accept(la, func(conn net.Conn) {
+		serveRPC(conn, map[rpcKey]rpcProgram{{abortProg, abortVers}: handleAbort})
+	})
+	go accept(lc, func(conn net.Conn) {
+		serveRPC(conn, map[rpcKey]rpcProgram{{coreProg, coreVers}: handle})
+	})
+	return lc.Addr().String()
+}
+
+func TestClientDevice(t *testing.T) {
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	c, err := Dial(fakeGateway(t, dmm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	d, err := b.NewDevice(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if got, err := d.Query("*IDN?"); err != nil || got != "ACME,DMM,0,1.0" {
+		t.Errorf("Query = %q, %v", got, err)
+	}
+	// The server has no interface link, so no lines are known and clearing
+	// the device falls back to a fixed delay.
+	if err := d.Clear(); err != nil {
+		t.Errorf("Clear failed: %v", err)
+	}
+	if n := dmm.Clears(); n != 1 {
+		t.Errorf("clears = %d, want 1", n)
+	}
+	if _, err := b.Lines(); err != nil {
+		t.Errorf("Lines failed: %v", err)
+	}
+}
+
+func TestClientAbort(t *testing.T) {
+	in := &stalled{sim.NewSCPI("ACME,DMM,0,1.0"), make(chan struct{}, 1), make(chan struct{})}
+	c, err := Dial(fakeGateway(t, in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var once sync.Once
+	release := func() { once.Do(func() { close(in.release) }) }
+	defer release()
+	d, err := linuxgpib.NewDevice(0, 22, linuxgpib.UseBackend(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if _, err := d.Write([]byte("*IDN?\n")); err != nil {
+		t.Fatal(err)
+	}
+	read := make(chan error)
+	go func() {
+		_, err := d.Read(make([]byte, 100))
+		read <- err
+	}()
+	<-in.waiting
+	if err := d.Abort(); err != nil {
+		t.Errorf("Abort failed: %v", err)
+	}
+	select {
+	case err := <-read:
+		if !errors.Is(err, &internal.Error{Iberr: internal.EABO}) {
+			t.Errorf("aborted Read got error %v; want EABO", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read still blocked after Abort")
+	}
+}
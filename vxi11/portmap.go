@@ -0,0 +1,52 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package vxi11
+
+import (
+	"net"
+)
+
+// Portmap is a minimal portmapper, which answers GETPORT requests over TCP for
+// the VXI-11 core channel only.
+type Portmap struct {
+	// CorePort is the TCP port of the core channel.
+	CorePort int
+}
+
+// Serve accepts connections on l and serves each in a new goroutine. It
+// returns when l is closed.
+func (p *Portmap) Serve(l net.Listener) error {
+	progs := map[rpcKey]rpcProgram{
+		{portmapProg, portmapVers}: p.handle,
+	}
+	return accept(l, func(conn net.Conn) {
+		serveRPC(conn, progs)
+	})
+}
+
+func (p *Portmap) handle(proc uint32, args *xdrReader, res *xdrWriter) uint32 {
+	switch proc {
+	case procPortmapNull:
+		return acceptSuccess
+	case procPortmapGet:
+		prog, vers, prot := args.uint(), args.uint(), args.uint()
+		args.uint() // port, which is ignored
+		port := 0
+		if prog == coreProg && vers == coreVers && prot == protoTCP {
+			port = p.CorePort
+		}
+		res.uint(uint32(port))
+		return acceptSuccess
+	default:
+		return acceptProcUnavail
+	}
+}
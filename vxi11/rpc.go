@@ -0,0 +1,256 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package vxi11
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ONC RPC message fields, from RFC 5531.
+const (
+	rpcVersion = 2
+
+	msgCall  = 0
+	msgReply = 1
+
+	replyAccepted = 0
+	replyDenied   = 1
+
+	acceptSuccess      = 0
+	acceptProgUnavail  = 1
+	acceptProgMismatch = 2
+	acceptProcUnavail  = 3
+	acceptGarbageArgs  = 4
+
+	rejectRPCMismatch = 0
+
+	authNone = 0
+
+	// lastFragment marks the final fragment of a record.
+	lastFragment = 1 << 31
+	// maxRecord limits the size of a received record.
+	maxRecord = 16 << 20
+)
+
+// readRecord reads a record, which may be split into several fragments.
+func readRecord(r io.Reader) ([]byte, error) {
+	var rec []byte
+	for {
+		var h [4]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return nil, err
+		}
+		v := binary.BigEndian.Uint32(h[:])
+		n := int(v &^ lastFragment)
+		if len(rec)+n > maxRecord {
+			return nil, errors.New("rpc: record too large")
+		}
+		start := len(rec)
+		rec = append(rec, make([]byte, n)...)
+		if _, err := io.ReadFull(r, rec[start:]); err != nil {
+			return nil, err
+		}
+		if v&lastFragment != 0 {
+			return rec, nil
+		}
+	}
+}
+
+// writeRecord writes a record as a single fragment.
+func writeRecord(w io.Writer, rec []byte) error {
+	b := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(rec)), uint32(len(rec))|lastFragment)
+	_, err := w.Write(append(b, rec...))
+	return err
+}
+
+// rpcProgram handles the procedures of one version of a program. It decodes
+// the arguments from args and encodes the results to res, returning an accept
+// status such as acceptSuccess.
+type rpcProgram func(proc uint32, args *xdrReader, res *xdrWriter) uint32
+
+// rpcKey identifies a version of a program.
+type rpcKey struct {
+	prog, vers uint32
+}
+
+// serveRPC handles calls on a stream connection until it is closed or an
+// error occurs. Each call is handled before the next is read.
+func serveRPC(conn io.ReadWriter, progs map[rpcKey]rpcProgram) error {
+	for {
+		rec, err := readRecord(conn)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		reply, ok := handleCall(rec, progs)
+		if !ok {
+			continue
+		}
+		if err := writeRecord(conn, reply); err != nil {
+			return err
+		}
+	}
+}
+
+// handleCall decodes a call message and returns the reply, or false if the
+// message should be ignored.
+func handleCall(rec []byte, progs map[rpcKey]rpcProgram) ([]byte, bool) {
+	r := &xdrReader{b: rec}
+	xid := r.uint()
+	if r.uint() != msgCall || r.err != nil {
+		return nil, false
+	}
+	rpcvers := r.uint()
+	prog, vers, proc := r.uint(), r.uint(), r.uint()
+	// Credentials and verifier are accepted without checking.
+	r.uint()
+	r.opaque(400)
+	r.uint()
+	r.opaque(400)
+	if r.err != nil {
+		return nil, false
+	}
+
+	w := &xdrWriter{}
+	w.uint(xid)
+	w.uint(msgReply)
+	if rpcvers != rpcVersion {
+		w.uint(replyDenied)
+		w.uint(rejectRPCMismatch)
+		w.uint(rpcVersion)
+		w.uint(rpcVersion)
+		return w.b, true
+	}
+	w.uint(replyAccepted)
+	w.uint(authNone)
+	w.opaque(nil)
+
+	p, ok := progs[rpcKey{prog, vers}]
+	if !ok {
+		// Report the supported versions if the program exists.
+		low, high := ^uint32(0), uint32(0)
+		for k := range progs {
+			if k.prog == prog {
+				low, high = min(low, k.vers), max(high, k.vers)
+			}
+		}
+		if high == 0 {
+			w.uint(acceptProgUnavail)
+		} else {
+			w.uint(acceptProgMismatch)
+			w.uint(low)
+			w.uint(high)
+		}
+		return w.b, true
+	}
+
+	res := &xdrWriter{}
+	stat := p(proc, r, res)
+	if stat == acceptSuccess && r.err != nil {
+		stat = acceptGarbageArgs
+	}
+	w.uint(stat)
+	if stat == acceptSuccess {
+		w.b = append(w.b, res.b...)
+	}
+	return w.b, true
+}
+
+// rpcClient makes calls to a program over a stream connection, one at a time.
+// If a record cannot be written or read in full, for example because a
+// deadline passes part-way through a reply, the stream can no longer be
+// followed: the connection is closed, and later calls fail.
+type rpcClient struct {
+	mu     sync.Mutex
+	conn   io.ReadWriteCloser
+	prog   uint32
+	vers   uint32
+	xid    uint32
+	broken error
+}
+
+func newRPCClient(conn io.ReadWriteCloser, prog, vers uint32) *rpcClient {
+	return &rpcClient{conn: conn, prog: prog, vers: vers}
+}
+
+func (c *rpcClient) Close() error {
+	return c.conn.Close()
+}
+
+// call invokes a procedure with the encoded arguments, and returns a reader
+// for the results.
+func (c *rpcClient) call(proc uint32, args []byte) (*xdrReader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken != nil {
+		return nil, c.broken
+	}
+	c.xid++
+	xid := c.xid
+
+	w := &xdrWriter{}
+	w.uint(xid)
+	w.uint(msgCall)
+	w.uint(rpcVersion)
+	w.uint(c.prog)
+	w.uint(c.vers)
+	w.uint(proc)
+	w.uint(authNone)
+	w.opaque(nil)
+	w.uint(authNone)
+	w.opaque(nil)
+	w.b = append(w.b, args...)
+	if err := writeRecord(c.conn, w.b); err != nil {
+		return nil, c.fail(err)
+	}
+
+	for {
+		rec, err := readRecord(c.conn)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+		r := &xdrReader{b: rec}
+		if r.uint() != xid {
+			// A reply to an earlier call which was abandoned.
+			continue
+		}
+		if r.uint() != msgReply {
+			return nil, c.fail(errors.New("rpc: expected a reply"))
+		}
+		if r.uint() != replyAccepted {
+			return nil, errors.New("rpc: call denied")
+		}
+		r.uint()
+		r.opaque(400)
+		if stat := r.uint(); stat != acceptSuccess {
+			return nil, fmt.Errorf("rpc: call failed with status %d", stat)
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		return r, nil
+	}
+}
+
+// fail gives up on the connection after an error which leaves the stream out
+// of step, and returns the error.
+func (c *rpcClient) fail(err error) error {
+	c.broken = fmt.Errorf("rpc: connection lost after error: %v", err)
+	c.conn.Close()
+	return err
+}
@@ -0,0 +1,706 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package vxi11
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+)
+
+// deviceKey identifies a device address on a board.
+type deviceKey struct {
+	board int
+	addr  linuxgpib.Address
+}
+
+// device is a GPIB device shared by all links to it.
+type device struct {
+	d    *linuxgpib.Device // nil until opened, and if opening fails
+	key  deviceKey
+	refs int // guarded by Server.mu
+
+	// opened is closed once d has been set, or opening it has failed.
+	opened chan struct{}
+	// closing is set, guarded by Server.mu, once the last link has been
+	// destroyed, and closed when d has been closed and the device removed.
+	closing chan struct{}
+
+	// owner holds the lock, and released is closed when it lets go. Both are
+	// guarded by Server.mu.
+	owner    *link
+	released chan struct{}
+
+	mu  sync.Mutex // serializes operations, so each uses its own timeout
+	tmo time.Duration
+}
+
+// link is a client's connection to a device.
+type link struct {
+	id   int32
+	dev  *device
+	conn *coreConn // the connection which created the link
+
+	pending   []byte // written without the END flag
+	unread    []byte // read from the device but not yet returned
+	unreadEnd bool   // whether unread was terminated by END
+
+	// abort is closed to abort the operation in progress on the link, if
+	// any. It is guarded by Server.mu.
+	abort chan struct{}
+}
+
+// Server serves GPIB devices over the VXI-11 core and abort channels.
+//
+// Data written without the END flag is held until a write with END, or the
+// next read, and then sent to the device in a single message. An abort on the
+// abort channel makes the operation in progress on a link fail at once with
+// ErrAbort, but the bus transfer itself runs until it completes or times out,
+// and holds up later operations on the device until then. Data it reads is
+// kept for the next read. Service requests are not supported. A link may only
+// be used on the core channel connection which created it.
+type Server struct {
+	boards map[int]*linuxgpib.Board
+	logger linuxgpib.Logger
+
+	mu        sync.Mutex
+	abortPort int
+	nextID    int32
+	links     map[int32]*link
+	devices   map[deviceKey]*device
+}
+
+// NewServer returns a server for devices on the given boards, which are keyed
+// by the board number in device names. The logger may be nil.
+func NewServer(boards map[int]*linuxgpib.Board, logger linuxgpib.Logger) *Server {
+	return &Server{
+		boards:  boards,
+		logger:  logger,
+		links:   map[int32]*link{},
+		devices: map[deviceKey]*device{},
+	}
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.logger != nil {
+		s.logger.Printf(format, v...)
+	}
+}
+
+// Serve accepts core channel connections on core and abort channel
+// connections on abort, and serves each in a new goroutine. It returns when
+// core is closed. Links are destroyed when the connection on which they were
+// created is closed.
+func (s *Server) Serve(core, abort net.Listener) error {
+	if a, ok := abort.Addr().(*net.TCPAddr); ok {
+		s.mu.Lock()
+		s.abortPort = a.Port
+		s.mu.Unlock()
+	}
+	abortProgs := map[rpcKey]rpcProgram{
+		{abortProg, abortVers}: s.handleAbort,
+	}
+	go accept(abort, func(conn net.Conn) {
+		serveRPC(conn, abortProgs)
+	})
+	return accept(core, s.serveCore)
+}
+
+// accept accepts connections on l and serves each in a new goroutine, closing
+// it afterwards. It returns when l is closed.
+func accept(l net.Listener, serve func(net.Conn)) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			serve(conn)
+		}()
+	}
+}
+
+// coreConn is a core channel connection. Links may only be used on the
+// connection which created them.
+type coreConn struct {
+	links []int32 // created on the connection, which handles calls in turn
+}
+
+func (s *Server) serveCore(conn net.Conn) {
+	c := &coreConn{}
+	handle := func(proc uint32, args *xdrReader, res *xdrWriter) uint32 {
+		if proc == procCreateLink {
+			return s.createLink(c, args, res)
+		}
+		return s.handleCore(c, proc, args, res)
+	}
+	err := serveRPC(conn, map[rpcKey]rpcProgram{{coreProg, coreVers}: handle})
+	if err != nil {
+		s.logf("Core channel from %v failed: %v", conn.RemoteAddr(), err)
+	}
+	for _, id := range c.links {
+		s.destroyLink(c, id)
+	}
+}
+
+func (s *Server) createLink(c *coreConn, args *xdrReader, res *xdrWriter) uint32 {
+	args.int() // client ID
+	lockDevice := args.bool()
+	lockTimeout := args.uint()
+	name := args.string(maxDeviceName)
+	if args.err != nil {
+		return acceptGarbageArgs
+	}
+
+	fail := func(e Error) uint32 {
+		res.uint(uint32(e))
+		res.int(0)
+		res.uint(0)
+		res.uint(0)
+		return acceptSuccess
+	}
+	board, addr, err := ParseDevice(name)
+	if err != nil {
+		s.logf("Refused link: %v", err)
+		return fail(ErrInvalidAddress)
+	}
+	b := s.boards[board]
+	if b == nil {
+		s.logf("Refused link to %q: no such board", name)
+		return fail(ErrInvalidAddress)
+	}
+
+	dv := s.openDevice(b, deviceKey{board, addr})
+	if dv == nil {
+		return fail(ErrNotAccessible)
+	}
+	s.mu.Lock()
+	s.nextID++
+	l := &link{id: s.nextID, dev: dv, conn: c}
+	s.links[l.id] = l
+	c.links = append(c.links, l.id)
+	abortPort := s.abortPort
+	s.mu.Unlock()
+	s.logf("Created link %d to %q", l.id, name)
+
+	if lockDevice {
+		if e := s.lock(l, flagWaitLock, lockTimeout); e != 0 {
+			s.destroyLink(c, l.id)
+			return fail(e)
+		}
+	}
+
+	res.uint(0)
+	res.int(l.id)
+	res.uint(uint32(abortPort))
+	res.uint(defaultRecvSize)
+	return acceptSuccess
+}
+
+// openDevice returns the device at key with a reference taken for a new link,
+// opening it if no other link uses it. It returns nil if the device cannot be
+// opened. The device is opened without holding s.mu, so that other links are
+// not held up meanwhile. If the device is being closed, which waits for any
+// aborted operation still on the bus, it is reopened once that is done.
+func (s *Server) openDevice(b *linuxgpib.Board, k deviceKey) *device {
+	for {
+		s.mu.Lock()
+		dv := s.devices[k]
+		if dv == nil {
+			dv = &device{key: k, opened: make(chan struct{})}
+			s.devices[k] = dv
+			s.mu.Unlock()
+			d, err := b.NewDevice(k.addr)
+			s.mu.Lock()
+			if err != nil {
+				delete(s.devices, k)
+				s.logf("Failed to open address %v on board %d: %v", k.addr, k.board, err)
+			} else {
+				dv.d = d
+				dv.refs++
+			}
+			close(dv.opened)
+			s.mu.Unlock()
+			if err != nil {
+				return nil
+			}
+			return dv
+		}
+		if closing := dv.closing; closing != nil {
+			s.mu.Unlock()
+			<-closing
+			continue
+		}
+		s.mu.Unlock()
+
+		<-dv.opened
+		s.mu.Lock()
+		if s.devices[k] == dv && dv.closing == nil {
+			dv.refs++
+			s.mu.Unlock()
+			return dv
+		}
+		// Opening failed, or the device is being closed by its last link,
+		// which the next iteration waits for.
+		failed := dv.d == nil
+		s.mu.Unlock()
+		if failed {
+			return nil
+		}
+	}
+}
+
+// destroyLink releases a link and its lock, and closes its device if no other
+// link uses it.
+func (s *Server) destroyLink(c *coreConn, id int32) Error {
+	s.mu.Lock()
+	l := s.links[id]
+	if l == nil || l.conn != c {
+		s.mu.Unlock()
+		return ErrInvalidLink
+	}
+	delete(s.links, id)
+	dv := l.dev
+	if dv.owner == l {
+		s.unlockLocked(dv)
+	}
+	dv.refs--
+	last := dv.refs == 0
+	if last {
+		dv.closing = make(chan struct{})
+	}
+	s.mu.Unlock()
+
+	// Closing the device waits for any operation in progress on the bus, so
+	// it is done without holding s.mu. The device stays in s.devices until
+	// then, so that a new link to it waits rather than finding it in use.
+	if last {
+		if err := dv.d.Close(); err != nil {
+			s.logf("Failed to close device for link %d: %v", id, err)
+		}
+		s.mu.Lock()
+		delete(s.devices, dv.key)
+		close(dv.closing)
+		s.mu.Unlock()
+	}
+	s.logf("Destroyed link %d", id)
+	return 0
+}
+
+// getLink returns the link with the given ID if it was created on the
+// connection, or nil.
+func (s *Server) getLink(c *coreConn, id int32) *link {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l := s.links[id]; l != nil && l.conn == c {
+		return l
+	}
+	return nil
+}
+
+// waitUnlocked waits until no other link holds the lock on the link's device.
+// It waits for up to lockTimeout milliseconds if flags include waitlock.
+func (s *Server) waitUnlocked(l *link, flags, lockTimeout uint32) Error {
+	deadline := time.Now().Add(time.Duration(lockTimeout) * time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		dv := l.dev
+		if dv.owner == nil || dv.owner == l {
+			return 0
+		}
+		remaining := time.Until(deadline)
+		if flags&flagWaitLock == 0 || remaining <= 0 {
+			return ErrLocked
+		}
+		released := dv.released
+		s.mu.Unlock()
+		t := time.NewTimer(remaining)
+		select {
+		case <-released:
+		case <-t.C:
+		}
+		t.Stop()
+		s.mu.Lock()
+	}
+}
+
+// lock acquires the lock on the link's device.
+func (s *Server) lock(l *link, flags, lockTimeout uint32) Error {
+	for {
+		if e := s.waitUnlocked(l, flags, lockTimeout); e != 0 {
+			return e
+		}
+		s.mu.Lock()
+		dv := l.dev
+		if dv.owner == nil {
+			dv.owner = l
+			dv.released = make(chan struct{})
+		}
+		won := dv.owner == l
+		s.mu.Unlock()
+		if won {
+			return 0
+		}
+	}
+}
+
+// unlockLocked releases the lock on a device. The caller must hold s.mu.
+func (s *Server) unlockLocked(dv *device) {
+	dv.owner = nil
+	close(dv.released)
+	dv.released = nil
+}
+
+// ioError converts an error from the device to a VXI-11 error code.
+func ioError(err error) Error {
+	var t interface{ Timeout() bool }
+	if errors.As(err, &t) && t.Timeout() {
+		return ErrTimeout
+	}
+	return ErrIO
+}
+
+// setTimeout changes the device's timeout if needed. The caller must hold
+// dv.mu.
+func (dv *device) setTimeout(ms uint32) error {
+	tmo := max(time.Duration(ms)*time.Millisecond, time.Millisecond)
+	if tmo == dv.tmo {
+		return nil
+	}
+	if err := dv.d.SetTimeout(tmo); err != nil {
+		return err
+	}
+	dv.tmo = tmo
+	return nil
+}
+
+// flush sends data held from writes without END. The caller must hold dv.mu.
+func (l *link) flush() error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+	_, err := l.dev.d.Write(l.pending)
+	l.pending = nil
+	return err
+}
+
+func (s *Server) handleCore(c *coreConn, proc uint32, args *xdrReader, res *xdrWriter) uint32 {
+	switch proc {
+	case procDeviceWrite:
+		id, ioTimeout, lockTimeout, flags := args.int(), args.uint(), args.uint(), args.uint()
+		data := args.opaque(maxRecord)
+		if args.err != nil {
+			return acceptGarbageArgs
+		}
+		n, e := s.write(c, id, ioTimeout, lockTimeout, flags, data)
+		res.uint(uint32(e))
+		res.uint(uint32(n))
+
+	case procDeviceRead:
+		id, size, ioTimeout, lockTimeout, flags, termChar := args.int(), args.uint(), args.uint(), args.uint(), args.uint(), args.uint()
+		if args.err != nil {
+			return acceptGarbageArgs
+		}
+		data, reason, e := s.read(c, id, size, ioTimeout, lockTimeout, flags, byte(termChar))
+		res.uint(uint32(e))
+		res.uint(reason)
+		res.opaque(data)
+
+	case procReadStb, procTrigger, procClear, procRemote, procLocal:
+		id, flags, lockTimeout, ioTimeout := args.int(), args.uint(), args.uint(), args.uint()
+		if args.err != nil {
+			return acceptGarbageArgs
+		}
+		stb, e := s.generic(c, proc, id, flags, lockTimeout, ioTimeout)
+		res.uint(uint32(e))
+		if proc == procReadStb {
+			res.uint(uint32(stb))
+		}
+
+	case procLock:
+		id, flags, lockTimeout := args.int(), args.uint(), args.uint()
+		if args.err != nil {
+			return acceptGarbageArgs
+		}
+		e := ErrInvalidLink
+		if l := s.getLink(c, id); l != nil {
+			e = s.lock(l, flags, lockTimeout)
+		}
+		res.uint(uint32(e))
+
+	case procUnlock:
+		id := args.int()
+		if args.err != nil {
+			return acceptGarbageArgs
+		}
+		res.uint(uint32(s.unlock(c, id)))
+
+	case procEnableSRQ:
+		id, enable := args.int(), args.bool()
+		args.opaque(maxHandle)
+		if args.err != nil {
+			return acceptGarbageArgs
+		}
+		e := ErrInvalidLink
+		if s.getLink(c, id) != nil {
+			e = 0
+			if enable {
+				e = ErrNoChannel
+			}
+		}
+		res.uint(uint32(e))
+
+	case procDocmd:
+		res.uint(uint32(ErrNotSupported))
+		res.opaque(nil)
+
+	case procDestroyLink:
+		id := args.int()
+		if args.err != nil {
+			return acceptGarbageArgs
+		}
+		res.uint(uint32(s.destroyLink(c, id)))
+
+	case procCreateIntr, procDestroyIntr:
+		res.uint(uint32(ErrNotSupported))
+
+	default:
+		return acceptProcUnavail
+	}
+	return acceptSuccess
+}
+
+func (s *Server) handleAbort(proc uint32, args *xdrReader, res *xdrWriter) uint32 {
+	if proc != procDeviceAbort {
+		return acceptProcUnavail
+	}
+	id := args.int()
+	if args.err != nil {
+		return acceptGarbageArgs
+	}
+	// The abort channel is a separate connection, so any link may be named.
+	s.mu.Lock()
+	e := ErrInvalidLink
+	if l := s.links[id]; l != nil {
+		e = 0
+		if l.abort != nil {
+			close(l.abort)
+			l.abort = nil
+		}
+	}
+	s.mu.Unlock()
+	res.uint(uint32(e))
+	return acceptSuccess
+}
+
+// abortable runs op, which uses the link's device, and returns its error. If
+// the operation is aborted meanwhile, it returns ErrAbort without waiting for
+// op, which runs on in the background.
+func (s *Server) abortable(l *link, op func() Error) Error {
+	abort := make(chan struct{})
+	s.mu.Lock()
+	l.abort = abort
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if l.abort == abort {
+			l.abort = nil
+		}
+		s.mu.Unlock()
+	}()
+
+	done := make(chan Error, 1)
+	go func() { done <- op() }()
+	select {
+	case e := <-done:
+		return e
+	case <-abort:
+		return ErrAbort
+	}
+}
+
+func (s *Server) write(c *coreConn, id int32, ioTimeout, lockTimeout, flags uint32, data []byte) (int, Error) {
+	l := s.getLink(c, id)
+	if l == nil {
+		return 0, ErrInvalidLink
+	}
+	if e := s.waitUnlocked(l, flags, lockTimeout); e != 0 {
+		return 0, e
+	}
+	e := s.abortable(l, func() Error {
+		dv := l.dev
+		dv.mu.Lock()
+		defer dv.mu.Unlock()
+		if len(l.pending)+len(data) > maxBufferedWrite {
+			l.pending = nil
+			return ErrNoResources
+		}
+		l.pending = append(l.pending, data...)
+		if flags&flagEnd == 0 {
+			return 0
+		}
+		if err := dv.setTimeout(ioTimeout); err != nil {
+			return ErrIO
+		}
+		if err := l.flush(); err != nil {
+			return ioError(err)
+		}
+		return 0
+	})
+	if e != 0 {
+		return 0, e
+	}
+	return len(data), 0
+}
+
+func (s *Server) read(c *coreConn, id int32, size, ioTimeout, lockTimeout, flags uint32, termChar byte) ([]byte, uint32, Error) {
+	l := s.getLink(c, id)
+	if l == nil {
+		return nil, 0, ErrInvalidLink
+	}
+	if e := s.waitUnlocked(l, flags, lockTimeout); e != 0 {
+		return nil, 0, e
+	}
+	// The device is read into l.unread, so that if the read is aborted, what
+	// it reads is kept for the next one. A read which fails part-way returns
+	// what was read with the error.
+	var rerr Error
+	e := s.abortable(l, func() Error {
+		dv := l.dev
+		dv.mu.Lock()
+		defer dv.mu.Unlock()
+		if err := dv.setTimeout(ioTimeout); err != nil {
+			return ErrIO
+		}
+		if err := l.flush(); err != nil {
+			return ioError(err)
+		}
+		if size == 0 || len(l.unread) != 0 {
+			return 0
+		}
+		buf := make([]byte, min(size, defaultRecvSize))
+		n, end, err := dv.d.ReadEnd(buf)
+		if err != nil {
+			rerr = ioError(err)
+			if n == 0 {
+				return rerr
+			}
+		}
+		l.unread, l.unreadEnd = buf[:n], end
+		return 0
+	})
+	if e != 0 {
+		return nil, 0, e
+	}
+	if size == 0 {
+		return nil, reasonReqCnt, 0
+	}
+
+	dv := l.dev
+	dv.mu.Lock()
+	defer dv.mu.Unlock()
+
+	// Stop at the termination character, which the device may not have been
+	// configured to stop at, and keep the rest for the next read.
+	data := l.unread
+	termChrSet := flags&flagTermChrSet != 0
+	if termChrSet {
+		if i := bytes.IndexByte(data, termChar); i >= 0 {
+			data = data[:i+1]
+		}
+	}
+	if len(data) > int(size) {
+		data = data[:size]
+	}
+	l.unread = l.unread[len(data):]
+
+	var reason uint32
+	if len(data) == int(size) {
+		reason |= reasonReqCnt
+	}
+	if termChrSet && len(data) > 0 && data[len(data)-1] == termChar {
+		reason |= reasonChr
+	}
+	if len(l.unread) == 0 {
+		l.unread = nil
+		if l.unreadEnd {
+			reason |= reasonEnd
+		}
+	}
+	return data, reason, rerr
+}
+
+// generic performs the operations which take Device_GenericParms.
+func (s *Server) generic(c *coreConn, proc uint32, id int32, flags, lockTimeout, ioTimeout uint32) (byte, Error) {
+	l := s.getLink(c, id)
+	if l == nil {
+		return 0, ErrInvalidLink
+	}
+	if e := s.waitUnlocked(l, flags, lockTimeout); e != 0 {
+		return 0, e
+	}
+	var stb byte
+	e := s.abortable(l, func() Error {
+		dv := l.dev
+		dv.mu.Lock()
+		defer dv.mu.Unlock()
+		if err := dv.setTimeout(ioTimeout); err != nil {
+			return ErrIO
+		}
+
+		var err error
+		switch proc {
+		case procReadStb:
+			stb, err = dv.d.Spoll()
+		case procTrigger:
+			err = dv.d.Trigger()
+		case procClear:
+			l.pending, l.unread = nil, nil
+			err = dv.d.Clear()
+		case procRemote:
+			err = dv.d.Remote()
+		case procLocal:
+			err = dv.d.Local()
+		}
+		if err != nil {
+			return ioError(err)
+		}
+		return 0
+	})
+	if e != 0 {
+		return 0, e
+	}
+	return stb, 0
+}
+
+func (s *Server) unlock(c *coreConn, id int32) Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.links[id]
+	if l == nil || l.conn != c {
+		return ErrInvalidLink
+	}
+	if l.dev.owner != l {
+		return ErrNoLock
+	}
+	s.unlockLocked(l.dev)
+	return 0
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// Package vxi11 implements the VXI-11 protocol, with which network instruments
+// and LAN/GPIB gateways are controlled over ONC RPC.
+//
+// A Server makes GPIB devices available as network instruments. Clients open a
+// link to a device by a name of the form "gpib0,22", or "gpib0,5,3" for
+// primary address 5 and secondary address 3. Since clients first ask a
+// portmapper for the port of the core channel, the server also provides a
+// minimal portmapper for hosts which do not run one.
+//
+// A Client does the reverse, making the devices behind a LAN/GPIB gateway
+// available to the linuxgpib package through the UseBackend option.
+package vxi11
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/msiegen/linuxgpib"
+)
+
+// RPC program numbers and versions, from the VXI-11 specification and RFC
+// 1833.
+const (
+	coreProg    = 0x0607af
+	coreVers    = 1
+	abortProg   = 0x0607b0
+	abortVers   = 1
+	portmapProg = 100000
+	portmapVers = 2
+
+	// PortmapPort is the standard port of the portmapper.
+	PortmapPort = 111
+)
+
+// Core channel procedures.
+const (
+	procCreateLink   = 10
+	procDeviceWrite  = 11
+	procDeviceRead   = 12
+	procReadStb      = 13
+	procTrigger      = 14
+	procClear        = 15
+	procRemote       = 16
+	procLocal        = 17
+	procLock         = 18
+	procUnlock       = 19
+	procEnableSRQ    = 20
+	procDocmd        = 22
+	procDestroyLink  = 23
+	procCreateIntr   = 25
+	procDestroyIntr  = 26
+	procDeviceAbort  = 1 // on the abort channel
+	procPortmapNull  = 0
+	procPortmapGet   = 3
+	protoTCP         = 6
+	maxDeviceName    = 256
+	maxHandle        = 40
+	defaultRecvSize  = 1 << 20
+	maxBufferedWrite = 16 << 20
+)
+
+// Operation flags.
+const (
+	flagWaitLock   = 0x01
+	flagEnd        = 0x08
+	flagTermChrSet = 0x80
+)
+
+// Read termination reasons.
+const (
+	reasonReqCnt = 0x01
+	reasonChr    = 0x02
+	reasonEnd    = 0x04
+)
+
+// Error is a VXI-11 error code.
+type Error uint32
+
+// Error codes from the VXI-11 specification.
+const (
+	ErrSyntax         Error = 1
+	ErrNotAccessible  Error = 3
+	ErrInvalidLink    Error = 4
+	ErrParameter      Error = 5
+	ErrNoChannel      Error = 6
+	ErrNotSupported   Error = 8
+	ErrNoResources    Error = 9
+	ErrLocked         Error = 11
+	ErrNoLock         Error = 12
+	ErrTimeout        Error = 15
+	ErrIO             Error = 17
+	ErrInvalidAddress Error = 21
+	ErrAbort          Error = 23
+	ErrChannelExists  Error = 29
+)
+
+var errorStrings = map[Error]string{
+	ErrSyntax:         "syntax error",
+	ErrNotAccessible:  "device not accessible",
+	ErrInvalidLink:    "invalid link identifier",
+	ErrParameter:      "parameter error",
+	ErrNoChannel:      "channel not established",
+	ErrNotSupported:   "operation not supported",
+	ErrNoResources:    "out of resources",
+	ErrLocked:         "device locked by another link",
+	ErrNoLock:         "no lock held by this link",
+	ErrTimeout:        "I/O timeout",
+	ErrIO:             "I/O error",
+	ErrInvalidAddress: "invalid address",
+	ErrAbort:          "abort",
+	ErrChannelExists:  "channel already established",
+}
+
+func (e Error) Error() string {
+	if s, ok := errorStrings[e]; ok {
+		return "vxi11: " + s
+	}
+	return fmt.Sprintf("vxi11: error %d", uint32(e))
+}
+
+// Timeout reports whether the error is an I/O timeout.
+func (e Error) Timeout() bool {
+	return e == ErrTimeout
+}
+
+// ParseDevice parses a device name of the form "gpib0,22" or "gpib0,5,3",
+// where the secondary address is in the range 0 to 30.
+func ParseDevice(name string) (board int, addr linuxgpib.Address, err error) {
+	parts := strings.Split(name, ",")
+	intf := strings.ToLower(parts[0])
+	if !strings.HasPrefix(intf, "gpib") || len(parts) < 2 || len(parts) > 3 {
+		return 0, 0, fmt.Errorf("invalid device name %q: want gpibN,PRIMARY[,SECONDARY]", name)
+	}
+	board, err = strconv.Atoi(intf[len("gpib"):])
+	if err != nil || board < 0 {
+		return 0, 0, fmt.Errorf("invalid device name %q: bad board number", name)
+	}
+	s := parts[1]
+	if len(parts) == 3 {
+		s += "." + parts[2]
+	}
+	addr, err = linuxgpib.ParseAddress(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid device name %q: %v", name, err)
+	}
+	return board, addr, nil
+}
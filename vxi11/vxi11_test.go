@@ -0,0 +1,452 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package vxi11
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/msiegen/linuxgpib"
+	"github.com/msiegen/linuxgpib/fault"
+	"github.com/msiegen/linuxgpib/sim"
+)
+
+func TestParseDevice(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		board int
+		addr  linuxgpib.Address
+		ok    bool
+	}{
+		{"gpib0,22", 0, 22, true},
+		{"GPIB1,5,3", 1, 5 | 0x63<<8, true},
+		{"inst0", 0, 0, false},
+		{"gpib0", 0, 0, false},
+		{"gpibx,22", 0, 0, false},
+		{"gpib0,31", 0, 0, false},
+		{"gpib0,5,31", 0, 0, false},
+	} {
+		board, addr, err := ParseDevice(tc.in)
+		if (err == nil) != tc.ok || board != tc.board || addr != tc.addr {
+			t.Errorf("ParseDevice(%q) = %d, %v, %v; want %d, %v, ok=%v", tc.in, board, addr, err, tc.board, tc.addr, tc.ok)
+		}
+	}
+}
+
+// testServer serves a simulated board and returns the addresses of the
+// portmapper and core channel, and the instrument at address 22.
+func testServer(t *testing.T) (portmap, core string, dmm *sim.SCPI) {
+	be := sim.New()
+	dmm = sim.NewSCPI("ACME,DMM,0,1.0")
+	be.Attach(22, dmm)
+	portmap, core, _ = serveBackend(t, be)
+	return portmap, core, dmm
+}
+
+// serveBackend serves board 0 of a backend and returns the addresses of the
+// portmapper and core channel, and the server.
+func serveBackend(t *testing.T, be linuxgpib.Backend) (portmap, core string, s *Server) {
+	b, err := linuxgpib.NewBoard(0, linuxgpib.UseBackend(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+
+	listen := func() net.Listener {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		return l
+	}
+	lc, la, lp := listen(), listen(), listen()
+	s = NewServer(map[int]*linuxgpib.Board{0: b}, nil)
+	go s.Serve(lc, la)
+	pm := &Portmap{CorePort: lc.Addr().(*net.TCPAddr).Port}
+	go pm.Serve(lp)
+	return lp.Addr().String(), lc.Addr().String(), s
+}
+
+// testLink is a link created by raw calls on the core channel.
+type testLink struct {
+	t         *testing.T
+	c         *rpcClient
+	id        int32
+	abortPort uint32
+}
+
+func dialLink(t *testing.T, core, name string) (*testLink, Error) {
+	conn, err := net.Dial("tcp", core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newRPCClient(conn, coreProg, coreVers)
+	t.Cleanup(func() { c.Close() })
+	w := &xdrWriter{}
+	w.int(1)
+	w.bool(false)
+	w.uint(0)
+	w.string(name)
+	r, err := c.call(procCreateLink, w.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, id, abortPort := Error(r.uint()), r.int(), r.uint()
+	return &testLink{t, c, id, abortPort}, e
+}
+
+func (l *testLink) call(proc uint32, w *xdrWriter) *xdrReader {
+	l.t.Helper()
+	r, err := l.c.call(proc, w.b)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return r
+}
+
+func (l *testLink) write(data string, flags uint32) Error {
+	w := &xdrWriter{}
+	w.int(l.id)
+	w.uint(1000)
+	w.uint(0)
+	w.uint(flags)
+	w.string(data)
+	return Error(l.call(procDeviceWrite, w).uint())
+}
+
+func (l *testLink) read(size uint32, flags uint32, termChar byte) (string, uint32, Error) {
+	w := &xdrWriter{}
+	w.int(l.id)
+	w.uint(size)
+	w.uint(1000)
+	w.uint(0)
+	w.uint(flags)
+	w.uint(uint32(termChar))
+	r := l.call(procDeviceRead, w)
+	e, reason := Error(r.uint()), r.uint()
+	return string(r.opaque(maxRecord)), reason, e
+}
+
+func (l *testLink) generic(proc uint32) (Error, uint32) {
+	w := &xdrWriter{}
+	w.int(l.id)
+	w.uint(0)
+	w.uint(0)
+	w.uint(1000)
+	r := l.call(proc, w)
+	e := Error(r.uint())
+	var stb uint32
+	if proc == procReadStb {
+		stb = r.uint()
+	}
+	return e, stb
+}
+
+// abort calls device_abort for the link on the abort channel.
+func (l *testLink) abort() Error {
+	l.t.Helper()
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", l.abortPort))
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	c := newRPCClient(conn, abortProg, abortVers)
+	defer c.Close()
+	w := &xdrWriter{}
+	w.int(l.id)
+	r, err := c.call(procDeviceAbort, w.b)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	return Error(r.uint())
+}
+
+func (l *testLink) lock(proc uint32) Error {
+	w := &xdrWriter{}
+	w.int(l.id)
+	if proc == procLock {
+		w.uint(0)
+		w.uint(0)
+	}
+	return Error(l.call(proc, w).uint())
+}
+
+func TestPortmap(t *testing.T) {
+	portmap, core, _ := testServer(t)
+	conn, err := net.Dial("tcp", portmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newRPCClient(conn, portmapProg, portmapVers)
+	defer c.Close()
+	w := &xdrWriter{}
+	w.uint(coreProg)
+	w.uint(coreVers)
+	w.uint(protoTCP)
+	w.uint(0)
+	r, err := c.call(procPortmapGet, w.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(core)
+	if got := r.uint(); fmt.Sprint(got) != port {
+		t.Errorf("GETPORT = %d; want %s", got, port)
+	}
+}
+
+func TestServer(t *testing.T) {
+	_, core, dmm := testServer(t)
+
+	if _, e := dialLink(t, core, "gpib1,22"); e != ErrInvalidAddress {
+		t.Errorf("create_link on a missing board = %v; want %v", e, ErrInvalidAddress)
+	}
+	l, e := dialLink(t, core, "gpib0,22")
+	if e != 0 {
+		t.Fatalf("create_link = %v", e)
+	}
+
+	// A message split across writes is sent as one.
+	if e := l.write("*ID", 0); e != 0 {
+		t.Errorf("device_write = %v", e)
+	}
+	if e := l.write("N?\n", flagEnd); e != 0 {
+		t.Errorf("device_write = %v", e)
+	}
+	if got, reason, e := l.read(4, 0, 0); got != "ACME" || reason != reasonReqCnt || e != 0 {
+		t.Errorf("device_read(4) = %q, %d, %v; want ACME, REQCNT", got, reason, e)
+	}
+	for _, want := range []string{",", "DMM,"} {
+		if got, reason, e := l.read(100, flagTermChrSet, ','); got != want || reason != reasonChr || e != 0 {
+			t.Errorf("device_read(term ',') = %q, %d, %v; want %q, CHR", got, reason, e, want)
+		}
+	}
+	if got, reason, e := l.read(100, 0, 0); got != "0,1.0\n" || reason != reasonEnd || e != 0 {
+		t.Errorf("device_read = %q, %d, %v; want END", got, reason, e)
+	}
+	if _, _, e := l.read(100, 0, 0); e != ErrTimeout {
+		t.Errorf("device_read with nothing to read = %v; want %v", e, ErrTimeout)
+	}
+
+	dmm.RequestService(0x41)
+	if e, stb := l.generic(procReadStb); e != 0 || stb != 0x41 {
+		t.Errorf("device_readstb = %v, %02X; want 41", e, stb)
+	}
+	for _, proc := range []uint32{procTrigger, procClear, procRemote, procLocal} {
+		if e, _ := l.generic(proc); e != 0 {
+			t.Errorf("procedure %d = %v", proc, e)
+		}
+	}
+	if dmm.Triggers() != 1 || dmm.Clears() != 1 {
+		t.Errorf("instrument got %d triggers and %d clears; want 1 each", dmm.Triggers(), dmm.Clears())
+	}
+
+	// A second link shares the device, but not its lock.
+	l2, e := dialLink(t, core, "gpib0,22")
+	if e != 0 {
+		t.Fatalf("second create_link = %v", e)
+	}
+	if e := l.lock(procLock); e != 0 {
+		t.Errorf("device_lock = %v", e)
+	}
+	if e := l2.lock(procLock); e != ErrLocked {
+		t.Errorf("device_lock by another link = %v; want %v", e, ErrLocked)
+	}
+	if e := l2.write("*RST\n", flagEnd); e != ErrLocked {
+		t.Errorf("device_write by another link = %v; want %v", e, ErrLocked)
+	}
+	if e := l2.lock(procUnlock); e != ErrNoLock {
+		t.Errorf("device_unlock by another link = %v; want %v", e, ErrNoLock)
+	}
+	if e := l.lock(procUnlock); e != 0 {
+		t.Errorf("device_unlock = %v", e)
+	}
+	if e := l2.write("*RST\n", flagEnd); e != 0 {
+		t.Errorf("device_write after unlock = %v", e)
+	}
+}
+
+func TestLinkScope(t *testing.T) {
+	_, core, _ := testServer(t)
+
+	// Links created at the same time share the device.
+	links := make([]*testLink, 4)
+	var wg sync.WaitGroup
+	for i := range links {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l, e := dialLink(t, core, "gpib0,22")
+			if e != 0 {
+				t.Errorf("create_link = %v", e)
+			}
+			links[i] = l
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	// A link cannot be used or destroyed from another connection.
+	l, other := links[0], links[1]
+	stolen := &testLink{t, other.c, l.id, l.abortPort}
+	if e := stolen.write("*RST\n", flagEnd); e != ErrInvalidLink {
+		t.Errorf("device_write on another connection = %v; want %v", e, ErrInvalidLink)
+	}
+	if e := stolen.lock(procLock); e != ErrInvalidLink {
+		t.Errorf("device_lock on another connection = %v; want %v", e, ErrInvalidLink)
+	}
+	w := &xdrWriter{}
+	w.int(l.id)
+	if e := Error(stolen.call(procDestroyLink, w).uint()); e != ErrInvalidLink {
+		t.Errorf("destroy_link on another connection = %v; want %v", e, ErrInvalidLink)
+	}
+	if e := l.write("*RST\n", flagEnd); e != 0 {
+		t.Errorf("device_write on the link's own connection = %v", e)
+	}
+}
+
+// stalled is an instrument whose reads wait until release is closed. Each
+// read first sends on waiting.
+type stalled struct {
+	*sim.SCPI
+	waiting chan struct{}
+	release chan struct{}
+}
+
+func (s *stalled) Send() []byte {
+	s.waiting <- struct{}{}
+	<-s.release
+	return s.SCPI.Send()
+}
+
+func TestAbort(t *testing.T) {
+	be := sim.New()
+	in := &stalled{sim.NewSCPI("ACME,DMM,0,1.0"), make(chan struct{}, 1), make(chan struct{})}
+	be.Attach(22, in)
+	_, core, _ := serveBackend(t, be)
+	var once sync.Once
+	release := func() { once.Do(func() { close(in.release) }) }
+	t.Cleanup(release)
+
+	l, e := dialLink(t, core, "gpib0,22")
+	if e != 0 {
+		t.Fatalf("create_link = %v", e)
+	}
+	if e := l.write("*IDN?\n", flagEnd); e != 0 {
+		t.Fatalf("device_write = %v", e)
+	}
+	read := make(chan Error)
+	go func() {
+		_, _, e := l.read(100, 0, 0)
+		read <- e
+	}()
+	<-in.waiting
+	if e := l.abort(); e != 0 {
+		t.Errorf("device_abort = %v", e)
+	}
+	select {
+	case e := <-read:
+		if e != ErrAbort {
+			t.Errorf("aborted device_read = %v; want %v", e, ErrAbort)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("device_read still blocked after device_abort")
+	}
+
+	// The transfer itself runs on, and what it read is kept for the next
+	// read.
+	release()
+	if got, reason, e := l.read(100, 0, 0); got != "ACME,DMM,0,1.0\n" || reason != reasonEnd || e != 0 {
+		t.Errorf("device_read after abort = %q, %d, %v; want the identity, END", got, reason, e)
+	}
+}
+
+func TestRelinkAfterAbort(t *testing.T) {
+	be := sim.New()
+	in := &stalled{sim.NewSCPI("ACME,DMM,0,1.0"), make(chan struct{}, 1), make(chan struct{})}
+	be.Attach(22, in)
+	_, core, s := serveBackend(t, be)
+	var once sync.Once
+	release := func() { once.Do(func() { close(in.release) }) }
+	t.Cleanup(release)
+
+	l, e := dialLink(t, core, "gpib0,22")
+	if e != 0 {
+		t.Fatalf("create_link = %v", e)
+	}
+	if e := l.write("*IDN?\n", flagEnd); e != 0 {
+		t.Fatalf("device_write = %v", e)
+	}
+	read := make(chan Error)
+	go func() {
+		_, _, e := l.read(100, 0, 0)
+		read <- e
+	}()
+	<-in.waiting
+	if e := l.abort(); e != 0 {
+		t.Errorf("device_abort = %v", e)
+	}
+	<-read
+
+	// Dropping the connection destroys the link, but closing the device waits
+	// for the read still on the bus. A new link waits for that too, rather
+	// than finding the device in use.
+	l.c.Close()
+	for {
+		s.mu.Lock()
+		n := len(s.links)
+		s.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	relinked := make(chan Error)
+	go func() {
+		_, e := dialLink(t, core, "gpib0,22")
+		relinked <- e
+	}()
+	time.Sleep(10 * time.Millisecond)
+	release()
+	if e := <-relinked; e != 0 {
+		t.Errorf("create_link after abort = %v", e)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	dmm := sim.NewSCPI("ACME,DMM,0,1.0")
+	s := sim.New()
+	s.Attach(22, dmm)
+	be, err := fault.New(s, 1, fault.Rule{Kind: fault.Timeout, Ops: []string{"Ibrd"}, Bytes: 4, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, core, _ := serveBackend(t, be)
+	l, e := dialLink(t, core, "gpib0,22")
+	if e != 0 {
+		t.Fatalf("create_link = %v", e)
+	}
+	if e := l.write("*IDN?\n", flagEnd); e != 0 {
+		t.Fatalf("device_write = %v", e)
+	}
+	// What was read before the timeout is returned with it.
+	if got, _, e := l.read(100, 0, 0); got != "ACME" || e != ErrTimeout {
+		t.Errorf("device_read timing out = %q, %v; want ACME, %v", got, e, ErrTimeout)
+	}
+	if got, reason, e := l.read(100, 0, 0); got != ",DMM,0,1.0\n" || reason != reasonEnd || e != 0 {
+		t.Errorf("device_read after a timeout = %q, %d, %v; want the rest, END", got, reason, e)
+	}
+}
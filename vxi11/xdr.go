@@ -0,0 +1,101 @@
+// Copyright 2026 Google LLC
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// version 2 as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+package vxi11
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errShort is returned when XDR data ends early.
+var errShort = errors.New("xdr: data too short")
+
+// xdrWriter encodes values in the XDR format of RFC 4506.
+type xdrWriter struct {
+	b []byte
+}
+
+func (w *xdrWriter) uint(v uint32) {
+	w.b = binary.BigEndian.AppendUint32(w.b, v)
+}
+
+func (w *xdrWriter) int(v int32) {
+	w.uint(uint32(v))
+}
+
+func (w *xdrWriter) bool(v bool) {
+	if v {
+		w.uint(1)
+	} else {
+		w.uint(0)
+	}
+}
+
+// opaque encodes variable-length data, padded to a multiple of four bytes.
+func (w *xdrWriter) opaque(b []byte) {
+	w.uint(uint32(len(b)))
+	w.b = append(w.b, b...)
+	for len(w.b)%4 != 0 {
+		w.b = append(w.b, 0)
+	}
+}
+
+func (w *xdrWriter) string(s string) {
+	w.opaque([]byte(s))
+}
+
+// xdrReader decodes values in the XDR format. After an error, it returns zero
+// values and err is set.
+type xdrReader struct {
+	b   []byte
+	err error
+}
+
+func (r *xdrReader) uint() uint32 {
+	if len(r.b) < 4 {
+		r.err = errShort
+		r.b = nil
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *xdrReader) int() int32 {
+	return int32(r.uint())
+}
+
+func (r *xdrReader) bool() bool {
+	return r.uint() != 0
+}
+
+// opaque decodes variable-length data of at most max bytes.
+func (r *xdrReader) opaque(max int) []byte {
+	n := int(r.uint())
+	if r.err != nil {
+		return nil
+	}
+	padded := (n + 3) &^ 3
+	if n > max || padded > len(r.b) || padded < n {
+		r.err = errShort
+		r.b = nil
+		return nil
+	}
+	b := r.b[:n:n]
+	r.b = r.b[padded:]
+	return b
+}
+
+func (r *xdrReader) string(max int) string {
+	return string(r.opaque(max))
+}